@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"lotto-tg-app/internal/db"
+)
+
+const usage = `Uso: migrate <comando>
+
+Comandos:
+  status   Lista las migraciones y si ya fueron aplicadas
+  up       Aplica las migraciones pendientes`
+
+func main() {
+	_ = godotenv.Load() // Load .env file if exists
+
+	if len(os.Args) != 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	dbURL, authToken, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := db.Connect(dbURL, authToken); err != nil {
+		log.Fatal("Failed to connect DB:", err)
+	}
+	defer db.DB.Close()
+
+	switch os.Args[1] {
+	case "status":
+		states, err := db.MigrationStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+		pending := 0
+		for _, st := range states {
+			if st.Applied {
+				fmt.Printf("  [x] %04d_%s  (%s)\n", st.Version, st.Name, st.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("  [ ] %04d_%s\n", st.Version, st.Name)
+				pending++
+			}
+		}
+		fmt.Printf("%d migraciones, %d pendientes\n", len(states), pending)
+
+	case "up":
+		n, err := db.Migrate()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d migraciones aplicadas\n", n)
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
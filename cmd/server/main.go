@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -14,7 +15,10 @@ import (
 	"lotto-tg-app/internal/db"
 	"lotto-tg-app/internal/handlers"
 	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/receipts"
 	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store/sqlstore"
 )
 
 func main() {
@@ -31,25 +35,91 @@ func main() {
 		port = "8080"
 	}
 
-	// 1. Init Database (Turso)
-	dbURL := os.Getenv("TURSO_DATABASE_URL")
-	authToken := os.Getenv("TURSO_AUTH_TOKEN")
-	if dbURL == "" || authToken == "" {
-		log.Fatal("TURSO_DATABASE_URL and TURSO_AUTH_TOKEN must be set")
+	// 1. Init Database (Turso o SQLite local con DATABASE_URL=file:...)
+	dbURL, authToken, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	if err := db.Init(dbURL, authToken); err != nil {
 		log.Fatal("Failed to init DB:", err)
 	}
 	defer db.DB.Close()
-	log.Println("Database initialized with Turso")
+	if db.IsLocal(dbURL) {
+		log.Printf("Database initialized with local SQLite (%s)", dbURL)
+	} else {
+		log.Println("Database initialized with Turso")
+	}
+
+	st := sqlstore.New(db.DB)
+	h := handlers.New(st)
+
+	// Primeros owners desde ADMIN_TELEGRAM_IDS (solo si no hay ninguno); el
+	// resto de los admins se invitan desde /admin/admins
+	if n, err := services.BootstrapAdmins(st, os.Getenv("ADMIN_TELEGRAM_IDS")); err != nil {
+		log.Fatal("Failed to bootstrap admins:", err)
+	} else if n > 0 {
+		log.Printf("%d owners creados desde ADMIN_TELEGRAM_IDS", n)
+	}
+	// ADMIN_PASSWORD en texto plano de versiones anteriores: se guarda hasheada
+	if ok, err := services.BootstrapPassword(st, os.Getenv("ADMIN_PASSWORD")); err != nil {
+		log.Printf("Warning: ADMIN_PASSWORD no migrada: %v", err)
+	} else if ok {
+		log.Println("ADMIN_PASSWORD guardada con hash como usuario 'admin' del primer owner; ya se puede quitar la variable")
+	}
+
+	// Comprobantes de pago (disco local o bucket S3 con RECEIPTS_S3_BUCKET)
+	if rs, err := receipts.FromEnv(); err != nil {
+		log.Printf("Warning: comprobantes desactivados: %v", err)
+	} else {
+		h.Receipts = rs
+	}
 
-	// 2. Init Telegram Bot
+	// 2. Init Telegram Bot: webhook si hay una URL pública, si no long polling
+	var webhook http.Handler
 	if token != "" {
-		if err := services.InitBot(token); err != nil {
+		if err := services.InitBot(token, st); err != nil {
 			log.Printf("Warning: Failed to init Telegram bot: %v", err)
+		} else if publicURL := os.Getenv("PUBLIC_URL"); publicURL != "" {
+			secret := services.WebhookSecret(os.Getenv("TELEGRAM_WEBHOOK_SECRET"), token)
+			if webhook, err = services.StartWebhook(publicURL, secret); err != nil {
+				log.Printf("Warning: webhook no registrado, usando polling: %v", err)
+				services.StartPolling()
+			}
+		} else {
+			services.StartPolling()
+		}
+	}
+
+	// 2.1 Liberar reservas vencidas (raffles.reserve_hours)
+	reapInterval := 5 * time.Minute
+	if v := os.Getenv("REAPER_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			reapInterval = d
+		} else {
+			log.Printf("Warning: REAPER_INTERVAL inválido (%q), usando %s", v, reapInterval)
+		}
+	}
+	reaper := services.NewReaper(st, reapInterval)
+	// Recordatorio al cliente antes de que venza su reserva (0 lo desactiva)
+	reaper.RemindBefore = 2 * time.Hour
+	if v := os.Getenv("REMINDER_BEFORE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			reaper.RemindBefore = d
+		} else {
+			log.Printf("Warning: REMINDER_BEFORE inválido (%q), usando %s", v, reaper.RemindBefore)
 		}
 	}
+	reaper.Start()
+	defer reaper.Stop()
+
+	// 2.2 Enviar las notificaciones encoladas (tabla outbox) con reintentos.
+	// Sin bot quedan pendientes hasta que se configure TELEGRAM_TOKEN.
+	if services.Bot != nil {
+		outbox := services.NewOutbox(st, h.Receipts, 2*time.Second)
+		outbox.Start()
+		defer outbox.Stop()
+	}
 
 	// 3. Setup Router
 	r := chi.NewRouter()
@@ -62,23 +132,69 @@ func main() {
 	FileServer(r, "/assets", http.Dir(filesDir))
 
 	// 5. Public Routes
-	r.Get("/", handlers.Home)
-	r.Get("/tickets/search", handlers.SearchTickets)
-	r.Get("/tickets/{number}/book", handlers.GetBookModal)
-	r.Post("/tickets/{number}/book", handlers.PostBook)
+	r.Get("/", h.Home)
+	r.Get("/tickets/search", h.SearchTickets)
+	r.Get("/tickets/book", h.GetBookModal)
+	r.Post("/tickets/book", h.PostBook)
+	r.Get("/tickets/{number}/book", h.GetBookModal)
+	r.Post("/tickets/{number}/book", h.PostBook)
 
-	// Admin Login (captura initData de Telegram)
+	// Actualizaciones del bot (solo en modo webhook; valida el secret token)
+	if webhook != nil {
+		r.Post(services.WebhookPath, webhook.ServeHTTP)
+	}
+
+	// Admin Login (cambia el initData de Telegram por una cookie de sesión)
 	r.Get("/admin/login", handlers.AdminLogin)
+	r.Post("/admin/login", h.AdminCreateSession)
 
-	// 6. Admin Routes (Protected by Telegram Auth)
+	// 6. Admin Routes (Protected by Telegram Auth, each group by role; POSTs need the CSRF token)
 	r.Group(func(r chi.Router) {
-		r.Use(tgmiddleware.TelegramAdminAuth)
-		r.Get("/admin", handlers.AdminDashboard)
-		r.Get("/admin/users/search", handlers.AdminSearchUsers)
-		r.Get("/admin/tickets/{id}/details", handlers.AdminGetTicketDetails)
-		r.Post("/admin/raffles", handlers.AdminCreateRaffle)
-		r.Post("/admin/tickets/{id}/payment", handlers.AdminAddPayment)
-		r.Post("/admin/tickets/{id}/release", handlers.AdminReleaseTicket)
+		r.Use(tgmiddleware.TelegramAdminAuth(st))
+		r.Use(tgmiddleware.CSRF)
+		r.Post("/admin/logout", h.AdminLogout)
+
+		// viewer: solo lectura
+		r.Group(func(r chi.Router) {
+			r.Use(tgmiddleware.RequireRole(models.RoleViewer))
+			r.Get("/admin", h.AdminDashboard)
+			r.Get("/admin/users/search", h.AdminSearchUsers)
+			r.Get("/admin/tickets/{id}/details", h.AdminGetTicketDetails)
+			r.Get("/admin/payments/{id}/receipt", h.AdminGetReceipt)
+		})
+
+		// cashier: registrar y revisar pagos, cargar tasas
+		r.Group(func(r chi.Router) {
+			r.Use(tgmiddleware.RequireRole(models.RoleCashier))
+			r.Post("/admin/tickets/{id}/payment", h.AdminAddPayment)
+			r.Post("/admin/payments/{id}/verify", h.AdminVerifyPayment)
+			r.Post("/admin/payments/{id}/reject", h.AdminRejectPayment)
+			r.Post("/admin/payments/{id}/reverse", h.AdminReversePayment)
+			r.Get("/admin/rates", h.AdminRates)
+			r.Post("/admin/rates", h.AdminAddRate)
+			r.Post("/admin/rates/import", h.AdminImportRates)
+		})
+
+		// owner: rifas, liberaciones, clientes, notificaciones, admins y audit log
+		r.Group(func(r chi.Router) {
+			r.Use(tgmiddleware.RequireRole(models.RoleOwner))
+			r.Post("/admin/users/merge", h.AdminMergeUsers)
+			r.Post("/admin/raffles", h.AdminCreateRaffle)
+			r.Post("/admin/raffles/{id}/close", h.AdminCloseRaffle)
+			r.Post("/admin/raffles/{id}/draw", h.AdminDrawRaffle)
+			r.Post("/admin/raffles/{id}/methods", h.AdminSetRaffleMethods)
+			r.Get("/admin/methods", h.AdminMethods)
+			r.Post("/admin/methods", h.AdminSaveMethod)
+			r.Post("/admin/tickets/{id}/release", h.AdminReleaseTicket)
+			r.Post("/admin/tickets/{id}/refund", h.AdminRefundTicket)
+			r.Post("/admin/outbox/{id}/retry", h.AdminRetryNotification)
+			r.Get("/admin/admins", h.AdminListAdmins)
+			r.Post("/admin/admins", h.AdminSaveAdmin)
+			r.Post("/admin/admins/{id}/revoke", h.AdminRevokeAdmin)
+			r.Post("/admin/admins/{id}/password", h.AdminSetPassword)
+			r.Get("/admin/audit", h.AdminAudit)
+			r.Get("/admin/audit.csv", h.AdminAuditCSV)
+		})
 	})
 
 	// 7. Start
@@ -2,79 +2,98 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
+	_ "modernc.org/sqlite"
 )
 
 var DB *sql.DB
 
-func Init(dbURL, authToken string) error {
-	var err error
+// ConfigFromEnv resuelve la URL de la base de datos.
+// DATABASE_URL tiene prioridad y acepta "file:lotto.db" (SQLite local, sin
+// cuenta de Turso) o una URL libsql://; si no está, se usan TURSO_DATABASE_URL
+// y TURSO_AUTH_TOKEN como antes.
+func ConfigFromEnv() (dbURL, authToken string, err error) {
+	dbURL = os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		dbURL = os.Getenv("TURSO_DATABASE_URL")
+	}
+	authToken = os.Getenv("TURSO_AUTH_TOKEN")
+
+	if dbURL == "" {
+		return "", "", errors.New("DATABASE_URL (file:...) or TURSO_DATABASE_URL and TURSO_AUTH_TOKEN must be set")
+	}
+	if !IsLocal(dbURL) && authToken == "" {
+		return "", "", errors.New("TURSO_AUTH_TOKEN must be set for remote databases")
+	}
+	return dbURL, authToken, nil
+}
 
-	// Build connection string with auth token
-	connStr := fmt.Sprintf("%s?authToken=%s", dbURL, authToken)
+// IsLocal indica si la URL apunta a un archivo SQLite local
+func IsLocal(dbURL string) bool {
+	return strings.HasPrefix(dbURL, "file:")
+}
 
-	DB, err = sql.Open("libsql", connStr)
-	if err != nil {
+// Init conecta a la base de datos y aplica las migraciones pendientes
+func Init(dbURL, authToken string) error {
+	if err := Connect(dbURL, authToken); err != nil {
 		return err
 	}
 
-	if err = DB.Ping(); err != nil {
+	if _, err := Migrate(); err != nil {
+		log.Printf("Error applying migrations: %v", err)
 		return err
 	}
 
-	return createTables()
+	return nil
 }
 
-func createTables() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		telegram_id INTEGER UNIQUE,
-		name TEXT NOT NULL,
-		phone TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS raffles (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		total_numbers INTEGER NOT NULL,
-		ticket_price REAL NOT NULL,
-		reserve_hours INTEGER DEFAULT 24,
-		status TEXT DEFAULT 'active',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS tickets (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		raffle_id INTEGER,
-		number TEXT NOT NULL,
-		user_id INTEGER,
-		status TEXT DEFAULT 'available',
-		reserved_at DATETIME,
-		FOREIGN KEY(raffle_id) REFERENCES raffles(id),
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);
-
-	CREATE TABLE IF NOT EXISTS payments (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		ticket_id INTEGER,
-		amount REAL NOT NULL,
-		method TEXT,
-		reference TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		is_verified BOOLEAN DEFAULT 0,
-		FOREIGN KEY(ticket_id) REFERENCES tickets(id)
-	);
-	`
-
-	_, err := DB.Exec(query)
+// Connect abre la conexión sin tocar el esquema (usado por cmd/migrate)
+func Connect(dbURL, authToken string) error {
+	var err error
+
+	if IsLocal(dbURL) {
+		DB, err = openLocal(dbURL)
+	} else {
+		// Build connection string with auth token
+		connStr := fmt.Sprintf("%s?authToken=%s", dbURL, authToken)
+		DB, err = sql.Open("libsql", connStr)
+	}
 	if err != nil {
-		log.Printf("Error creating tables: %v", err)
 		return err
 	}
 
-	return nil
+	return DB.Ping()
+}
+
+// openLocal abre un archivo SQLite con el driver embebido (sin CGO).
+// Las transacciones toman el lock de escritura al empezar (_txlock=immediate)
+// para que dos reservas simultáneas esperen en vez de fallar con SQLITE_BUSY.
+func openLocal(dbURL string) (*sql.DB, error) {
+	dsn := dbURL
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	dsn += sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(dbURL, ":memory:") {
+		// Cada conexión a :memory: es una base distinta
+		conn.SetMaxOpenConns(1)
+	} else if _, err := conn.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
 }
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitLocal(t *testing.T) {
+	if err := Init("file:"+filepath.Join(t.TempDir(), "lotto.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	states, err := MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range states {
+		if !st.Applied {
+			t.Errorf("migración %04d_%s sin aplicar", st.Version, st.Name)
+		}
+	}
+	if n, err := Migrate(); err != nil || n != 0 {
+		t.Fatalf("segunda pasada aplicó %d (%v), quería 0", n, err)
+	}
+
+	var mode string
+	if err := DB.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Fatalf("journal_mode = %s, quería wal", mode)
+	}
+
+	// foreign_keys va en el DSN para que valga en todas las conexiones
+	var fk int
+	if err := DB.QueryRow("PRAGMA foreign_keys").Scan(&fk); err != nil {
+		t.Fatal(err)
+	}
+	if fk != 1 {
+		t.Fatalf("foreign_keys = %d, quería 1", fk)
+	}
+	if _, err := DB.Exec("INSERT INTO tickets (raffle_id, number) VALUES (999, '01')"); err == nil {
+		t.Fatal("se insertó un ticket de una rifa inexistente")
+	}
+}
+
+func TestAppendOnlyTriggers(t *testing.T) {
+	if err := Init("file:"+filepath.Join(t.TempDir(), "lotto.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	for _, q := range []string{
+		"INSERT INTO audit_log (id, actor, action, created_at) VALUES (1, 'tg:1 Ana', 'payment.add', '2026-10-01 12:00:00')",
+		"INSERT INTO raffles (id, name, total_numbers, ticket_price) VALUES (1, 'Rifa', 100, 1000)",
+		"INSERT INTO tickets (id, raffle_id, number) VALUES (1, 1, '01'), (2, 1, '02')",
+		"INSERT INTO payments (id, ticket_id, amount, status) VALUES (1, 1, 500, 'pending')",
+	} {
+		if _, err := DB.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Ni el audit log ni el libro de pagos se editan o borran por SQL
+	for q, want := range map[string]string{
+		"UPDATE audit_log SET actor = 'otro' WHERE id = 1":    "audit_log es append-only",
+		"UPDATE audit_log SET after_json = '{}' WHERE id = 1": "audit_log es append-only",
+		"DELETE FROM audit_log WHERE id = 1":                  "audit_log es append-only",
+		"UPDATE payments SET amount = 100 WHERE id = 1":       "los pagos no se editan",
+		"UPDATE payments SET ticket_id = 2 WHERE id = 1":      "los pagos no se editan",
+		"DELETE FROM payments WHERE id = 1":                   "los pagos no se borran",
+	} {
+		if _, err := DB.Exec(q); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v, quería %q", q, err, want)
+		}
+	}
+	var actor string
+	if err := DB.QueryRow("SELECT actor FROM audit_log WHERE id = 1").Scan(&actor); err != nil || actor != "tg:1 Ana" {
+		t.Fatalf("la entrada quedó con actor %q (%v)", actor, err)
+	}
+
+	// Revisar un pago sí se puede: cambia el estado, no el monto
+	if _, err := DB.Exec("UPDATE payments SET status = 'verified', is_verified = 1 WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInitMemory(t *testing.T) {
+	// Con una sola conexión todas las consultas ven las tablas migradas
+	if err := Init("file::memory:", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	var n int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM payment_methods").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("la base en memoria no tiene el catálogo de métodos")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "file:lotto.db")
+	t.Setenv("TURSO_DATABASE_URL", "libsql://ejemplo.turso.io")
+	t.Setenv("TURSO_AUTH_TOKEN", "")
+
+	// DATABASE_URL gana y un archivo local no necesita token
+	url, _, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "file:lotto.db" {
+		t.Fatalf("url = %s, quería file:lotto.db", url)
+	}
+
+	t.Setenv("DATABASE_URL", "")
+	if _, _, err := ConfigFromEnv(); err == nil {
+		t.Fatal("aceptó una base remota sin TURSO_AUTH_TOKEN")
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// migrateTo abre una base nueva y aplica solo hasta la migración version,
+// para cargar datos con el esquema de ese momento
+func migrateTo(t *testing.T, version int) {
+	t.Helper()
+	if err := Connect("file:"+filepath.Join(t.TempDir(), "lotto.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Close() })
+	if err := ensureMigrationsTable(); err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		if err := applyMigration(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exec(t *testing.T, query string) {
+	t.Helper()
+	if _, err := DB.Exec(query); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func count(t *testing.T, query string) int {
+	t.Helper()
+	var n int
+	if err := DB.QueryRow(query).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrateDuplicateTickets(t *testing.T) {
+	migrateTo(t, 2)
+	exec(t, `
+		INSERT INTO users (id, name, phone) VALUES (1, 'Ana', '0414-0000001');
+		INSERT INTO raffles (id, name, total_numbers, ticket_price) VALUES (1, 'Rifa', 100, 10);
+		-- 01: dos copias disponibles
+		INSERT INTO tickets (id, raffle_id, number) VALUES (1, 1, '01'), (2, 1, '01');
+		-- 02: la copia nueva está reservada y tiene un pago
+		INSERT INTO tickets (id, raffle_id, number) VALUES (3, 1, '02');
+		INSERT INTO tickets (id, raffle_id, number, user_id, status) VALUES (4, 1, '02', 1, 'reserved');
+		INSERT INTO payments (id, ticket_id, amount) VALUES (1, 4, 5);
+		-- 03: Ana lo reservó dos veces y pagó una; cada copia con su pago
+		INSERT INTO tickets (id, raffle_id, number, user_id, status) VALUES (5, 1, '03', 1, 'reserved'), (6, 1, '03', 1, 'paid');
+		INSERT INTO payments (id, ticket_id, amount) VALUES (2, 5, 3), (3, 6, 10);
+		INSERT INTO released_payments (payment_id, ticket_id, user_id, amount) VALUES (9, 5, 1, 2);
+	`)
+
+	if _, err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	rows, err := DB.Query("SELECT id || ':' || number || ':' || status FROM tickets ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s)
+	}
+	// Queda la más antigua del 01, la reservada del 02 y la pagada del 03
+	if got := strings.Join(ids, " "); got != "1:01:available 4:02:reserved 6:03:paid" {
+		t.Fatalf("tickets = %s", got)
+	}
+	// Los pagos y el historial de la copia borrada pasan a la que queda
+	if n := count(t, "SELECT COUNT(*) FROM payments WHERE ticket_id = 6"); n != 2 {
+		t.Fatalf("el #03 quedó con %d pagos, quería 2", n)
+	}
+	if n := count(t, "SELECT COUNT(*) FROM released_payments WHERE ticket_id = 6"); n != 1 {
+		t.Fatal("el historial del #03 no pasó a la copia que queda")
+	}
+	if n := count(t, "SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'migration_0003_%'"); n != 0 {
+		t.Fatalf("quedaron %d tablas auxiliares de la migración", n)
+	}
+	if _, err := DB.Exec("INSERT INTO tickets (raffle_id, number) VALUES (1, '01')"); err == nil {
+		t.Fatal("se pudo duplicar un número después de migrar")
+	}
+}
+
+func TestMigrateStopsOnTicketsOfTwoCustomers(t *testing.T) {
+	migrateTo(t, 2)
+	exec(t, `
+		INSERT INTO users (id, name, phone) VALUES (1, 'Ana', '0414-0000001'), (2, 'Beto', '0414-0000002');
+		INSERT INTO raffles (id, name, total_numbers, ticket_price) VALUES (1, 'Rifa', 100, 10);
+		INSERT INTO tickets (id, raffle_id, number) VALUES (1, 1, '01'), (2, 1, '01');
+		INSERT INTO tickets (id, raffle_id, number, user_id, status) VALUES (3, 1, '07', 1, 'paid'), (4, 1, '07', 2, 'reserved');
+		INSERT INTO payments (id, ticket_id, amount) VALUES (1, 3, 10), (2, 4, 5);
+	`)
+
+	// Se detiene en la 0003 con un mensaje claro y sin tocar nada
+	for pass := 1; pass <= 2; pass++ {
+		n, err := Migrate()
+		if err == nil || !strings.Contains(err.Error(), "más de un cliente") {
+			t.Fatalf("pasada %d: %v, quería el error de números con dos clientes", pass, err)
+		}
+		if n != 0 {
+			t.Fatalf("pasada %d aplicó %d migraciones", pass, n)
+		}
+		if got := count(t, "SELECT COUNT(*) FROM tickets"); got != 4 {
+			t.Fatalf("pasada %d: quedaron %d tickets, quería los 4", pass, got)
+		}
+		if got := count(t, "SELECT COUNT(*) FROM schema_migrations WHERE version = 3"); got != 0 {
+			t.Fatal("la 0003 quedó marcada como aplicada")
+		}
+	}
+
+	// Resuelto a mano (Beto pasa a otro número), la migración sigue
+	exec(t, "UPDATE tickets SET number = '08' WHERE id = 4")
+	if _, err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, "SELECT COUNT(*) FROM tickets"); got != 3 {
+		t.Fatalf("quedaron %d tickets, quería 3", got)
+	}
+	if n, err := Migrate(); err != nil || n != 0 {
+		t.Fatalf("otra pasada aplicó %d (%v), quería 0", n, err)
+	}
+}
@@ -0,0 +1,74 @@
+-- Esquema inicial. Usa IF NOT EXISTS para adoptar las bases de datos
+-- creadas antes de existir las migraciones.
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	telegram_id INTEGER UNIQUE,
+	name TEXT NOT NULL,
+	phone TEXT
+);
+
+CREATE TABLE IF NOT EXISTS raffles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	total_numbers INTEGER NOT NULL,
+	ticket_price REAL NOT NULL,
+	reserve_hours INTEGER DEFAULT 24,
+	status TEXT DEFAULT 'active',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tickets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	raffle_id INTEGER,
+	number TEXT NOT NULL,
+	user_id INTEGER,
+	status TEXT DEFAULT 'available',
+	reserved_at DATETIME,
+	FOREIGN KEY(raffle_id) REFERENCES raffles(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ticket_id INTEGER,
+	amount REAL NOT NULL,
+	method TEXT,
+	reference TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	is_verified BOOLEAN DEFAULT 0,
+	FOREIGN KEY(ticket_id) REFERENCES tickets(id)
+);
+
+CREATE TABLE IF NOT EXISTS released_payments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	payment_id INTEGER,
+	ticket_id INTEGER,
+	user_id INTEGER,
+	amount REAL NOT NULL,
+	method TEXT,
+	reference TEXT,
+	is_verified BOOLEAN DEFAULT 0,
+	paid_at DATETIME,
+	released_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	reason TEXT,
+	FOREIGN KEY(ticket_id) REFERENCES tickets(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS raffle_draws (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	raffle_id INTEGER NOT NULL UNIQUE,
+	winning_number TEXT NOT NULL,
+	ticket_id INTEGER,
+	user_id INTEGER,
+	ticket_status TEXT,
+	method TEXT NOT NULL,
+	seed TEXT,
+	source TEXT,
+	drawn_by TEXT,
+	drawn_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(raffle_id) REFERENCES raffles(id),
+	FOREIGN KEY(ticket_id) REFERENCES tickets(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
@@ -0,0 +1,15 @@
+-- Teléfono normalizado (solo dígitos, formato local 0XXXXXXXXXX) para
+-- reconocer al mismo cliente aunque escriba el número distinto.
+
+ALTER TABLE users ADD COLUMN phone_norm TEXT;
+
+UPDATE users SET phone_norm =
+	replace(replace(replace(replace(replace(replace(COALESCE(phone, ''), ' ', ''), '-', ''), '+', ''), '(', ''), ')', ''), '.', '');
+
+-- +58 414 1234567 -> 04141234567
+UPDATE users SET phone_norm = '0' || substr(phone_norm, 3)
+WHERE phone_norm LIKE '58%' AND length(phone_norm) = 12;
+
+UPDATE users SET phone_norm = NULL WHERE phone_norm = '';
+
+CREATE INDEX IF NOT EXISTS idx_users_phone_norm ON users(phone_norm);
@@ -0,0 +1,49 @@
+-- Un número solo puede existir una vez por rifa. Si alguna base quedó con
+-- duplicados se conserva una fila por número: la pagada, si no la reservada
+-- y si no la más antigua. Las copias se borran y sus pagos e historial pasan
+-- a la fila que queda. Si el número lo tienen (o lo pagaron) clientes
+-- distintos no hay cómo elegir: la migración se detiene sin tocar nada para
+-- que se resuelva a mano.
+
+CREATE TABLE migration_0003_conflicts (
+	raffle_id INTEGER,
+	number TEXT,
+	CONSTRAINT "hay números reservados o vendidos a más de un cliente en la misma rifa; libera o reasigna las copias antes de migrar (SELECT raffle_id, number, id, user_id, status FROM tickets WHERE (raffle_id, number) IN (SELECT raffle_id, number FROM tickets GROUP BY 1, 2 HAVING COUNT(*) > 1))" CHECK (0)
+);
+
+INSERT INTO migration_0003_conflicts (raffle_id, number)
+SELECT raffle_id, number FROM tickets
+WHERE status <> 'available'
+   OR id IN (SELECT ticket_id FROM payments WHERE ticket_id IS NOT NULL)
+GROUP BY raffle_id, number
+HAVING COUNT(DISTINCT COALESCE(user_id, 0)) > 1;
+
+-- Cada copia con la fila que queda en su lugar
+CREATE TABLE migration_0003_copies AS
+SELECT id, keep_id FROM (
+	SELECT id, FIRST_VALUE(id) OVER (
+		PARTITION BY raffle_id, number
+		ORDER BY status = 'available', status <> 'paid', id
+	) AS keep_id
+	FROM tickets
+)
+WHERE id <> keep_id;
+
+UPDATE payments
+SET ticket_id = (SELECT keep_id FROM migration_0003_copies c WHERE c.id = payments.ticket_id)
+WHERE ticket_id IN (SELECT id FROM migration_0003_copies);
+
+UPDATE released_payments
+SET ticket_id = (SELECT keep_id FROM migration_0003_copies c WHERE c.id = released_payments.ticket_id)
+WHERE ticket_id IN (SELECT id FROM migration_0003_copies);
+
+UPDATE raffle_draws
+SET ticket_id = (SELECT keep_id FROM migration_0003_copies c WHERE c.id = raffle_draws.ticket_id)
+WHERE ticket_id IN (SELECT id FROM migration_0003_copies);
+
+DELETE FROM tickets WHERE id IN (SELECT id FROM migration_0003_copies);
+
+DROP TABLE migration_0003_copies;
+DROP TABLE migration_0003_conflicts;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_raffle_number ON tickets(raffle_id, number);
@@ -0,0 +1,12 @@
+-- Revisión de pagos: los que registra el cliente quedan 'pending' hasta que
+-- un admin los aprueba ('verified') o los rechaza ('rejected') con un motivo.
+-- is_verified se mantiene sincronizado para released_payments.
+
+ALTER TABLE payments ADD COLUMN status TEXT DEFAULT 'pending';
+ALTER TABLE payments ADD COLUMN reviewed_by TEXT;
+ALTER TABLE payments ADD COLUMN reviewed_at DATETIME;
+ALTER TABLE payments ADD COLUMN reject_reason TEXT;
+
+UPDATE payments SET status = 'verified' WHERE is_verified = 1;
+
+CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);
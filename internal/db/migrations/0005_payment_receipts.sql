@@ -0,0 +1,8 @@
+-- Comprobante de transferencia subido por el cliente (clave en el
+-- almacenamiento de receipts y su tipo MIME). Se conserva al archivar.
+
+ALTER TABLE payments ADD COLUMN receipt_key TEXT;
+ALTER TABLE payments ADD COLUMN receipt_type TEXT;
+
+ALTER TABLE released_payments ADD COLUMN receipt_key TEXT;
+ALTER TABLE released_payments ADD COLUMN receipt_type TEXT;
@@ -0,0 +1,12 @@
+-- Chats de admins que reciben las notificaciones del bot (antes un único
+-- AdminChatID en memoria). Cada admin elige qué categorías recibe.
+
+CREATE TABLE IF NOT EXISTS notification_recipients (
+	chat_id INTEGER PRIMARY KEY,
+	telegram_id INTEGER NOT NULL,
+	name TEXT,
+	bookings BOOLEAN DEFAULT 1,
+	payments BOOLEAN DEFAULT 1,
+	expiries BOOLEAN DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
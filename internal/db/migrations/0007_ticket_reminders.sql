@@ -0,0 +1,4 @@
+-- Momento en que se le recordó al cliente que su reserva está por vencer,
+-- para no repetir el recordatorio en cada pasada del Reaper.
+
+ALTER TABLE tickets ADD COLUMN reminded_at DATETIME;
@@ -0,0 +1,21 @@
+-- Mensajes de Telegram por enviar. Se escriben en la misma transacción que
+-- la reserva o el pago y los entrega services.Outbox con reintentos, así una
+-- caída de Telegram no demora el request ni pierde la notificación.
+-- Un mensaje por chat: si falla uno, se reintenta solo ese.
+
+CREATE TABLE IF NOT EXISTS outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	chat_id INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	reply_markup TEXT,
+	receipt_key TEXT,
+	receipt_type TEXT,
+	status TEXT NOT NULL DEFAULT 'pending', -- pending, sent, failed
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	next_attempt_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	sent_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(status, next_attempt_at);
@@ -0,0 +1,13 @@
+-- Admins con rol, en lugar de la lista fija ADMIN_TELEGRAM_IDS (que ahora
+-- solo se usa para crear los primeros owners si la tabla está vacía).
+--   owner:   todo, incluida la gestión de admins
+--   cashier: consulta y registra/verifica pagos
+--   viewer:  solo consulta
+
+CREATE TABLE IF NOT EXISTS admins (
+	telegram_id INTEGER PRIMARY KEY,
+	name TEXT,
+	role TEXT NOT NULL DEFAULT 'viewer',
+	invited_by TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,13 @@
+-- Sesiones del panel de admin. La cookie lleva un token firmado; acá se
+-- guarda su hash (sha256) para poder cerrar o revocar la sesión en el servidor.
+
+CREATE TABLE IF NOT EXISTS admin_sessions (
+	id TEXT PRIMARY KEY,
+	telegram_id INTEGER NOT NULL,
+	name TEXT,
+	csrf_token TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_admin ON admin_sessions(telegram_id);
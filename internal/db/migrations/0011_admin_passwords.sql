@@ -0,0 +1,14 @@
+-- Login con contraseña (BasicAuth) por admin, en lugar de ADMIN_PASSWORD en
+-- texto plano. Sin password_hash el admin solo entra por Telegram.
+ALTER TABLE admins ADD COLUMN username TEXT;
+ALTER TABLE admins ADD COLUMN password_hash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admins_username ON admins(username);
+
+-- Intentos fallidos de login por IP ("ip:<dirección>") y por usuario
+-- ("user:<username>"), para bloquear la fuerza bruta
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL DEFAULT 0,
+	first_failed_at DATETIME NOT NULL,
+	locked_until DATETIME
+);
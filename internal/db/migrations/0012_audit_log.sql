@@ -0,0 +1,32 @@
+-- Registro de cada cambio hecho por un admin (panel o bot) y por el Reaper:
+-- quién, qué acción, sobre qué rifa/ticket/pago y los valores antes y
+-- después como JSON. Se escribe en la misma transacción que el cambio.
+-- Es append-only: los triggers rechazan UPDATE y DELETE.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor TEXT NOT NULL,  -- "tg:<id> <nombre>", "reaper", ...
+	action TEXT NOT NULL, -- models.Audit* (payment.add, ticket.release, ...)
+	raffle_id INTEGER,
+	ticket_id INTEGER,
+	payment_id INTEGER,
+	target TEXT,          -- Otros destinos: "admin:<id>", "user:<id>", "outbox:<id>"
+	before_json TEXT,
+	after_json TEXT,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_raffle ON audit_log(raffle_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_ticket ON audit_log(ticket_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_payment ON audit_log(payment_id);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log es append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log es append-only');
+END;
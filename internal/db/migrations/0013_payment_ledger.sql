@@ -0,0 +1,39 @@
+-- Los pagos pasan a ser un libro contable: no se editan ni se borran. Una
+-- corrección, una devolución o el dinero que pasa a saldo a favor o a otro
+-- ticket es una entrada nueva con monto negativo (kind).
+-- user_id es el cliente dueño del dinero: el saldo de un ticket suma solo
+-- las entradas de su cliente actual, así al liberarlo y venderlo a otro no
+-- arrastra el historial del anterior.
+ALTER TABLE payments ADD COLUMN kind TEXT NOT NULL DEFAULT 'payment'; -- payment, reversal, refund, credit, transfer
+ALTER TABLE payments ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE payments ADD COLUMN related_id INTEGER REFERENCES payments(id); -- Pago revertido
+ALTER TABLE payments ADD COLUMN reason TEXT;
+
+UPDATE payments SET user_id = (SELECT user_id FROM tickets WHERE tickets.id = payments.ticket_id);
+
+CREATE INDEX IF NOT EXISTS idx_payments_ticket_user ON payments(ticket_id, user_id);
+
+-- Revisar (status) sigue permitido; el monto, el ticket y el tipo no cambian
+CREATE TRIGGER IF NOT EXISTS payments_no_delete BEFORE DELETE ON payments
+BEGIN
+	SELECT RAISE(ABORT, 'los pagos no se borran: registra una reversión');
+END;
+
+CREATE TRIGGER IF NOT EXISTS payments_no_edit BEFORE UPDATE OF amount, ticket_id, kind ON payments
+BEGIN
+	SELECT RAISE(ABORT, 'los pagos no se editan: registra una reversión');
+END;
+
+-- Saldo a favor de cada cliente: la suma de sus movimientos. Entra al
+-- liberar un ticket con dinero cobrado (o al vencer la reserva).
+CREATE TABLE IF NOT EXISTS credits (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	amount REAL NOT NULL, -- Positivo entra, negativo se usa
+	payment_id INTEGER REFERENCES payments(id), -- Entrada del ticket de donde salió
+	reason TEXT,
+	created_by TEXT,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credits_user ON credits(user_id);
@@ -0,0 +1,34 @@
+-- Cada rifa tiene su moneda base: la del precio, los saldos y los totales.
+-- Los pagos guardan la moneda y el monto en que se recibieron y la tasa con
+-- la que se convirtieron a la moneda de la rifa (amount).
+ALTER TABLE raffles ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+ALTER TABLE payments ADD COLUMN currency TEXT;
+ALTER TABLE payments ADD COLUMN original_amount REAL;
+ALTER TABLE payments ADD COLUMN rate REAL; -- Unidades de currency por 1 de la moneda de la rifa
+
+UPDATE payments SET currency = 'USD', original_amount = amount, rate = 1;
+
+-- La moneda y la tasa tampoco cambian
+DROP TRIGGER IF EXISTS payments_no_edit;
+CREATE TRIGGER payments_no_edit BEFORE UPDATE OF amount, ticket_id, kind, currency, original_amount, rate ON payments
+BEGIN
+	SELECT RAISE(ABORT, 'los pagos no se editan: registra una reversión');
+END;
+
+-- El saldo a favor se lleva por moneda (la de la rifa de donde salió)
+ALTER TABLE credits ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+-- Tasas de cambio cargadas por los admins: 1 base = rate currency desde
+-- valid_from. Se usa la última vigente a la hora del pago.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	currency TEXT NOT NULL,
+	base TEXT NOT NULL,
+	rate REAL NOT NULL,
+	valid_from DATETIME NOT NULL,
+	created_by TEXT,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_lookup ON exchange_rates(currency, base, valid_from);
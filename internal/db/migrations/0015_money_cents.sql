@@ -0,0 +1,31 @@
+-- Los montos pasan de REAL a INTEGER en centavos (models.Money): las sumas de
+-- muchos abonos dan exactamente el precio. SQLite no cambia el tipo de una
+-- columna, así que cada una se copia a una nueva, se borra y se renombra.
+-- released_payments ya no se usa (libro de pagos) y queda como estaba.
+
+-- El trigger nombra amount y original_amount, que no se pueden borrar mientras exista
+DROP TRIGGER IF EXISTS payments_no_edit;
+
+ALTER TABLE raffles ADD COLUMN ticket_price_cents INTEGER NOT NULL DEFAULT 0;
+UPDATE raffles SET ticket_price_cents = CAST(ROUND(ticket_price * 100) AS INTEGER);
+ALTER TABLE raffles DROP COLUMN ticket_price;
+ALTER TABLE raffles RENAME COLUMN ticket_price_cents TO ticket_price;
+
+ALTER TABLE payments ADD COLUMN amount_cents INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN original_amount_cents INTEGER;
+UPDATE payments SET amount_cents = CAST(ROUND(amount * 100) AS INTEGER),
+	original_amount_cents = CAST(ROUND(original_amount * 100) AS INTEGER);
+ALTER TABLE payments DROP COLUMN amount;
+ALTER TABLE payments DROP COLUMN original_amount;
+ALTER TABLE payments RENAME COLUMN amount_cents TO amount;
+ALTER TABLE payments RENAME COLUMN original_amount_cents TO original_amount;
+
+ALTER TABLE credits ADD COLUMN amount_cents INTEGER NOT NULL DEFAULT 0;
+UPDATE credits SET amount_cents = CAST(ROUND(amount * 100) AS INTEGER);
+ALTER TABLE credits DROP COLUMN amount;
+ALTER TABLE credits RENAME COLUMN amount_cents TO amount;
+
+CREATE TRIGGER payments_no_edit BEFORE UPDATE OF amount, ticket_id, kind, currency, original_amount, rate ON payments
+BEGIN
+	SELECT RAISE(ABORT, 'los pagos no se editan: registra una reversión');
+END;
@@ -0,0 +1,31 @@
+-- Catálogo de métodos de pago que administran los owners. payments.method
+-- guarda el código. Los públicos se ofrecen en la Mini App con sus
+-- instrucciones; ref_pattern es la expresión regular que debe cumplir la
+-- referencia (vacía acepta cualquiera).
+CREATE TABLE IF NOT EXISTS payment_methods (
+	code TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	instructions TEXT NOT NULL DEFAULT '',
+	ref_pattern TEXT NOT NULL DEFAULT '',
+	ref_hint TEXT NOT NULL DEFAULT '',
+	public BOOLEAN NOT NULL DEFAULT 0,
+	position INTEGER NOT NULL DEFAULT 0,
+	updated_by TEXT,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- cash y transfer son los que ya usaban los pagos; la Mini App solo ofrecía
+-- transferencia, así que es el único público hasta que se carguen los datos
+INSERT OR IGNORE INTO payment_methods (code, name, public, position) VALUES
+	('transfer', 'Transferencia bancaria', 1, 1),
+	('pago_movil', 'Pago móvil', 0, 2),
+	('zelle', 'Zelle', 0, 3),
+	('crypto', 'Cripto (USDT)', 0, 4),
+	('cash', 'Efectivo', 0, 5);
+
+-- Métodos que acepta cada rifa; una rifa sin filas acepta todos los públicos
+CREATE TABLE IF NOT EXISTS raffle_payment_methods (
+	raffle_id INTEGER NOT NULL REFERENCES raffles(id),
+	method TEXT NOT NULL REFERENCES payment_methods(code),
+	PRIMARY KEY (raffle_id, method)
+);
@@ -2,18 +2,23 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
-	"lotto-tg-app/internal/db"
+	tgmiddleware "lotto-tg-app/internal/middleware"
 	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
 )
 
-// AdminLogin muestra página que captura initData de Telegram y redirige al admin
+// AdminLogin muestra página que envía el initData de Telegram a
+// AdminCreateSession y redirige al admin
 func AdminLogin(w http.ResponseWriter, r *http.Request) {
 	html := `<!DOCTYPE html>
 <html>
@@ -63,14 +68,26 @@ func AdminLogin(w http.ResponseWriter, r *http.Request) {
 		tg.ready();
 		tg.expand();
 
+		function showError(msg) {
+			document.getElementById("error").style.display = "block";
+			document.getElementById("error").innerText = msg;
+		}
+
 		if (tg.initData) {
-			document.cookie = "tg_init_data=" + encodeURIComponent(tg.initData) + "; path=/; SameSite=Lax; max-age=86400";
-			setTimeout(() => {
+			fetch("/admin/login", {
+				method: "POST",
+				headers: {"Content-Type": "application/x-www-form-urlencoded"},
+				body: "init_data=" + encodeURIComponent(tg.initData),
+				credentials: "same-origin",
+			}).then(async res => {
+				if (!res.ok) {
+					showError(await res.text());
+					return;
+				}
 				window.location.href = "/admin";
-			}, 500);
+			}).catch(e => showError("Error de conexión: " + e.message));
 		} else {
-			document.getElementById("error").style.display = "block";
-			document.getElementById("error").innerText = "No se detectó Telegram. Abre desde la Mini App.";
+			showError("No se detectó Telegram. Abre desde la Mini App.");
 		}
 	</script>
 </body>
@@ -81,54 +98,61 @@ func AdminLogin(w http.ResponseWriter, r *http.Request) {
 
 // Data structure for Admin Dashboard
 type AdminData struct {
-	Title          string
-	RaffleName     string
-	ActiveRaffles  []models.Raffle // For the dropdown
+	Title            string
+	RaffleName       string
+	ActiveRaffles    []models.Raffle // For the dropdown (active and closed)
 	SelectedRaffleID int64
-	TotalCollected float64
-	PendingAmount  float64
-	SoldCount      int
-	TotalTickets   int
-	Tickets        []models.Ticket
+	SelectedStatus   string
+	Draw             *models.Draw
+	Currency         string // Of the selected raffle; all the amounts below are in it
+	Currencies       []string
+	Methods          []models.PaymentMethod // Catalog offered when an admin records or refunds a payment
+	MethodNames      map[string]string      // Name of each method code, for the payment lists
+	TotalCollected   models.Money           // Verified payments only
+	ByCurrency       []models.CurrencyTotal // TotalCollected by the currency the money came in
+	UnverifiedAmount models.Money           // Reported by customers, waiting for review
+	PendingAmount    models.Money
+	SoldCount        int
+	TotalTickets     int
+	Tickets          []models.Ticket
+	Duplicates       [][]models.User        // Customers sharing the same phone
+	PendingPayments  []models.Payment       // Transfers waiting for verification (all raffles)
+	FailedMessages   []models.OutboxMessage // Telegram notifications that could not be delivered
+	Admin            models.Admin           // Logged-in admin; the template hides what the role can't do
+	CSRF             string                 // Sent by every form (csrf_token) and htmx request
 }
 
-func AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	if len(q) < 2 {
 		json.NewEncoder(w).Encode([]models.User{})
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT id, name, phone FROM users WHERE name LIKE ? OR phone LIKE ? LIMIT 5", "%"+q+"%", "%"+q+"%")
+	users, err := h.Store.Users().Search(q, 5)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer rows.Close()
-
-	var users []models.User
-	for rows.Next() {
-		var u models.User
-		rows.Scan(&u.ID, &u.Name, &u.Phone)
-		users = append(users, u)
+	for i := range users {
+		if users[i].Credit, err = h.Store.Credits().Balances(users[i].ID); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
 
-func AdminDashboard(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 	selectedID, _ := strconv.ParseInt(r.URL.Query().Get("raffle_id"), 10, 64)
 
-	// 1. Get List of all active raffles for the selector
-	rafRows, _ := db.DB.Query("SELECT id, name FROM raffles WHERE status = 'active'")
-	var activeRaffles []models.Raffle
-	for rafRows.Next() {
-		var raf models.Raffle
-		rafRows.Scan(&raf.ID, &raf.Name)
-		activeRaffles = append(activeRaffles, raf)
+	// 1. Get List of raffles for the selector (closed ones stay until they are drawn)
+	activeRaffles, err := h.Store.Raffles().List("active", "closed")
+	if err != nil {
+		log.Printf("Error listing raffles: %v", err)
 	}
-	rafRows.Close()
 
 	// If no raffle selected but there are active ones, pick the first one
 	if selectedID == 0 && len(activeRaffles) > 0 {
@@ -137,60 +161,91 @@ func AdminDashboard(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Get Stats & Tickets for the SELECTED raffle
 	var tickets []models.Ticket
-	var totalCollected, pending float64
+	var totalCollected, unverified, pending models.Money
 	var soldCount int
 	var raffleName string = "Sin Sorteo Seleccionado"
-	var totalTickets int
+	var selectedStatus string
+	var draw *models.Draw
+	currency := models.DefaultCurrency
+	var byCurrency []models.CurrencyTotal
 
 	if selectedID > 0 {
-		rows, err := db.DB.Query(`
-			SELECT 
-				t.id, t.number, t.status, 
-				COALESCE(u.name, 'Anon'), COALESCE(u.phone, ''),
-				COALESCE((SELECT SUM(amount) FROM payments WHERE ticket_id = t.id), 0) as paid,
-				r.ticket_price,
-				r.name
-			FROM tickets t
-			LEFT JOIN users u ON t.user_id = u.id
-			JOIN raffles r ON t.raffle_id = r.id
-			WHERE r.id = ?
-			ORDER BY t.number ASC
-		`, selectedID)
-		
+		raffle, err := h.Store.Raffles().Get(selectedID)
 		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var t models.Ticket
-				var price float64
-				rows.Scan(&t.ID, &t.Number, &t.Status, &t.UserName, &t.UserPhone, &t.TotalPaid, &price, &raffleName)
-				
-				t.Remaining = price - t.TotalPaid
-				if t.Status != "available" {
-					totalCollected += t.TotalPaid
-					pending += t.Remaining
-					soldCount++
-				}
-				tickets = append(tickets, t)
+			raffleName = raffle.Name
+			selectedStatus = raffle.Status
+			currency = raffle.Currency
+
+			if draw, err = h.getDraw(raffle.ID); err != nil {
+				log.Printf("Error fetching draw for raffle %d: %v", raffle.ID, err)
+			}
+
+			stats, err := services.Stats(h.Store, raffle)
+			if err != nil {
+				log.Printf("Error fetching tickets for raffle %d: %v", raffle.ID, err)
 			}
-			totalTickets = len(tickets)
+			tickets = stats.Tickets
+			totalCollected = stats.Collected
+			unverified = stats.Unverified
+			pending = stats.Pending
+			soldCount = stats.Sold
+			byCurrency = stats.ByCurrency
 		}
 	}
 
+	duplicates, err := h.Store.Users().Duplicates()
+	if err != nil {
+		log.Printf("Error finding duplicate users: %v", err)
+	}
+
+	pendingPayments, err := h.Store.Payments().ListPending()
+	if err != nil {
+		log.Printf("Error listing pending payments: %v", err)
+	}
+
+	failedMessages, err := h.Store.Outbox().ListFailed(20)
+	if err != nil {
+		log.Printf("Error listing failed notifications: %v", err)
+	}
+
+	methods, err := h.Store.Methods().List()
+	if err != nil {
+		log.Printf("Error listing payment methods: %v", err)
+	}
+	methodNames := map[string]string{services.MethodCredit: "Saldo a favor"}
+	for _, m := range methods {
+		methodNames[m.Code] = m.Name
+	}
+
 	data := AdminData{
 		Title:            "Admin Panel",
 		RaffleName:       raffleName,
 		ActiveRaffles:    activeRaffles,
 		SelectedRaffleID: selectedID,
+		SelectedStatus:   selectedStatus,
+		Draw:             draw,
+		Currency:         currency,
+		Currencies:       models.Currencies,
+		Methods:          methods,
+		MethodNames:      methodNames,
 		TotalCollected:   totalCollected,
+		ByCurrency:       byCurrency,
+		UnverifiedAmount: unverified,
 		PendingAmount:    pending,
 		SoldCount:        soldCount,
-		TotalTickets:     totalTickets, 
+		TotalTickets:     len(tickets),
 		Tickets:          tickets,
+		Duplicates:       duplicates,
+		PendingPayments:  pendingPayments,
+		FailedMessages:   failedMessages,
+		Admin:            tgmiddleware.CurrentAdmin(r),
+		CSRF:             tgmiddleware.CSRFToken(r),
 	}
 
 	// Custom template parsing to include functions
 	funcMap := template.FuncMap{
-		"add": func(a, b float64) float64 { return a + b },
+		"add":   func(a, b models.Money) models.Money { return a + b },
+		"money": models.FormatMoney,
 	}
 
 	// Use the base filename as the template name
@@ -211,57 +266,83 @@ func AdminDashboard(w http.ResponseWriter, r *http.Request) {
 }
 
 // AdminGetTicketDetails returns JSON with ticket, user and payment info
-func AdminGetTicketDetails(w http.ResponseWriter, r *http.Request) {
-	ticketID := chi.URLParam(r, "id")
+func (h *Handler) AdminGetTicketDetails(w http.ResponseWriter, r *http.Request) {
+	ticketID, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 
 	var data struct {
 		Ticket   models.Ticket    `json:"ticket"`
 		User     models.User      `json:"user"`
 		Payments []models.Payment `json:"payments"`
-		Price    float64          `json:"price"`
-	}
-
-	// 1. Get Ticket & Price (usando COALESCE para manejar NULL)
-	err := db.DB.QueryRow(`
-		SELECT t.id, t.number, t.status, r.ticket_price,
-		       COALESCE(u.id, 0), COALESCE(u.name, ''), COALESCE(u.phone, '')
-		FROM tickets t
-		JOIN raffles r ON t.raffle_id = r.id
-		LEFT JOIN users u ON t.user_id = u.id
-		WHERE t.id = ?`, ticketID).Scan(
-		&data.Ticket.ID, &data.Ticket.Number, &data.Ticket.Status, &data.Price,
-		&data.User.ID, &data.User.Name, &data.User.Phone,
-	)
+		Price    models.Money     `json:"price"`
+		Credit   models.Money     `json:"credit"` // Customer's credit balance in the raffle's currency
+	}
+
+	// 1. Get Ticket, Price & User
+	ticket, err := h.Store.Tickets().Get(ticketID)
 	if err != nil {
-		log.Printf("Error getting ticket %s: %v", ticketID, err)
+		log.Printf("Error getting ticket %d: %v", ticketID, err)
 		http.Error(w, "Ticket not found", 404)
 		return
 	}
+	raffle, err := h.Store.Raffles().Get(ticket.RaffleID)
+	if err != nil {
+		log.Printf("Error getting raffle of ticket %d: %v", ticketID, err)
+		http.Error(w, "Ticket not found", 404)
+		return
+	}
+	if ticket.UserID != nil {
+		if data.User, err = h.Store.Users().Get(*ticket.UserID); err != nil {
+			log.Printf("Error getting user of ticket %d: %v", ticketID, err)
+		}
+		if data.Credit, err = h.Store.Credits().Balance(*ticket.UserID, raffle.Currency); err != nil {
+			log.Printf("Error getting credit of ticket %d: %v", ticketID, err)
+		}
+	}
 
-	// 2. Get Payments and calculate totals
-	var totalPaid float64
-	rows, _ := db.DB.Query("SELECT amount, method, reference, is_verified, created_at FROM payments WHERE ticket_id = ? ORDER BY created_at DESC", ticketID)
-	defer rows.Close()
-	for rows.Next() {
-		var p models.Payment
-		rows.Scan(&p.Amount, &p.Method, &p.Reference, &p.IsVerified, &p.CreatedAt)
-		data.Payments = append(data.Payments, p)
-		totalPaid += p.Amount
+	// 2. Get Payments
+	data.Payments, err = h.Store.Payments().ListByTicket(ticketID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
 	}
 
 	// Calculate remaining
-	data.Ticket.TotalPaid = totalPaid
-	data.Ticket.Remaining = data.Price - totalPaid
+	data.Ticket = ticket
+	data.Price = raffle.TicketPrice
+	data.Ticket.Remaining = data.Price - ticket.TotalPaid
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
 }
 
-func AdminCreateRaffle(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AdminCreateRaffle(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	name := r.FormValue("name")
-	price, _ := strconv.ParseFloat(r.FormValue("price"), 64)
+	price, err := models.ParseMoney(r.FormValue("price"))
+	if err != nil || price <= 0 {
+		http.Error(w, "Precio inválido", 400)
+		return
+	}
 	raffleType := r.FormValue("type") // "terminal" or "triple"
+	currency := r.FormValue("currency")
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if !models.ValidCurrency(currency) {
+		http.Error(w, services.ErrCurrency.Error(), 400)
+		return
+	}
+
+	// Horas para pagar antes de que la reserva se libere (0 = nunca vence)
+	reserveHours := 24
+	if v := r.FormValue("reserve_hours"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours < 0 {
+			http.Error(w, "Horas de reserva inválidas", 400)
+			return
+		}
+		reserveHours = hours
+	}
 
 	totalNumbers := 100
 	format := "%02d" // 00-99
@@ -271,31 +352,28 @@ func AdminCreateRaffle(w http.ResponseWriter, r *http.Request) {
 		format = "%03d" // 000-999
 	}
 
-	// Transaction
-	tx, _ := db.DB.Begin()
-
-	// REMOVED: Automatic archive of old raffles.
-	// Now we can have multiple active raffles.
-
-	// Create Raffle
-	res, err := tx.Exec("INSERT INTO raffles (name, total_numbers, ticket_price) VALUES (?, ?, ?)", name, totalNumbers, price)
-	if err != nil {
-		tx.Rollback()
-		http.Error(w, "Error creando sorteo: "+err.Error(), 500)
-		return
+	numbers := make([]string, totalNumbers)
+	for i := range numbers {
+		numbers[i] = fmt.Sprintf(format, i)
 	}
-	raffleID, _ := res.LastInsertId()
 
-	// Generate Tickets
-	stmt, _ := tx.Prepare("INSERT INTO tickets (raffle_id, number) VALUES (?, ?)")
-	for i := 0; i < totalNumbers; i++ {
-		num := fmt.Sprintf(format, i)
-		stmt.Exec(raffleID, num)
+	// Multiple raffles can be active at the same time
+	raffle := models.Raffle{
+		Name:         name,
+		TotalNumbers: totalNumbers,
+		TicketPrice:  price,
+		Currency:     currency,
+		ReserveHours: reserveHours,
 	}
-	stmt.Close()
-
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Error finalizando transacción", 500)
+	err = h.Store.Tx(func(tx store.Store) error {
+		if err := tx.Raffles().Create(&raffle, numbers); err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: tgmiddleware.AdminIdentity(r), Action: models.AuditRaffleCreate, RaffleID: &raffle.ID, CreatedAt: h.Now()}
+		return services.Audit(tx, e, nil, raffle)
+	})
+	if err != nil {
+		http.Error(w, "Error creando sorteo: "+err.Error(), 500)
 		return
 	}
 
@@ -303,62 +381,113 @@ func AdminCreateRaffle(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
-func AdminAddPayment(w http.ResponseWriter, r *http.Request) {
-	ticketID := chi.URLParam(r, "id")
-	r.ParseForm()
-	amount, _ := strconv.ParseFloat(r.FormValue("amount"), 64)
+func (h *Handler) AdminAddPayment(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Ticket inválido", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	amount, err := models.ParseMoney(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		http.Error(w, "Monto inválido", 400)
+		return
+	}
 	method := r.FormValue("method")
 	ref := r.FormValue("reference")
+	currency := r.FormValue("currency") // Empty means the raffle's currency
 	name := r.FormValue("name")
 	phone := r.FormValue("phone")
 
-	tx, _ := db.DB.Begin()
-
-	// 1. If ticket is available, we need to assign a user first
-	var status string
-	var userID int64
-	err := tx.QueryRow("SELECT status, user_id FROM tickets WHERE id = ?", ticketID).Scan(&status, &userID)
-
-	if status == "available" {
-		// Create or find user (simple create for now)
-		res, _ := tx.Exec("INSERT INTO users (name, phone) VALUES (?, ?)", name, phone)
-		userID, _ = res.LastInsertId()
-		tx.Exec("UPDATE tickets SET user_id = ?, status = 'reserved', reserved_at = CURRENT_TIMESTAMP WHERE id = ?", userID, ticketID)
+	err = h.Store.Tx(func(tx store.Store) error {
+		ticket, err := services.AddPayment(tx, services.PaymentInput{
+			TicketID:  ticketID,
+			Amount:    amount,
+			Method:    method,
+			Reference: ref,
+			Currency:  currency,
+			Name:      name,
+			Phone:     phone,
+			AdminID:   tgmiddleware.AdminIdentity(r),
+		}, h.Now())
+		if err != nil {
+			return err
+		}
+		// Queued with the payment, so the customer only hears of saved payments
+		h.Customers.In(tx).PaymentAdded(ticket.ID, amount, currency)
+		return nil
+	})
+	if errors.Is(err, services.ErrSalesClosed) || errors.Is(err, services.ErrNoCustomer) || errors.Is(err, services.ErrNoCredit) ||
+		errors.Is(err, services.ErrCurrency) || errors.Is(err, services.ErrNoRate) || errors.Is(err, services.ErrMethod) ||
+		errors.Is(err, services.ErrInvalidAmount) || errors.Is(err, services.ErrCurrencyMismatch) {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Ticket no encontrado", 404)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "El ticket cambió de estado, recarga la página", http.StatusConflict)
+		return
 	}
-
-	// 2. Insert Payment
-	_, err = tx.Exec("INSERT INTO payments (ticket_id, amount, method, reference, is_verified) VALUES (?, ?, ?, ?, 1)", ticketID, amount, method, ref)
 	if err != nil {
-		tx.Rollback()
-		http.Error(w, err.Error(), 500)
+		log.Printf("Error adding payment to ticket %d: %v", ticketID, err)
+		http.Error(w, "Error guardando el pago", 500)
 		return
 	}
 
-	// 3. Check if fully paid
-	var totalPaid, price float64
-	tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE ticket_id = ?", ticketID).Scan(&totalPaid)
-	tx.QueryRow("SELECT r.ticket_price FROM tickets t JOIN raffles r ON t.raffle_id = r.id WHERE t.id = ?", ticketID).Scan(&price)
+	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusSeeOther)
+}
+
+// AdminReleaseTicket devuelve el ticket a disponible. Si tiene pagos
+// verificados, el form dice qué hacer con ese dinero (mode): devolverlo
+// (method), dejarlo como saldo a favor o pasarlo a otro número (to_raffle_id
+// y to_number).
+func (h *Handler) AdminReleaseTicket(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Ticket inválido", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	opts := services.ReleaseOptions{
+		Mode:   r.FormValue("mode"),
+		Method: r.FormValue("method"),
+		Reason: r.FormValue("reason"),
+	}
+	if opts.Mode == services.ReleaseTransfer {
+		raffleID, _ := strconv.ParseInt(r.FormValue("to_raffle_id"), 10, 64)
+		_, to, err := services.FindTicket(h.Store, raffleID, strings.TrimSpace(r.FormValue("to_number")))
+		if err != nil {
+			http.Error(w, "Número destino no encontrado", 400)
+			return
+		}
+		opts.ToTicket = to.ID
+	}
 
-	if totalPaid >= price {
-		tx.Exec("UPDATE tickets SET status = 'paid' WHERE id = ?", ticketID)
+	admin := tgmiddleware.AdminIdentity(r)
+	err = h.Store.Tx(func(tx store.Store) error {
+		ticket, err := services.ReleaseTicket(tx, ticketID, admin, opts, h.Now())
+		if err != nil {
+			return err
+		}
+		h.Customers.In(tx).Released(ticket, false, opts.Mode)
+		return nil
+	})
+	if !ledgerError(w, err) {
+		return
 	}
+	log.Printf("Ticket %d liberado por %s (%s)", ticketID, admin, opts.Mode)
 
-	tx.Commit()
-	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusSeeOther)
+	// Return simple success text. If hx-target is "closest tr", the row disappears.
+	// If hx-swap is "none", nothing happens except the after-request trigger.
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "OK")
 }
-
-func AdminReleaseTicket(w http.ResponseWriter, r *http.Request) {
-	ticketID := chi.URLParam(r, "id")
-	
-	// Reset ticket
-	tx, _ := db.DB.Begin()
-	tx.Exec("DELETE FROM payments WHERE ticket_id = ?", ticketID)
-	tx.Exec("UPDATE tickets SET user_id = NULL, status = 'available', reserved_at = NULL WHERE id = ?", ticketID)
-		tx.Commit()
-	
-		// Return simple success text. If hx-target is "closest tr", the row disappears.
-		// If hx-swap is "none", nothing happens except the after-request trigger.
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "OK")
-	}
-	
\ No newline at end of file
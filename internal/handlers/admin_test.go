@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestAdminAddPaymentRejectsBadAmounts(t *testing.T) {
+	st := memstore.New()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+	if err := st.Raffles().Create(&raffle, []string{"01"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.AddRate(st, models.ExchangeRate{Currency: "VES", Base: raffle.Currency, Rate: 40}, "test", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := st.Tickets().GetByNumber(raffle.ID, "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(st)
+	h.Now = func() time.Time { return now }
+
+	// Errores del cliente, no del servidor: 400 con el motivo
+	for name, form := range map[string]url.Values{
+		"menos de un centavo al convertir": {"amount": {"0.01"}, "currency": {"VES"}, "method": {"pago_movil"}, "name": {"Ana"}},
+		"sin saldo a favor":                {"amount": {"5"}, "method": {services.MethodCredit}, "name": {"Ana"}},
+	} {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/admin/tickets/%d/payments", ticket.ID), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", fmt.Sprint(ticket.ID))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		h.AdminAddPayment(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d %s, quería 400", name, w.Code, w.Body)
+		}
+	}
+	if got, _ := st.Tickets().Get(ticket.ID); got.Status != "available" {
+		t.Fatalf("el ticket quedó %s, quería disponible", got.Status)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// AdminsData is the data for the admin management page (owners only)
+type AdminsData struct {
+	Title      string
+	RaffleName string // Shown in the layout header
+	Admin      models.Admin
+	Admins     []models.Admin
+	Roles      []string
+	CSRF       string
+}
+
+// AdminListAdmins shows the admins with their roles and the invite form
+func (h *Handler) AdminListAdmins(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.Store.Admins().List()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data := AdminsData{
+		Title:      "Admins",
+		RaffleName: "Administradores",
+		Admin:      tgmiddleware.CurrentAdmin(r),
+		Admins:     admins,
+		Roles:      models.AdminRoles,
+		CSRF:       tgmiddleware.CSRFToken(r),
+	}
+
+	t, err := template.ParseFiles("web/templates/layout.html", "web/templates/admins.html")
+	if err != nil {
+		log.Printf("Error parsing admins templates: %v", err)
+		http.Error(w, "Template Parse Error", 500)
+		return
+	}
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Error executing admins template: %v", err)
+		http.Error(w, "Template Exec Error", 500)
+	}
+}
+
+// AdminSaveAdmin invites a Telegram user or changes the role of an admin
+func (h *Handler) AdminSaveAdmin(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := strconv.ParseInt(strings.TrimSpace(r.FormValue("telegram_id")), 10, 64)
+	if err != nil || telegramID <= 0 {
+		http.Error(w, "ID de Telegram inválido", 400)
+		return
+	}
+	a := models.Admin{
+		TelegramID: telegramID,
+		Name:       strings.TrimSpace(r.FormValue("name")),
+		Role:       r.FormValue("role"),
+	}
+	by := tgmiddleware.AdminIdentity(r)
+
+	err = services.SaveAdmin(h.Store, a, by)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		http.Error(w, "Rol inválido", 400)
+		return
+	case errors.Is(err, services.ErrLastOwner):
+		http.Error(w, "No se puede quitar el rol al único owner", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Admin %d guardado con rol %s por %s", telegramID, a.Role, by)
+	http.Redirect(w, r, "/admin/admins", http.StatusSeeOther)
+}
+
+// AdminRevokeAdmin removes access to the panel and the bot
+func (h *Handler) AdminRevokeAdmin(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Admin inválido", 400)
+		return
+	}
+
+	by := tgmiddleware.AdminIdentity(r)
+	revoked, err := services.RevokeAdmin(h.Store, telegramID, by)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Admin no encontrado", 404)
+		return
+	case errors.Is(err, services.ErrLastOwner):
+		http.Error(w, "No se puede revocar al único owner", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Admin %d (%s, %s) revocado por %s", telegramID, revoked.Name, revoked.Role, by)
+	http.Redirect(w, r, "/admin/admins", http.StatusSeeOther)
+}
+
+// AdminSetPassword sets the username and password for BasicAuth login;
+// both empty disable it
+func (h *Handler) AdminSetPassword(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Admin inválido", 400)
+		return
+	}
+	username := strings.ToLower(strings.TrimSpace(r.FormValue("username")))
+	by := tgmiddleware.AdminIdentity(r)
+
+	err = services.SetAdminPassword(h.Store, telegramID, username, r.FormValue("password"), by)
+	switch {
+	case errors.Is(err, services.ErrInvalidUsername), errors.Is(err, services.ErrWeakPassword):
+		http.Error(w, err.Error(), 400)
+		return
+	case errors.Is(err, services.ErrUsernameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Admin no encontrado", 404)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if username == "" {
+		log.Printf("Login con contraseña deshabilitado para admin %d por %s", telegramID, by)
+	} else {
+		log.Printf("Contraseña del admin %d (%s) cambiada por %s", telegramID, username, by)
+	}
+	http.Redirect(w, r, "/admin/admins", http.StatusSeeOther)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// auditPageSize is how many entries the audit page shows; the CSV has all
+const auditPageSize = 200
+
+// AuditData is the data for the audit log page (owners only)
+type AuditData struct {
+	Title      string
+	RaffleName string // Shown in the layout header
+	Admin      models.Admin
+	CSRF       string
+	Entries    []models.AuditEntry
+	Actions    []string
+	Filter     url.Values // Query as sent, to refill the form
+	Query      string     // Same filter for the CSV link
+	Truncated  bool       // There are more than auditPageSize entries
+}
+
+// auditFilter reads the filter from the query: actor, action, raffle_id,
+// ticket_id, payment_id and the days from/to (YYYY-MM-DD, both included)
+func auditFilter(q url.Values) (store.AuditFilter, error) {
+	f := store.AuditFilter{
+		Actor:  strings.TrimSpace(q.Get("actor")),
+		Action: q.Get("action"),
+	}
+	ids := []struct {
+		name string
+		dst  *int64
+	}{{"raffle_id", &f.RaffleID}, {"ticket_id", &f.TicketID}, {"payment_id", &f.PaymentID}}
+	for _, id := range ids {
+		v := strings.TrimSpace(q.Get(id.name))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, err
+		}
+		*id.dst = n
+	}
+	if v := q.Get("from"); v != "" {
+		day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, err
+		}
+		f.From = day
+	}
+	if v := q.Get("to"); v != "" {
+		day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, err
+		}
+		f.To = day.AddDate(0, 0, 1)
+	}
+	return f, nil
+}
+
+// AdminAudit shows the audit log, newest first, with filters
+func (h *Handler) AdminAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, err := auditFilter(q)
+	if err != nil {
+		http.Error(w, "Filtro inválido", 400)
+		return
+	}
+	f.Limit = auditPageSize + 1
+	entries, err := h.Store.Audit().List(f)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data := AuditData{
+		Title:      "Audit log",
+		RaffleName: "Registro de cambios",
+		Admin:      tgmiddleware.CurrentAdmin(r),
+		CSRF:       tgmiddleware.CSRFToken(r),
+		Entries:    entries,
+		Actions:    models.AuditActions,
+		Filter:     q,
+		Query:      r.URL.RawQuery,
+	}
+	if len(entries) > auditPageSize {
+		data.Entries = entries[:auditPageSize]
+		data.Truncated = true
+	}
+
+	t, err := template.ParseFiles("web/templates/layout.html", "web/templates/audit.html")
+	if err != nil {
+		log.Printf("Error parsing audit templates: %v", err)
+		http.Error(w, "Template Parse Error", 500)
+		return
+	}
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Error executing audit template: %v", err)
+		http.Error(w, "Template Exec Error", 500)
+	}
+}
+
+// AdminAuditCSV exports every entry matching the same filters as AdminAudit
+func (h *Handler) AdminAuditCSV(w http.ResponseWriter, r *http.Request) {
+	f, err := auditFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtro inválido", 400)
+		return
+	}
+	entries, err := h.Store.Audit().List(f)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"audit-"+h.Now().Format("20060102-1504")+".csv\"")
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "actor", "action", "raffle_id", "ticket_id", "payment_id", "target", "before", "after"})
+	for _, e := range entries {
+		cw.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			e.Actor,
+			e.Action,
+			optionalID(e.RaffleID),
+			optionalID(e.TicketID),
+			optionalID(e.PaymentID),
+			e.Target,
+			e.Before,
+			e.After,
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Error writing audit CSV: %v", err)
+	}
+}
+
+func optionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/db"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+	"lotto-tg-app/internal/store/memstore"
+	"lotto-tg-app/internal/store/sqlstore"
+)
+
+// testStores devuelve las dos implementaciones del store, la SQL sobre un
+// archivo SQLite nuevo con todas las migraciones
+func testStores(t *testing.T) map[string]store.Store {
+	t.Helper()
+	if err := db.Init("file:"+filepath.Join(t.TempDir(), "test.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	return map[string]store.Store{
+		"memstore": memstore.New(),
+		"sqlite":   sqlstore.New(db.DB),
+	}
+}
+
+func TestPostBookConcurrent(t *testing.T) {
+	const bookers = 20
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, []string{"07"}); err != nil {
+				t.Fatal(err)
+			}
+			h := New(st)
+			path := fmt.Sprintf("/tickets/book?raffle_id=%d", raffle.ID)
+
+			codes := make([]int, bookers)
+			var start, done sync.WaitGroup
+			start.Add(1)
+			for i := range bookers {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					form := url.Values{
+						"numbers":   {"07"},
+						"name":      {"Cliente"},
+						"phone":     {fmt.Sprintf("0414-%07d", i)},
+						"method":    {"transfer"},
+						"reference": {"123"},
+						"amount":    {"10"},
+					}
+					req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+					w := httptest.NewRecorder()
+					start.Wait()
+					h.PostBook(w, req)
+					codes[i] = w.Code
+				}()
+			}
+			start.Done()
+			done.Wait()
+
+			booked, conflicts := 0, 0
+			for _, code := range codes {
+				switch code {
+				case http.StatusOK:
+					booked++
+				case http.StatusConflict:
+					conflicts++
+				default:
+					t.Errorf("respuesta inesperada %d", code)
+				}
+			}
+			if booked != 1 || conflicts != bookers-1 {
+				t.Fatalf("%d reservas y %d conflictos, quería 1 y %d", booked, conflicts, bookers-1)
+			}
+
+			ticket, err := st.Tickets().GetByNumber(raffle.ID, "07")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ticket.Status != "reserved" || ticket.UserID == nil {
+				t.Fatalf("ticket %s de %v, quería reservado", ticket.Status, ticket.UserID)
+			}
+			payments, err := st.Payments().ListByTicket(ticket.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(payments) != 1 {
+				t.Fatalf("%d pagos en el ticket, quería 1", len(payments))
+			}
+		})
+	}
+}
+
+// racyStore simula que otra reserva se lleva el ticket lost justo antes de
+// que esta lo reserve, después de ver todos los números disponibles
+type racyStore struct {
+	store.Store
+	lost int64
+}
+
+func (s racyStore) Tx(fn func(store.Store) error) error {
+	return s.Store.Tx(func(tx store.Store) error { return fn(racyStore{tx, s.lost}) })
+}
+
+func (s racyStore) Tickets() store.TicketStore { return racyTickets{s.Store.Tickets(), s.lost} }
+
+type racyTickets struct {
+	store.TicketStore
+	lost int64
+}
+
+func (t racyTickets) Reserve(id, userID int64, at time.Time) error {
+	if id == t.lost {
+		return store.ErrConflict
+	}
+	return t.TicketStore.Reserve(id, userID, at)
+}
+
+func TestPostBookCartIsAtomic(t *testing.T) {
+	numbers := []string{"01", "02", "03", "04", "05"}
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, append(numbers, "06")); err != nil {
+				t.Fatal(err)
+			}
+			book := func(h *Handler, phone string, numbers ...string) *httptest.ResponseRecorder {
+				form := url.Values{"numbers": {strings.Join(numbers, ",")}, "name": {"Cliente"}, "phone": {phone},
+					"method": {"transfer"}, "reference": {"123"}, "amount": {"50"}}
+				req := httptest.NewRequest("POST", fmt.Sprintf("/tickets/book?raffle_id=%d", raffle.ID), strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				w := httptest.NewRecorder()
+				h.PostBook(w, req)
+				return w
+			}
+			// Ninguno de numbers quedó reservado ni con pagos
+			untouched := func() {
+				t.Helper()
+				for _, number := range numbers {
+					if number == "03" {
+						continue
+					}
+					ticket, err := st.Tickets().GetByNumber(raffle.ID, number)
+					if err != nil {
+						t.Fatal(err)
+					}
+					payments, err := st.Payments().ListByTicket(ticket.ID)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ticket.Status != "available" || ticket.UserID != nil || len(payments) != 0 {
+						t.Fatalf("#%s quedó %s de %v con %d pagos, quería disponible", number, ticket.Status, ticket.UserID, len(payments))
+					}
+				}
+				if _, err := st.Users().FindByPhone("0414-0000002"); !errors.Is(err, store.ErrNotFound) {
+					t.Fatalf("quedó el cliente de la reserva fallida (%v)", err)
+				}
+			}
+
+			// Otro cliente ya tiene el 03: el carrito entero se rechaza
+			h := New(st)
+			if w := book(h, "0414-0000001", "03"); w.Code != http.StatusOK {
+				t.Fatalf("reserva del #03: %d %s", w.Code, w.Body)
+			}
+			if w := book(h, "0414-0000002", numbers...); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "03") {
+				t.Fatalf("carrito con el #03 tomado: %d %s, quería 409 por el #03", w.Code, w.Body)
+			}
+			untouched()
+
+			// Se lo llevan a mitad de la transacción, con 01 y 02 ya reservados
+			lost, err := st.Tickets().GetByNumber(raffle.ID, "04")
+			if err != nil {
+				t.Fatal(err)
+			}
+			racy := New(racyStore{st, lost.ID})
+			if w := book(racy, "0414-0000002", "01", "02", "04", "05"); w.Code != http.StatusConflict {
+				t.Fatalf("carrito con el #04 tomado a mitad: %d %s, quería 409", w.Code, w.Body)
+			}
+			untouched()
+			queued, err := st.Outbox().ListDue(time.Now().Add(time.Hour), 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range queued {
+				if strings.Contains(m.Text, "0414-0000002") {
+					t.Fatalf("quedó el aviso de la reserva fallida: %s", m.Text)
+				}
+			}
+		})
+	}
+}
+
+// initData firma los datos de la Mini App para telegramID como lo hace Telegram
+func initData(t *testing.T, telegramID int64) string {
+	return initDataAt(t, telegramID, time.Now())
+}
+
+func initDataAt(t *testing.T, telegramID int64, authDate time.Time) string {
+	t.Helper()
+	t.Setenv("TELEGRAM_TOKEN", "123:test")
+	params := url.Values{
+		"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		"user":      {fmt.Sprintf(`{"id":%d,"first_name":"Cliente"}`, telegramID)},
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte("123:test"))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte("auth_date=" + params.Get("auth_date") + "\nuser=" + params.Get("user")))
+	params.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return params.Encode()
+}
+
+func TestPostBookCreditOnlyForOwnAccount(t *testing.T) {
+	st := memstore.New()
+	raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+	if err := st.Raffles().Create(&raffle, []string{"01", "02", "03"}); err != nil {
+		t.Fatal(err)
+	}
+	owner := int64(555)
+	ana := models.User{Name: "Ana", Phone: "0414-1234567", TelegramID: &owner}
+	if err := st.Users().Create(&ana); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Credits().Add(&models.Credit{UserID: ana.ID, Amount: models.Cents(10, 0), Currency: raffle.Currency}); err != nil {
+		t.Fatal(err)
+	}
+	h := New(st)
+
+	book := func(number string, telegramID int64, authDate time.Time) models.Ticket {
+		form := url.Values{"numbers": {number}, "name": {"Ana"}, "phone": {ana.Phone}, "amount": {"0"}}
+		req := httptest.NewRequest("POST", fmt.Sprintf("/tickets/book?raffle_id=%d", raffle.ID), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if telegramID != 0 {
+			req.Header.Set("X-Telegram-Init-Data", initDataAt(t, telegramID, authDate))
+		}
+		w := httptest.NewRecorder()
+		h.PostBook(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("reserva #%s: %d %s", number, w.Code, w.Body)
+		}
+		ticket, err := st.Tickets().GetByNumber(raffle.ID, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+
+	// Con solo el teléfono de Ana la reserva es suya, pero su saldo no se toca
+	if ticket := book("01", 0, time.Now()); ticket.TotalVerified != 0 || *ticket.UserID != ana.ID {
+		t.Fatalf("#01 con %s verificado de %d, quería 0 de Ana", ticket.TotalVerified, *ticket.UserID)
+	}
+	if balance, _ := st.Credits().Balance(ana.ID, raffle.Currency); balance != models.Cents(10, 0) {
+		t.Fatalf("saldo %s, quería 10.00", balance)
+	}
+
+	// Un initData viejo (filtrado o reenviado) tampoco
+	if ticket := book("03", owner, time.Now().Add(-2*time.Hour)); ticket.TotalVerified != 0 {
+		t.Fatalf("#03 con %s verificado, quería 0 con un initData vencido", ticket.TotalVerified)
+	}
+	if balance, _ := st.Credits().Balance(ana.ID, raffle.Currency); balance != models.Cents(10, 0) {
+		t.Fatalf("saldo %s, quería 10.00", balance)
+	}
+
+	// Su propia cuenta de Telegram sí lo usa
+	if ticket := book("02", owner, time.Now()); ticket.Status != "paid" || *ticket.UserID != ana.ID {
+		t.Fatalf("#02 %s de %d, quería pagado por Ana", ticket.Status, *ticket.UserID)
+	}
+	if balance, _ := st.Credits().Balance(ana.ID, raffle.Currency); balance != 0 {
+		t.Fatalf("saldo %s, quería 0", balance)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// getDraw returns the draw of a raffle, or nil if it has not been drawn yet
+func (h *Handler) getDraw(raffleID int64) (*models.Draw, error) {
+	d, err := h.Store.Draws().Get(raffleID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// AdminCloseRaffle cierra las ventas de una rifa activa antes del sorteo
+func (h *Handler) AdminCloseRaffle(w http.ResponseWriter, r *http.Request) {
+	raffleID, _ := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	admin := tgmiddleware.AdminIdentity(r)
+
+	err := h.Store.Tx(func(tx store.Store) error {
+		if err := tx.Raffles().UpdateStatus(raffleID, "active", "closed"); err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: admin, Action: models.AuditRaffleClose, RaffleID: &raffleID, CreatedAt: h.Now()}
+		return services.Audit(tx, e, map[string]string{"status": "active"}, map[string]string{"status": "closed"})
+	})
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "La rifa no está activa", 400)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Rifa %d cerrada por %s", raffleID, admin)
+	http.Redirect(w, r, "/admin?raffle_id="+strconv.FormatInt(raffleID, 10), http.StatusSeeOther)
+}
+
+// AdminDrawRaffle registra el número ganador de una rifa cerrada.
+// mode=manual usa el número del resultado oficial; mode=random lo sortea el
+// servidor con una semilla aleatoria que queda guardada para auditoría.
+func (h *Handler) AdminDrawRaffle(w http.ResponseWriter, r *http.Request) {
+	raffleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID de sorteo inválida", 400)
+		return
+	}
+	r.ParseForm()
+	mode := r.FormValue("mode")
+	source := strings.TrimSpace(r.FormValue("source"))
+
+	raffle, err := h.Store.Raffles().Get(raffleID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Sorteo no encontrado", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if raffle.Status != "closed" {
+		http.Error(w, "Primero cierra las ventas de la rifa", 400)
+		return
+	}
+
+	draw := models.Draw{
+		RaffleID: raffle.ID,
+		Method:   mode,
+		Source:   source,
+		DrawnBy:  tgmiddleware.AdminIdentity(r),
+		DrawnAt:  h.Now(),
+	}
+
+	switch mode {
+	case "manual":
+		n, err := strconv.Atoi(strings.TrimSpace(r.FormValue("number")))
+		if err != nil || n < 0 || n >= raffle.TotalNumbers {
+			http.Error(w, "Número ganador inválido", 400)
+			return
+		}
+		draw.WinningNumber = services.FormatNumber(n, raffle.TotalNumbers)
+	case "random":
+		draw.Seed, err = services.NewDrawSeed()
+		if err != nil {
+			http.Error(w, "Error generando semilla", 500)
+			return
+		}
+		draw.WinningNumber, err = services.DrawNumber(draw.Seed, raffle.ID, raffle.TotalNumbers)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if draw.Source == "" {
+			draw.Source = "Sorteo del servidor"
+		}
+	default:
+		http.Error(w, "Modo de sorteo inválido", 400)
+		return
+	}
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		d, err := services.RecordDraw(tx, draw)
+		if err != nil {
+			return err
+		}
+		// Results go to every admin and one message to each participant,
+		// queued with the draw itself
+		if err := services.QueueAdmin(tx, "", drawSummary(d)); err != nil {
+			return err
+		}
+		h.Customers.In(tx).DrawResult(d)
+		return nil
+	})
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "La rifa ya fue sorteada", 400)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error registrando el sorteo: "+err.Error(), 500)
+		return
+	}
+
+	http.Redirect(w, r, "/admin?raffle_id="+strconv.FormatInt(raffle.ID, 10), http.StatusSeeOther)
+}
+
+func drawSummary(d models.Draw) string {
+	text := fmt.Sprintf("🏆 Sorteo %s\nNúmero ganador: #%s\n", d.RaffleName, d.WinningNumber)
+	if d.HasWinner() {
+		text += fmt.Sprintf("👤 Ganador: %s (%s)\nEstado del ticket: %s", d.WinnerName, d.WinnerPhone, d.TicketStatus)
+	} else {
+		text += "Número no vendido: sin ganador"
+	}
+	if d.Seed != "" {
+		text += "\n🔐 Semilla: " + d.Seed
+	}
+	return text
+}
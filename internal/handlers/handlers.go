@@ -1,19 +1,40 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"lotto-tg-app/internal/db"
 	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/receipts"
 	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
 )
 
+// Handler agrupa las dependencias de los controladores HTTP.
+// Se construye en main.go con New; en tests se puede usar memstore.
+type Handler struct {
+	Store     store.Store
+	Receipts  receipts.Store      // Comprobantes de pago; nil desactiva la subida
+	Customers *services.Customers // Avisos por Telegram a los clientes; nil no avisa
+	Now       func() time.Time    // Reloj inyectable
+}
+
+func New(st store.Store) *Handler {
+	return &Handler{
+		Store:     st,
+		Customers: services.NewCustomers(st),
+		Now:       time.Now,
+	}
+}
+
 // Helper to render templates
 func render(w http.ResponseWriter, tmpl string, data interface{}) {
 	t, err := template.ParseFiles(
@@ -25,59 +46,56 @@ func render(w http.ResponseWriter, tmpl string, data interface{}) {
 		log.Println("Template error:", err)
 		return
 	}
-	
+
 	if err := t.Execute(w, data); err != nil {
 		log.Println("Template execute error:", err)
 	}
 }
 
 // Home Handler - Shows list of raffles or the selected raffle grid
-func Home(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	raffleIDParam := r.URL.Query().Get("id")
 
 	// IF no ID is provided, show the list of ACTIVE raffles
 	if raffleIDParam == "" {
-	
-rows, err := db.DB.Query("SELECT id, name, total_numbers, ticket_price FROM raffles WHERE status = 'active' ORDER BY created_at DESC")
+		raffles, err := h.Store.Raffles().List("active")
 		if err != nil {
 			http.Error(w, "DB Error", 500)
 			return
 		}
-		defer rows.Close()
-
-		var raffles []models.Raffle
-		for rows.Next() {
-			var raf models.Raffle
-		
-rows.Scan(&raf.ID, &raf.Name, &raf.TotalNumbers, &raf.TicketPrice)
-			raffles = append(raffles, raf)
-		}
-
-		        if len(raffles) == 0 {
-		            w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		            fmt.Fprint(w, `
-		                <div style="font-family: sans-serif; text-align: center; padding: 50px;">
-		                    <h1>⏳ No hay sorteos activos</h1>
-		                    <p>Pronto anunciaremos nuevas rifas.</p>
-		                </div>
-		            `)
-		            return
-		        }
+
+		results, err := h.Store.Draws().Recent(5)
+		if err != nil {
+			log.Printf("Error fetching draws: %v", err)
+		}
+
+		if len(raffles) == 0 && len(results) == 0 {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `
+				<div style="font-family: sans-serif; text-align: center; padding: 50px;">
+					<h1>⏳ No hay sorteos activos</h1>
+					<p>Pronto anunciaremos nuevas rifas.</p>
+				</div>
+			`)
+			return
+		}
 		// IF only one raffle, just show it directly (optional, but better UX)
-		if len(raffles) == 1 {
+		if len(raffles) == 1 && len(results) == 0 {
 			http.Redirect(w, r, "/?id="+strconv.FormatInt(raffles[0].ID, 10), http.StatusSeeOther)
 			return
 		}
 
-		// Show List Template (we'll create it now)
+		// Show List Template
 		data := struct {
 			Title      string
 			RaffleName string
 			Raffles    []models.Raffle
+			Results    []models.Draw
 		}{
 			Title:      "Sorteos Disponibles",
 			RaffleName: "Elige tu Rifa",
 			Raffles:    raffles,
+			Results:    results,
 		}
 		render(w, "raffle_list.html", data)
 		return
@@ -90,14 +108,22 @@ rows.Scan(&raf.ID, &raf.Name, &raf.TotalNumbers, &raf.TicketPrice)
 		return
 	}
 
-	var raffle models.Raffle
-	err = db.DB.QueryRow("SELECT id, name, total_numbers, ticket_price FROM raffles WHERE id = ?", id).Scan(&raffle.ID, &raffle.Name, &raffle.TotalNumbers, &raffle.TicketPrice)
-	if err == sql.ErrNoRows {
+	raffle, err := h.Store.Raffles().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Sorteo no encontrado", 404)
 		return
 	}
+	if err != nil {
+		http.Error(w, "DB Error", 500)
+		return
+	}
+
+	draw, err := h.getDraw(raffle.ID)
+	if err != nil {
+		log.Printf("Error fetching draw for raffle %d: %v", raffle.ID, err)
+	}
 
-	tickets, err := getTickets(raffle.ID, "")
+	tickets, err := h.Store.Tickets().List(raffle.ID, "")
 	if err != nil {
 		log.Printf("Error fetching tickets for raffle %d: %v", raffle.ID, err)
 		http.Error(w, "Error cargando tickets", 500)
@@ -110,11 +136,19 @@ rows.Scan(&raf.ID, &raf.Name, &raf.TotalNumbers, &raf.TicketPrice)
 		Title      string
 		RaffleName string
 		RaffleID   int64
+		Price      models.Money
+		Currency   string
+		Open       bool
+		Draw       *models.Draw
 		Tickets    []models.Ticket
 	}{
 		Title:      "Lotería - " + raffle.Name,
 		RaffleName: raffle.Name,
 		RaffleID:   raffle.ID,
+		Price:      raffle.TicketPrice,
+		Currency:   raffle.Currency,
+		Open:       raffle.Status == "active",
+		Draw:       draw,
 		Tickets:    tickets,
 	}
 
@@ -122,18 +156,24 @@ rows.Scan(&raf.ID, &raf.Name, &raf.TotalNumbers, &raf.TicketPrice)
 }
 
 // Search Handler (HTMX) - Now needs to know which raffle to search in
-func SearchTickets(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SearchTickets(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	raffleID, _ := strconv.ParseInt(r.URL.Query().Get("raffle_id"), 10, 64)
 
-	tickets, _ := getTickets(raffleID, query)
+	tickets, err := h.Store.Tickets().List(raffleID, query)
+	if err != nil {
+		log.Printf("Error searching tickets: %v", err)
+	}
+	raffle, _ := h.Store.Raffles().Get(raffleID)
 
 	data := struct {
 		Tickets  []models.Ticket
 		RaffleID int64
+		Open     bool
 	}{
 		Tickets:  tickets,
 		RaffleID: raffleID,
+		Open:     raffle.Status == "active",
 	}
 
 	t, _ := template.ParseFiles("web/templates/index.html")
@@ -142,105 +182,368 @@ func SearchTickets(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// requestedNumbers reads the numbers to book: either the {number} in the URL
+// or the cart selection sent as repeated "numbers" values.
+func requestedNumbers(r *http.Request) ([]string, error) {
+	if number := chi.URLParam(r, "number"); number != "" {
+		return []string{number}, nil
+	}
+
+	// The booking form is multipart when it carries a receipt
+	if err := r.ParseMultipartForm(receipts.MaxSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var numbers []string
+	for _, v := range r.Form["numbers"] {
+		for _, n := range strings.Split(v, ",") {
+			n = strings.TrimSpace(n)
+			if n != "" && !seen[n] {
+				seen[n] = true
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	sort.Strings(numbers)
+	return numbers, nil
+}
+
+// splitAmount divides one payment across n tickets to the cent; the leftover
+// cents go to the first tickets so the parts always add up to the total.
+func splitAmount(amount models.Money, n int) []models.Money {
+	parts := make([]models.Money, n)
+	for i := range parts {
+		parts[i] = amount / models.Money(n)
+		if models.Money(i) < amount%models.Money(n) {
+			parts[i]++
+		}
+	}
+	return parts
+}
+
+// upload is a receipt received with a booking, already validated
+type upload struct {
+	Key         string
+	ContentType string
+	Data        []byte
+}
+
+// readReceipt returns the uploaded receipt, or nil if the customer sent none
+func (h *Handler) readReceipt(r *http.Request) (*upload, error) {
+	f, _, err := r.FormFile("receipt")
+	// Plain form posts can't carry a file
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if h.Receipts == nil {
+		return nil, errors.New("no se pueden recibir comprobantes en este momento")
+	}
+	data, contentType, err := receipts.Read(f)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{
+		Key:         receipts.NewKey(contentType, h.Now()),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// conflictError lists the numbers that were taken while the customer was booking
+type conflictError struct {
+	Numbers []string
+}
+
+func (e *conflictError) Error() string {
+	return "Números no disponibles: " + strings.Join(e.Numbers, ", ")
+}
+
 // Get Book Modal - Needs raffle ID implicitly from ticket or explicitly
-func GetBookModal(w http.ResponseWriter, r *http.Request) {
-	number := chi.URLParam(r, "number")
+func (h *Handler) GetBookModal(w http.ResponseWriter, r *http.Request) {
+	numbers, err := requestedNumbers(r)
+	if err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
 	raffleID, _ := strconv.ParseInt(r.URL.Query().Get("raffle_id"), 10, 64)
-	
-	var ticket models.Ticket
-	var raffle models.Raffle
-	
-	err := db.DB.QueryRow(`
-		SELECT t.id, t.number, t.status, r.id, r.ticket_price 
-		FROM tickets t 
-		JOIN raffles r ON t.raffle_id = r.id 
-		WHERE t.number = ? AND r.id = ?`, number, raffleID).Scan(&ticket.ID, &ticket.Number, &ticket.Status, &raffle.ID, &raffle.TicketPrice)
 
-	if err != nil {
+	raffle, err := h.Store.Raffles().Get(raffleID)
+	if err != nil || raffle.Status != "active" || len(numbers) == 0 {
 		http.Error(w, "Ticket not found", 404)
 		return
 	}
 
-	data := struct {
-		Ticket models.Ticket
-		Raffle models.Raffle
-	}{ticket, raffle}
+	var tickets []models.Ticket
+	for _, number := range numbers {
+		ticket, err := h.Store.Tickets().GetByNumber(raffle.ID, number)
+		if err != nil {
+			http.Error(w, "Ticket not found", 404)
+			return
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	total := raffle.TicketPrice * models.Money(len(tickets))
+
+	// The customer may pay in any currency with a rate loaded for today
+	var totals []models.CurrencyTotal
+	for _, currency := range models.Currencies {
+		converted, _, err := services.Convert(h.Store, total, raffle.Currency, currency, h.Now())
+		if errors.Is(err, services.ErrNoRate) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error converting price of raffle %d to %s: %v", raffle.ID, currency, err)
+			continue
+		}
+		totals = append(totals, models.CurrencyTotal{Currency: currency, Original: converted, Amount: total})
+	}
 
-	t, _ := template.ParseFiles("web/templates/book_modal.html")
+	// Only the public methods the raffle accepts, with their instructions
+	methods, err := services.RaffleMethods(h.Store, raffle.ID)
+	if err != nil {
+		log.Printf("Error listing payment methods of raffle %d: %v", raffle.ID, err)
+	}
+
+	data := struct {
+		Tickets []models.Ticket
+		Raffle  models.Raffle
+		Total   models.Money
+		Totals  []models.CurrencyTotal // Total in each currency the customer can pay in
+		Methods []models.PaymentMethod
+	}{tickets, raffle, total, totals, methods}
+
+	t, _ := template.New("book_modal.html").Funcs(template.FuncMap{"money": models.FormatMoney}).ParseFiles("web/templates/book_modal.html")
 	t.Execute(w, data)
 }
 
-// Process Booking
-func PostBook(w http.ResponseWriter, r *http.Request) {
-	number := chi.URLParam(r, "number")
-	raffleID, _ := strconv.ParseInt(r.URL.Query().Get("raffle_id"), 10, 64)
-	r.ParseForm()
-	
+// Process Booking - all selected numbers are reserved in one transaction, for
+// one customer, with the payment split across the tickets. If any number was
+// taken meanwhile nothing is saved and the conflicts are returned.
+func (h *Handler) PostBook(w http.ResponseWriter, r *http.Request) {
+	raffleID, err := strconv.ParseInt(r.URL.Query().Get("raffle_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Rifa inválida", 400)
+		return
+	}
+	numbers, err := requestedNumbers(r)
+	if err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	if len(numbers) == 0 {
+		http.Error(w, "Selecciona al menos un número", 400)
+		return
+	}
+
 	name := r.FormValue("name")
 	phone := r.FormValue("phone")
 	method := r.FormValue("method")
-	ref := r.FormValue("reference")
-	amountStr := r.FormValue("amount")
-	amount, _ := strconv.ParseFloat(amountStr, 64)
-
-	tx, _ := db.DB.Begin()
-
-	var ticketID int64
-	err := tx.QueryRow("SELECT id FROM tickets WHERE number = ? AND raffle_id = ? AND status = 'available'", number, raffleID).Scan(&ticketID)
-	if err != nil {
-		tx.Rollback()
-		http.Error(w, "Ticket no disponible", 400)
+	ref := strings.TrimSpace(r.FormValue("reference"))
+	currency := r.FormValue("currency") // Empty means the raffle's currency
+	amount, err := models.ParseMoney(r.FormValue("amount"))
+	if err != nil || amount < 0 {
+		http.Error(w, "Monto inválido", 400)
 		return
 	}
 
-	res, _ := tx.Exec("INSERT INTO users (name, phone) VALUES (?, ?)", name, phone)
-	userID, _ := res.LastInsertId()
-
-	_, err = tx.Exec("UPDATE tickets SET user_id = ?, status = 'reserved', reserved_at = CURRENT_TIMESTAMP WHERE id = ?", userID, ticketID)
-	_, err = tx.Exec("INSERT INTO payments (ticket_id, amount, method, reference) VALUES (?, ?, ?, ?)", ticketID, amount, method, ref)
+	// A payment must use a method the raffle offers, with its reference format
+	if amount > 0 {
+		pm, err := services.PublicMethod(h.Store, raffleID, method)
+		if err == nil {
+			err = services.CheckReference(pm, ref)
+		}
+		switch {
+		case errors.Is(err, services.ErrMethod), errors.Is(err, services.ErrReference):
+			http.Error(w, err.Error(), 400)
+			return
+		case err != nil:
+			log.Printf("Error checking payment method %q (raffle %d): %v", method, raffleID, err)
+			http.Error(w, "Error saving", 500)
+			return
+		}
+	}
 
+	receipt, err := h.readReceipt(r)
 	if err != nil {
-		tx.Rollback()
-		http.Error(w, "Error saving", 500)
+		http.Error(w, "Comprobante inválido: "+err.Error(), 400)
+		return
+	}
+	if receipt != nil && amount == 0 {
+		http.Error(w, "Indica el monto del comprobante", 400)
 		return
 	}
+	if receipt != nil {
+		if err := h.Receipts.Save(receipt.Key, receipt.ContentType, receipt.Data); err != nil {
+			log.Printf("Error saving receipt %s: %v", receipt.Key, err)
+			http.Error(w, "No se pudo guardar el comprobante", 500)
+			return
+		}
+	}
 
-	tx.Commit()
+	tgID := telegramID(r)
+	errUnavailable := errors.New("ticket no disponible")
+	bookedAt := h.Now()
+	var raffle models.Raffle
+	var credit models.Money // Paid from the customer's credit balance
+	alert := services.BookingAlert{RaffleID: raffleID}
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		var err error
+		raffle, err = tx.Raffles().Get(raffleID)
+		if errors.Is(err, store.ErrNotFound) || raffle.Status != "active" {
+			return errUnavailable
+		}
+		if err != nil {
+			return err
+		}
+		if currency == "" {
+			currency = raffle.Currency
+		}
 
-	// 5. Notify Admin via Telegram
-	notificationText := fmt.Sprintf("🎟️ *Nueva Reserva: #%s*\n👤 Cliente: %s\n📞 Telf: %s\n💰 Monto: $%v\n💳 Ref: %s\n\n_Rifa ID: %d_", 
-		number, name, phone, amount, ref, raffleID)
-	services.NotifyAdmin(notificationText)
-	
-	// HTMX: Tell the client to refresh the grid
-	w.Header().Set("HX-Trigger", "ticketBooked")
-	w.WriteHeader(http.StatusOK)
-}
+		var tickets []models.Ticket
+		conflicts := &conflictError{}
+		for _, number := range numbers {
+			ticket, err := tx.Tickets().GetByNumber(raffle.ID, number)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+			if err != nil || ticket.Status != "available" {
+				conflicts.Numbers = append(conflicts.Numbers, number)
+				continue
+			}
+			tickets = append(tickets, ticket)
+		}
+		if len(conflicts.Numbers) > 0 {
+			return conflicts
+		}
 
-// Helper to fetch tickets
-func getTickets(raffleID int64, query string) ([]models.Ticket, error) {
-	sqlQuery := "SELECT number, status FROM tickets WHERE raffle_id = ?"
-	args := []interface{}{raffleID}
+		user, err := services.FindOrCreateUser(tx, name, phone, tgID)
+		if err != nil {
+			return err
+		}
+		alert.UserID = user.ID
+		alert.TicketIDs, alert.PaymentIDs = nil, nil
+		credit = 0
+		// Only the customer's own Telegram account spends their credit: a phone
+		// number alone may be someone else's
+		ownCredit := tgID != nil && user.TelegramID != nil && *user.TelegramID == *tgID
+
+		parts := splitAmount(amount, len(tickets))
+		for i, ticket := range tickets {
+			// Reserve only updates rows still 'available', so a concurrent
+			// booking that got there first makes this one fail as a conflict.
+			if err := tx.Tickets().Reserve(ticket.ID, user.ID, bookedAt); err != nil {
+				if errors.Is(err, store.ErrConflict) {
+					return &conflictError{Numbers: []string{ticket.Number}}
+				}
+				return err
+			}
+			alert.TicketIDs = append(alert.TicketIDs, ticket.ID)
+
+			// The reported amount may be in another currency (e.g. bolívares)
+			var converted models.Money
+			var rate float64
+			if parts[i] > 0 {
+				if converted, rate, err = services.Convert(tx, parts[i], currency, raffle.Currency, bookedAt); err != nil {
+					return err
+				}
+			}
+
+			// Stored credit (e.g. from a released number) pays what the customer didn't report
+			if ownCredit {
+				used, err := services.UseCredit(tx, ticket.ID, raffle.TicketPrice-converted, services.ActorBooking, bookedAt)
+				if err != nil {
+					return err
+				}
+				credit += used
+			}
+
+			// Nothing to verify if the customer only reserved
+			if parts[i] == 0 {
+				continue
+			}
+			payment := models.Payment{
+				TicketID:       ticket.ID,
+				Amount:         converted,
+				Method:         method,
+				Reference:      ref,
+				Status:         "pending",
+				Currency:       currency,
+				OriginalAmount: parts[i],
+				Rate:           rate,
+			}
+			if receipt != nil {
+				payment.ReceiptKey = receipt.Key
+				payment.ReceiptType = receipt.ContentType
+			}
+			if err := tx.Payments().Create(&payment); err != nil {
+				return err
+			}
+			alert.PaymentIDs = append(alert.PaymentIDs, payment.ID)
+		}
 
-	if query != "" {
-		sqlQuery += " AND number LIKE ?"
-		args = append(args, "%"+query+"%")
-	}
-	
-	sqlQuery += " ORDER BY number ASC"
+		// 5. Notify Admin via Telegram. Queued with the booking, so the alert
+		// is neither lost nor sent for a booking that was rolled back.
+		title := "Nueva Reserva: #" + numbers[0]
+		if len(numbers) > 1 {
+			title = fmt.Sprintf("Nueva Reserva (%d números): #%s", len(numbers), strings.Join(numbers, ", #"))
+		}
+		alert.Text = fmt.Sprintf("🎟️ *%s*\n👤 Cliente: %s\n📞 Telf: %s\n💰 Monto: %s\n💳 Ref: %s\n\n_Rifa ID: %d_",
+			title, name, phone, models.FormatMoney(amount, currency), ref, raffleID)
+		if credit > 0 {
+			alert.Text += "\n💵 Saldo a favor usado: " + raffle.Money(credit)
+		}
+		if err := services.QueueAdminBooking(tx, alert); err != nil {
+			return err
+		}
+		// Only Mini App bookings have a Telegram chat to confirm to
+		h.Customers.In(tx).Booked(tgID, raffle, numbers, amount, currency, credit, bookedAt)
 
+		// The payment goes to the admins who verify transfers
+		if amount == 0 {
+			return nil
+		}
+		paymentText := fmt.Sprintf("⏳ Pago por verificar: %s de %s (%s) por #%s\n💳 Ref: %s",
+			models.FormatMoney(amount, currency), name, phone, strings.Join(numbers, ", #"), ref)
+		if receipt != nil {
+			return services.QueueAdminReceipt(tx, paymentText, receipt.Key, receipt.ContentType)
+		}
+		return services.QueueAdmin(tx, models.NotifyPayments, paymentText)
+	})
 
-rows, err := db.DB.Query(sqlQuery, args...)
-	if err != nil {
-		return nil, err
+	// Nothing points to the receipt if the booking failed
+	if err != nil && receipt != nil {
+		if err := h.Receipts.Delete(receipt.Key); err != nil {
+			log.Printf("Error deleting receipt %s: %v", receipt.Key, err)
+		}
 	}
-	defer rows.Close()
 
-	var tickets []models.Ticket
-	for rows.Next() {
-		var t models.Ticket
-		rows.Scan(&t.Number, &t.Status)
-		tickets = append(tickets, t)
+	var conflicts *conflictError
+	if errors.As(err, &conflicts) {
+		http.Error(w, conflicts.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errUnavailable) {
+		http.Error(w, "Ticket no disponible", 400)
+		return
+	}
+	if errors.Is(err, services.ErrCurrency) || errors.Is(err, services.ErrNoRate) {
+		http.Error(w, err.Error(), 400)
+		return
 	}
-	return tickets, nil
+	if err != nil {
+		log.Printf("Error booking tickets %v (raffle %d): %v", numbers, raffleID, err)
+		http.Error(w, "Error saving", 500)
+		return
+	}
+
+	// HTMX: Tell the client to refresh the grid
+	w.Header().Set("HX-Trigger", "ticketBooked")
+	w.WriteHeader(http.StatusOK)
 }
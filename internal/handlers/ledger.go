@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// Correcciones del libro de pagos desde el modal del ticket. Responden "OK" o
+// el error en texto para que el JS lo muestre.
+
+// AdminReversePayment anula un pago verificado cargado por error; el pago
+// queda en el historial junto a su reversión.
+func (h *Handler) AdminReversePayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Pago inválido", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	admin := tgmiddleware.AdminIdentity(r)
+
+	reversal, err := services.ReversePayment(h.Store, paymentID, admin, r.FormValue("reason"), h.Now())
+	if !ledgerError(w, err) {
+		return
+	}
+	log.Printf("Pago %d revertido por %s: %s", paymentID, admin, reversal.Reason)
+	fmt.Fprint(w, "OK")
+}
+
+// AdminRefundTicket devuelve al cliente parte de lo cobrado en el ticket sin
+// liberarlo (ej: pagó de más)
+func (h *Handler) AdminRefundTicket(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Ticket inválido", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	amount, err := models.ParseMoney(r.FormValue("amount"))
+	if err != nil {
+		http.Error(w, "Monto inválido", 400)
+		return
+	}
+	admin := tgmiddleware.AdminIdentity(r)
+
+	_, err = services.RefundTicket(h.Store, ticketID, amount, r.FormValue("method"), r.FormValue("reason"), admin, h.Now())
+	if !ledgerError(w, err) {
+		return
+	}
+	log.Printf("Devolución de %s del ticket %d registrada por %s", amount, ticketID, admin)
+	fmt.Fprint(w, "OK")
+}
+
+// ledgerError responde el error de una operación sobre el libro de pagos.
+// Devuelve true si no hubo error.
+func ledgerError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "No encontrado", 404)
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "El ticket cambió de estado, recarga la página", http.StatusConflict)
+	case errors.Is(err, services.ErrNotReversible), errors.Is(err, services.ErrAlreadyReversed),
+		errors.Is(err, services.ErrInvalidAmount), errors.Is(err, services.ErrOverBalance),
+		errors.Is(err, services.ErrReasonRequired), errors.Is(err, services.ErrRefundMethod),
+		errors.Is(err, services.ErrReleaseMode), errors.Is(err, services.ErrTransferTarget),
+		errors.Is(err, services.ErrSalesClosed), errors.Is(err, services.ErrCurrencyMismatch):
+		http.Error(w, err.Error(), 400)
+	default:
+		log.Printf("Error updating payment ledger: %v", err)
+		http.Error(w, "Error guardando el cambio", 500)
+	}
+	return false
+}
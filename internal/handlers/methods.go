@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// MethodsData is the data for the payment methods page (owners)
+type MethodsData struct {
+	Title        string
+	RaffleName   string // Shown in the layout header
+	Admin        models.Admin
+	CSRF         string
+	Methods      []models.PaymentMethod
+	NextPosition int             // Default position of a new method, after the last one
+	Raffles      []RaffleMethods // Active and closed raffles
+}
+
+// RaffleMethods is a raffle with the methods it enabled; none enabled means
+// it offers every public method
+type RaffleMethods struct {
+	Raffle  models.Raffle
+	Enabled map[string]bool
+}
+
+// AdminMethods shows the payment methods catalog and which ones each raffle offers
+func (h *Handler) AdminMethods(w http.ResponseWriter, r *http.Request) {
+	methods, err := h.Store.Methods().List()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	raffles, err := h.Store.Raffles().List("active", "closed")
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data := MethodsData{
+		Title:      "Métodos de pago",
+		RaffleName: "Métodos de pago",
+		Admin:      tgmiddleware.CurrentAdmin(r),
+		CSRF:       tgmiddleware.CSRFToken(r),
+		Methods:    methods,
+	}
+	for _, m := range methods {
+		data.NextPosition = max(data.NextPosition, m.Position+1)
+	}
+	for _, raffle := range raffles {
+		codes, err := h.Store.Methods().ForRaffle(raffle.ID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		rm := RaffleMethods{Raffle: raffle, Enabled: map[string]bool{}}
+		for _, code := range codes {
+			rm.Enabled[code] = true
+		}
+		data.Raffles = append(data.Raffles, rm)
+	}
+
+	t, err := template.ParseFiles("web/templates/layout.html", "web/templates/methods.html")
+	if err != nil {
+		log.Printf("Error parsing methods templates: %v", err)
+		http.Error(w, "Template Parse Error", 500)
+		return
+	}
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Error executing methods template: %v", err)
+		http.Error(w, "Template Exec Error", 500)
+	}
+}
+
+// AdminSaveMethod creates a method of the catalog or updates the one with the
+// same code
+func (h *Handler) AdminSaveMethod(w http.ResponseWriter, r *http.Request) {
+	position, _ := strconv.Atoi(r.FormValue("position"))
+	m := models.PaymentMethod{
+		Code:         r.FormValue("code"),
+		Name:         r.FormValue("name"),
+		Instructions: r.FormValue("instructions"),
+		RefPattern:   r.FormValue("ref_pattern"),
+		RefHint:      r.FormValue("ref_hint"),
+		Public:       r.FormValue("public") != "",
+		Position:     position,
+	}
+	by := tgmiddleware.AdminIdentity(r)
+
+	m, err := services.SaveMethod(h.Store, m, by, h.Now())
+	switch {
+	case errors.Is(err, services.ErrMethodCode), errors.Is(err, services.ErrMethodName), errors.Is(err, services.ErrRefPattern):
+		http.Error(w, err.Error(), 400)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Método de pago %s guardado por %s", m.Code, by)
+	http.Redirect(w, r, "/admin/methods", http.StatusSeeOther)
+}
+
+// AdminSetRaffleMethods chooses the public methods a raffle offers (method
+// checkboxes); none checked offers them all
+func (h *Handler) AdminSetRaffleMethods(w http.ResponseWriter, r *http.Request) {
+	raffleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Rifa inválida", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	by := tgmiddleware.AdminIdentity(r)
+
+	err = services.SetRaffleMethods(h.Store, raffleID, r.Form["method"], by, h.Now())
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Rifa no encontrada", 404)
+		return
+	case errors.Is(err, services.ErrMethod):
+		http.Error(w, err.Error(), 400)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Métodos de pago de la rifa %d cambiados por %s: %v", raffleID, by, r.Form["method"])
+	http.Redirect(w, r, "/admin/methods", http.StatusSeeOther)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// AdminRetryNotification vuelve a encolar una notificación que no se pudo
+// entregar; el worker del outbox la envía en la próxima pasada.
+func (h *Handler) AdminRetryNotification(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Notificación inválida", 400)
+		return
+	}
+
+	admin := tgmiddleware.AdminIdentity(r)
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		if err := tx.Outbox().Retry(id, h.Now()); err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: admin, Action: models.AuditOutboxRetry, Target: fmt.Sprintf("outbox:%d", id), CreatedAt: h.Now()}
+		return services.Audit(tx, e, map[string]string{"status": "failed"}, map[string]string{"status": "pending"})
+	})
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "La notificación ya no está fallida, recarga la página", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Notificación %d reintentada por %s", id, admin)
+	redirectBack(w, r)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/receipts"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// AdminVerifyPayment aprueba una transferencia reportada por el cliente
+func (h *Handler) AdminVerifyPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Pago inválido", 400)
+		return
+	}
+	admin := tgmiddleware.AdminIdentity(r)
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		if err := services.VerifyPayment(tx, paymentID, admin, h.Now()); err != nil {
+			return err
+		}
+		h.Customers.In(tx).PaymentReviewed(paymentID)
+		return nil
+	})
+	if !h.reviewError(w, err) {
+		return
+	}
+
+	log.Printf("Pago %d verificado por %s", paymentID, admin)
+	redirectBack(w, r)
+}
+
+// AdminRejectPayment rechaza una transferencia que no llegó o no coincide.
+// El pago queda en el historial con el motivo y no cuenta para el saldo.
+func (h *Handler) AdminRejectPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Pago inválido", 400)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", 400)
+		return
+	}
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		http.Error(w, "Indica el motivo del rechazo", 400)
+		return
+	}
+	admin := tgmiddleware.AdminIdentity(r)
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		if err := services.RejectPayment(tx, paymentID, admin, reason, h.Now()); err != nil {
+			return err
+		}
+		h.Customers.In(tx).PaymentReviewed(paymentID)
+		return nil
+	})
+	if !h.reviewError(w, err) {
+		return
+	}
+
+	log.Printf("Pago %d rechazado por %s: %s", paymentID, admin, reason)
+	redirectBack(w, r)
+}
+
+// AdminGetReceipt muestra el comprobante que subió el cliente con el pago
+func (h *Handler) AdminGetReceipt(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Pago inválido", 400)
+		return
+	}
+
+	p, err := h.Store.Payments().Get(paymentID)
+	if err != nil || !p.HasReceipt() || h.Receipts == nil {
+		http.Error(w, "Comprobante no encontrado", 404)
+		return
+	}
+
+	f, err := h.Receipts.Open(p.ReceiptKey)
+	if errors.Is(err, receipts.ErrNotFound) {
+		http.Error(w, "Comprobante no encontrado", 404)
+		return
+	}
+	if err != nil {
+		log.Printf("Error opening receipt %s: %v", p.ReceiptKey, err)
+		http.Error(w, "Error leyendo el comprobante", 500)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", p.ReceiptType)
+	w.Header().Set("Content-Disposition", "inline; filename=\""+path.Base(p.ReceiptKey)+"\"")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, f)
+}
+
+// reviewError responde al error de una revisión; devuelve true si no hubo error
+func (h *Handler) reviewError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Pago no encontrado", 404)
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "El pago ya fue revisado", http.StatusConflict)
+	default:
+		log.Printf("Error reviewing payment: %v", err)
+		http.Error(w, "Error guardando la revisión", 500)
+	}
+	return false
+}
+
+// redirectBack vuelve a la página desde la que se envió el formulario
+func redirectBack(w http.ResponseWriter, r *http.Request) {
+	redirect := r.Header.Get("Referer")
+	if redirect == "" {
+		redirect = "/admin"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+)
+
+// ratesPageSize is how many rates the rates page lists, newest first
+const ratesPageSize = 100
+
+// RatesData is the data for the exchange rates page (cashiers and owners)
+type RatesData struct {
+	Title      string
+	RaffleName string // Shown in the layout header
+	Admin      models.Admin
+	CSRF       string
+	Rates      []models.ExchangeRate
+	Currencies []string
+	Default    string // Base currency preselected in the form
+	Today      string // Default date of a new rate (YYYY-MM-DD)
+}
+
+// AdminRates shows the loaded exchange rates and the forms to add or import them
+func (h *Handler) AdminRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.Store.Rates().List(ratesPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	data := RatesData{
+		Title:      "Tasas",
+		RaffleName: "Tasas de cambio",
+		Admin:      tgmiddleware.CurrentAdmin(r),
+		CSRF:       tgmiddleware.CSRFToken(r),
+		Rates:      rates,
+		Currencies: models.Currencies,
+		Default:    models.DefaultCurrency,
+		Today:      h.Now().Format("2006-01-02"),
+	}
+
+	t, err := template.ParseFiles("web/templates/layout.html", "web/templates/rates.html")
+	if err != nil {
+		log.Printf("Error parsing rates templates: %v", err)
+		http.Error(w, "Template Parse Error", 500)
+		return
+	}
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Error executing rates template: %v", err)
+		http.Error(w, "Template Exec Error", 500)
+	}
+}
+
+// AdminAddRate loads the rate of the day: 1 base = rate currency from date
+// (YYYY-MM-DD, empty means now)
+func (h *Handler) AdminAddRate(w http.ResponseWriter, r *http.Request) {
+	rate, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(r.FormValue("rate")), ",", "."), 64)
+	if err != nil {
+		http.Error(w, services.ErrInvalidRate.Error(), 400)
+		return
+	}
+	rt := models.ExchangeRate{
+		Currency: r.FormValue("currency"),
+		Base:     r.FormValue("base"),
+		Rate:     rate,
+	}
+	if v := r.FormValue("date"); v != "" {
+		if rt.ValidFrom, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			http.Error(w, "Fecha inválida", 400)
+			return
+		}
+	}
+	by := tgmiddleware.AdminIdentity(r)
+
+	rt, err = services.AddRate(h.Store, rt, by, h.Now())
+	switch {
+	case errors.Is(err, services.ErrCurrency), errors.Is(err, services.ErrInvalidRate):
+		http.Error(w, err.Error(), 400)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Tasa 1 %s = %v %s cargada por %s", rt.Base, rt.Rate, rt.Currency, by)
+	http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+}
+
+// AdminImportRates loads the rates of a CSV file (see services.ImportRates)
+func (h *Handler) AdminImportRates(w http.ResponseWriter, r *http.Request) {
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Adjunta el archivo CSV de tasas", 400)
+		return
+	}
+	defer f.Close()
+	by := tgmiddleware.AdminIdentity(r)
+
+	n, err := services.ImportRates(h.Store, f, by, h.Now())
+	switch {
+	case errors.Is(err, services.ErrRatesFile), errors.Is(err, services.ErrCurrency), errors.Is(err, services.ErrInvalidRate):
+		http.Error(w, err.Error(), 400)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("%d tasas importadas por %s", n, by)
+	http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+}
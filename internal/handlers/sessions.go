@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/store"
+)
+
+// AdminCreateSession cambia el initData de la Mini App (firmado y reciente)
+// por la cookie de sesión del panel
+func (h *Handler) AdminCreateSession(w http.ResponseWriter, r *http.Request) {
+	user, err := tgmiddleware.ValidateLogin(r.FormValue("init_data"), h.Now())
+	if err != nil {
+		log.Printf("Login de admin rechazado: %v", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	admin, err := h.Store.Admins().Get(user.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		log.Printf("Usuario Telegram no es admin: %d", user.ID)
+		http.Error(w, "Tu cuenta de Telegram no es admin", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if err := tgmiddleware.StartSession(w, h.Store.Sessions(), admin, user.FirstName, h.Now()); err != nil {
+		log.Printf("Error creando sesión para %d: %v", user.ID, err)
+		http.Error(w, "Error interno", 500)
+		return
+	}
+	log.Printf("Admin Telegram autenticado: %s (ID: %d, rol: %s)", user.FirstName, user.ID, admin.Role)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminLogout cierra la sesión en el servidor y borra la cookie
+func (h *Handler) AdminLogout(w http.ResponseWriter, r *http.Request) {
+	if err := tgmiddleware.EndSession(w, r, h.Store.Sessions()); err != nil {
+		log.Printf("Error cerrando sesión de %s: %v", tgmiddleware.AdminIdentity(r), err)
+		http.Error(w, "Error interno", 500)
+		return
+	}
+	log.Printf("Sesión cerrada por %s", tgmiddleware.AdminIdentity(r))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	tgmiddleware "lotto-tg-app/internal/middleware"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/services"
+	"lotto-tg-app/internal/store"
+)
+
+// telegramID devuelve el ID del cliente si la reserva viene de la Mini App
+func telegramID(r *http.Request) *int64 {
+	if tgUser := tgmiddleware.TelegramUserFromRequest(r); tgUser != nil {
+		return &tgUser.ID
+	}
+	return nil
+}
+
+// AdminMergeUsers une clientes duplicados en keep_id: sus tickets pasan al
+// cliente principal y los duplicados se eliminan.
+func (h *Handler) AdminMergeUsers(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	keepID, err := strconv.ParseInt(r.FormValue("keep_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Cliente principal inválido", 400)
+		return
+	}
+
+	var mergeIDs []int64
+	for _, v := range r.Form["merge_ids"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Cliente a unir inválido", 400)
+			return
+		}
+		if id != keepID {
+			mergeIDs = append(mergeIDs, id)
+		}
+	}
+	if len(mergeIDs) == 0 {
+		http.Error(w, "No hay clientes para unir", 400)
+		return
+	}
+
+	admin := tgmiddleware.AdminIdentity(r)
+
+	err = h.Store.Tx(func(tx store.Store) error {
+		// Los clientes como estaban, para el audit log
+		var before []models.User
+		for _, id := range append([]int64{keepID}, mergeIDs...) {
+			u, err := tx.Users().Get(id)
+			if err != nil {
+				return err
+			}
+			before = append(before, u)
+		}
+		if err := tx.Users().Merge(keepID, mergeIDs); err != nil {
+			return err
+		}
+		after, err := tx.Users().Get(keepID)
+		if err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: admin, Action: models.AuditUserMerge, Target: fmt.Sprintf("user:%d", keepID), CreatedAt: h.Now()}
+		return services.Audit(tx, e, before, after)
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Cliente no encontrado", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	log.Printf("Clientes %v unidos en %d por %s", mergeIDs, keepID, admin)
+	redirectBack(w, r)
+}
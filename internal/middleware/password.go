@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Login con usuario y contraseña (BasicAuth) contra admins.password_hash.
+// Los intentos fallidos se cuentan por IP y por usuario en login_attempts;
+// al superar el límite dentro de la ventana, esa IP o usuario queda
+// bloqueado un rato aunque después acierte la contraseña.
+const (
+	loginWindow     = 15 * time.Minute
+	loginLockout    = 15 * time.Minute
+	maxUserFailures = 5
+	maxIPFailures   = 20
+)
+
+var (
+	errBadPassword = errors.New("usuario o contraseña incorrectos")
+	errLocked      = errors.New("demasiados intentos fallidos")
+)
+
+// dummyHash se compara cuando el usuario no existe, para que la respuesta
+// tarde lo mismo y no revele qué usuarios hay
+var dummyHash = sync.OnceValue(func() []byte {
+	h, _ := bcrypt.GenerateFromPassword([]byte("usuario-inexistente"), bcrypt.DefaultCost)
+	return h
+})
+
+// clientIP es la IP del request. Detrás de un proxy (App Platform, Fly...)
+// RemoteAddr es el proxy: con TRUST_PROXY=true se usa X-Forwarded-For. Se
+// toma la última entrada, la que agregó el proxy; las anteriores las manda
+// el cliente y podría cambiarlas para esquivar el bloqueo por IP.
+func clientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY") == "true" {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			last := fwd[strings.LastIndex(fwd, ",")+1:]
+			return strings.TrimSpace(last)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// passwordLogin valida usuario y contraseña. Devuelve errLocked (con hasta
+// cuándo) si la IP o el usuario están bloqueados, errBadPassword si no
+// coinciden, o el admin.
+func passwordLogin(st store.Store, ip, username, password string, now time.Time) (models.Admin, time.Time, error) {
+	keys := []string{"ip:" + ip, "user:" + username}
+	for _, key := range keys {
+		a, err := st.LoginAttempts().Get(key)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return models.Admin{}, time.Time{}, err
+		}
+		if a.LockedUntil != nil && a.LockedUntil.After(now) {
+			return models.Admin{}, *a.LockedUntil, errLocked
+		}
+	}
+
+	admin, err := st.Admins().GetByUsername(username)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return models.Admin{}, time.Time{}, err
+	}
+	// Sin contraseña configurada (o vacía) el login por contraseña está deshabilitado
+	if err != nil || !admin.HasPassword() || password == "" {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+	} else if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) == nil {
+		if err := st.LoginAttempts().Delete("user:" + username); err != nil {
+			log.Printf("Error limpiando intentos de %q: %v", username, err)
+		}
+		return admin, time.Time{}, nil
+	}
+
+	for _, key := range keys {
+		if err := recordFailure(st, key, now); err != nil {
+			return models.Admin{}, time.Time{}, err
+		}
+	}
+	return models.Admin{}, time.Time{}, errBadPassword
+}
+
+// recordFailure suma un intento fallido y bloquea la clave al llegar al límite.
+// Lee y guarda en una transacción para que dos intentos simultáneos no
+// cuenten como uno.
+func recordFailure(st store.Store, key string, now time.Time) error {
+	return st.Tx(func(tx store.Store) error {
+		a, err := tx.LoginAttempts().Get(key)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if err != nil || now.Sub(a.FirstFailedAt) > loginWindow {
+			a = models.LoginAttempt{Key: key, FirstFailedAt: now}
+		}
+		a.Failures++
+
+		limit := maxUserFailures
+		if strings.HasPrefix(key, "ip:") {
+			limit = maxIPFailures
+		}
+		log.Printf("Login con contraseña fallido (%s, intento %d de %d)", key, a.Failures, limit)
+		if a.Failures >= limit {
+			until := now.Add(loginLockout)
+			a.LockedUntil = &until
+			log.Printf("Login bloqueado para %s hasta %s", key, until.Format("15:04"))
+		}
+		return tx.LoginAttempts().Save(a)
+	})
+}
+
+// checkBasicAuth autentica el header Authorization: Basic. handled es true si
+// ya respondió (bloqueo o error); sin header devuelve ok=false y handled=false.
+func checkBasicAuth(w http.ResponseWriter, r *http.Request, st store.Store) (s session, ok, handled bool) {
+	username, password, hasAuth := r.BasicAuth()
+	if !hasAuth {
+		return session{}, false, false
+	}
+
+	admin, lockedUntil, err := passwordLogin(st, clientIP(r), username, password, time.Now())
+	switch {
+	case errors.Is(err, errLocked):
+		wait := time.Until(lockedUntil).Round(time.Minute)
+		w.Header().Set("Retry-After", fmt.Sprint(int(time.Until(lockedUntil).Seconds())+1))
+		http.Error(w, fmt.Sprintf("Demasiados intentos fallidos, intenta de nuevo en %s", wait), http.StatusTooManyRequests)
+		return session{}, false, true
+	case errors.Is(err, errBadPassword):
+		return session{}, false, false
+	case err != nil:
+		log.Printf("Error verificando contraseña de %q: %v", username, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return session{}, false, true
+	}
+
+	if admin.Name == "" {
+		admin.Name = admin.Username
+	}
+	return session{
+		identity: fmt.Sprintf("tg:%d %s", admin.TelegramID, admin.Username),
+		admin:    admin,
+		// Cambia si cambia la contraseña
+		csrf: sign("csrf:" + admin.Username + ":" + admin.PasswordHash),
+	}, true, false
+}
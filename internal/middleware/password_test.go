@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/admin", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.7")
+
+	t.Setenv("TRUST_PROXY", "")
+	if ip := clientIP(r); ip != "10.0.0.1" {
+		t.Fatalf("sin TRUST_PROXY: %s, quería 10.0.0.1", ip)
+	}
+
+	// La primera entrada la inventa el cliente; la última es la del proxy
+	t.Setenv("TRUST_PROXY", "true")
+	if ip := clientIP(r); ip != "203.0.113.7" {
+		t.Fatalf("con TRUST_PROXY: %s, quería 203.0.113.7", ip)
+	}
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if ip := clientIP(r); ip != "203.0.113.7" {
+		t.Fatalf("una sola entrada: %s, quería 203.0.113.7", ip)
+	}
+}
+
+func TestRecordFailureLocks(t *testing.T) {
+	st := memstore.New()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxUserFailures; i++ {
+		if err := recordFailure(st, "user:ana", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := st.LoginAttempts().Get("user:ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Failures != maxUserFailures || a.LockedUntil == nil || !a.LockedUntil.Equal(now.Add(loginLockout)) {
+		t.Fatalf("intento = %+v, quería bloqueado hasta %v", a, now.Add(loginLockout))
+	}
+
+	// Pasada la ventana se empieza a contar de nuevo
+	later := now.Add(loginWindow + time.Minute)
+	if err := recordFailure(st, "user:ana", later); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := st.LoginAttempts().Get("user:ana"); a.Failures != 1 || a.LockedUntil != nil {
+		t.Fatalf("intento = %+v, quería 1 fallo sin bloqueo", a)
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+)
+
+// RequireRole deja pasar solo a los admins con al menos ese rol
+// (models.RoleOwner, RoleCashier o RoleViewer). Va después de TelegramAdminAuth.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !CurrentAdmin(r).Can(role) {
+				log.Printf("Acceso denegado a %s %s para %s: requiere rol %s", r.Method, r.URL.Path, AdminIdentity(r), role)
+				http.Error(w, "Acceso denegado: tu rol no permite esta acción", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestRequireRole(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "secreto-de-test")
+	st := memstore.New()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// Cada rol pasa por las rutas de su nivel y de los de abajo, nunca arriba
+	cases := []struct {
+		admin, route string
+		want         int
+	}{
+		{models.RoleViewer, models.RoleViewer, http.StatusOK},
+		{models.RoleViewer, models.RoleCashier, http.StatusForbidden},
+		{models.RoleViewer, models.RoleOwner, http.StatusForbidden},
+		{models.RoleCashier, models.RoleViewer, http.StatusOK},
+		{models.RoleCashier, models.RoleCashier, http.StatusOK},
+		{models.RoleCashier, models.RoleOwner, http.StatusForbidden},
+		{models.RoleOwner, models.RoleViewer, http.StatusOK},
+		{models.RoleOwner, models.RoleCashier, http.StatusOK},
+		{models.RoleOwner, models.RoleOwner, http.StatusOK},
+	}
+	for i, c := range cases {
+		admin := models.Admin{TelegramID: int64(i + 1), Name: c.admin, Role: c.admin}
+		if err := st.Admins().Save(admin); err != nil {
+			t.Fatal(err)
+		}
+		h := TelegramAdminAuth(st)(RequireRole(c.route)(ok))
+		if w := serve(h, "GET", login(t, st, admin, time.Now()), "", nil); w.Code != c.want {
+			t.Errorf("%s en ruta de %s: %d, quería %d", c.admin, c.route, w.Code, c.want)
+		}
+	}
+
+	// Sin sesión el Admin vacío no tiene ningún permiso
+	if w := serve(RequireRole(models.RoleViewer)(ok), "GET", nil, "", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("sin admin: %d, quería 403", w.Code)
+	}
+	// Un rol desconocido en la tabla tampoco
+	odd := models.Admin{TelegramID: 99, Name: "Raro", Role: "superadmin"}
+	if err := st.Admins().Save(odd); err != nil {
+		t.Fatal(err)
+	}
+	h := TelegramAdminAuth(st)(RequireRole(models.RoleViewer)(ok))
+	if w := serve(h, "GET", login(t, st, odd, time.Now()), "", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("rol desconocido: %d, quería 403", w.Code)
+	}
+}
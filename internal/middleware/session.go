@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// El login desde la Mini App cambia el initData (validado y reciente) por una
+// sesión: cookie HttpOnly con un token firmado, cuyo hash se guarda en
+// admin_sessions para poder cerrarla desde el servidor.
+const (
+	SessionCookie = "admin_session"
+	SessionTTL    = 12 * time.Hour
+	// LoginMaxAge es la antigüedad máxima del auth_date del initData al hacer login
+	LoginMaxAge = time.Hour
+
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+var (
+	// ErrStaleInitData se devuelve cuando el initData es viejo (o sin auth_date)
+	ErrStaleInitData = errors.New("initData vencido, vuelve a abrir la Mini App")
+	// ErrInvalidInitData se devuelve cuando la firma del initData no es válida
+	ErrInvalidInitData = errors.New("initData inválido")
+)
+
+// sessionSecret firma las cookies y el token CSRF de BasicAuth. Sin
+// SESSION_SECRET se deriva del token del bot, igual en todas las instancias.
+func sessionSecret() []byte {
+	if s := os.Getenv("SESSION_SECRET"); s != "" {
+		return []byte(s)
+	}
+	sum := sha256.Sum256([]byte("session:" + os.Getenv("TELEGRAM_TOKEN")))
+	return sum[:]
+}
+
+func sign(value string) string {
+	mac := hmac.New(sha256.New, sessionSecret())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sessionID es lo que se guarda en la tabla en lugar del token
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateLogin valida el initData como ValidateInitData y además exige que
+// auth_date no tenga más de LoginMaxAge, para que un initData filtrado no
+// sirva para abrir sesiones después.
+func ValidateLogin(initData string, now time.Time) (*TelegramUser, error) {
+	user, valid := ValidateInitData(initData)
+	if !valid {
+		return nil, ErrInvalidInitData
+	}
+	params, _ := url.ParseQuery(initData)
+	authDate, err := strconv.ParseInt(params.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, ErrStaleInitData
+	}
+	age := now.Sub(time.Unix(authDate, 0))
+	if age > LoginMaxAge || age < -time.Minute {
+		return nil, ErrStaleInitData
+	}
+	return user, nil
+}
+
+// StartSession crea la sesión del admin y envía la cookie
+func StartSession(w http.ResponseWriter, sessions store.SessionStore, admin models.Admin, name string, now time.Time) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return err
+	}
+	s := models.AdminSession{
+		ID:         sessionID(token),
+		TelegramID: admin.TelegramID,
+		Name:       name,
+		CSRFToken:  csrf,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(SessionTTL),
+	}
+	// Aprovecha el login para limpiar las vencidas
+	if err := sessions.DeleteExpired(now); err != nil {
+		log.Printf("Error borrando sesiones vencidas: %v", err)
+	}
+	if err := sessions.Create(s); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token + "." + sign(token),
+		Path:     "/admin",
+		Expires:  s.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// La cookie con el initData crudo de versiones anteriores ya no se usa
+	http.SetCookie(w, &http.Cookie{Name: "tg_init_data", Path: "/", MaxAge: -1})
+	return nil
+}
+
+// EndSession borra la sesión del request (si hay) y la cookie
+func EndSession(w http.ResponseWriter, r *http.Request, sessions store.SessionStore) error {
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Path: "/admin", MaxAge: -1, HttpOnly: true, Secure: true})
+	token, ok := sessionToken(r)
+	if !ok {
+		return nil
+	}
+	return sessions.Delete(sessionID(token))
+}
+
+// sessionToken devuelve el token de la cookie si la firma es válida
+func sessionToken(r *http.Request) (string, bool) {
+	c, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return "", false
+	}
+	token, mac, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(sign(token))) {
+		return "", false
+	}
+	return token, true
+}
+
+// loadSession busca la sesión de la cookie y su admin. ok es false si no hay
+// sesión válida: cookie ausente o mal firmada, sesión cerrada o vencida, o
+// admin revocado.
+func loadSession(r *http.Request, st store.Store, now time.Time) (models.AdminSession, models.Admin, bool, error) {
+	token, ok := sessionToken(r)
+	if !ok {
+		return models.AdminSession{}, models.Admin{}, false, nil
+	}
+	s, err := st.Sessions().Get(sessionID(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return s, models.Admin{}, false, nil
+	}
+	if err != nil {
+		return s, models.Admin{}, false, err
+	}
+	if !s.ExpiresAt.After(now) {
+		return s, models.Admin{}, false, nil
+	}
+	// El rol se lee en cada request: un cambio de rol aplica de inmediato
+	admin, err := st.Admins().Get(s.TelegramID)
+	if errors.Is(err, store.ErrNotFound) {
+		return s, admin, false, nil
+	}
+	if err != nil {
+		return s, admin, false, err
+	}
+	if admin.Name == "" {
+		admin.Name = s.Name
+	}
+	return s, admin, true, nil
+}
+
+// CSRFToken devuelve el token que los formularios del panel tienen que
+// enviar en el campo csrf_token (o el header X-CSRF-Token)
+func CSRFToken(r *http.Request) string {
+	s, _ := r.Context().Value(adminKey).(session)
+	return s.csrf
+}
+
+// CSRF rechaza los POST (y demás métodos que modifican) sin el token de la
+// sesión. Va después de TelegramAdminAuth.
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		expected := CSRFToken(r)
+		got := r.Header.Get(csrfHeader)
+		if got == "" {
+			got = r.FormValue(csrfField)
+		}
+		if expected == "" || !hmac.Equal([]byte(got), []byte(expected)) {
+			log.Printf("Token CSRF inválido en %s %s de %s", r.Method, r.URL.Path, AdminIdentity(r))
+			http.Error(w, "Token CSRF inválido, recarga la página", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
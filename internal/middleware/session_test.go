@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+const testToken = "123:test"
+
+// signInitData firma params como lo hace Telegram con el token del bot
+func signInitData(t *testing.T, params url.Values) string {
+	t.Helper()
+	t.Setenv("TELEGRAM_TOKEN", testToken)
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + params.Get(k)
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(testToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(lines, "\n")))
+
+	signed := url.Values{}
+	for k := range params {
+		signed.Set(k, params.Get(k))
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed.Encode()
+}
+
+func initDataAt(t *testing.T, telegramID int64, authDate time.Time) string {
+	return signInitData(t, url.Values{
+		"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		"user":      {fmt.Sprintf(`{"id":%d,"first_name":"Ana"}`, telegramID)},
+	})
+}
+
+func TestValidateLogin(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	user, err := ValidateLogin(initDataAt(t, 42, now.Add(-time.Minute)), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 42 {
+		t.Fatalf("usuario %d, quería 42", user.ID)
+	}
+
+	// Un initData viejo, del futuro o sin auth_date no abre sesión
+	for name, data := range map[string]string{
+		"viejo":      initDataAt(t, 42, now.Add(-LoginMaxAge-time.Minute)),
+		"del futuro": initDataAt(t, 42, now.Add(10*time.Minute)),
+		"sin fecha":  signInitData(t, url.Values{"user": {`{"id":42}`}}),
+		"fecha rota": signInitData(t, url.Values{"auth_date": {"ayer"}, "user": {`{"id":42}`}}),
+	} {
+		if _, err := ValidateLogin(data, now); !errors.Is(err, ErrStaleInitData) {
+			t.Errorf("initData %s: %v, quería ErrStaleInitData", name, err)
+		}
+	}
+
+	// Cambiar el usuario invalida la firma
+	tampered := strings.Replace(initDataAt(t, 42, now), "%3A42", "%3A43", 1)
+	if _, err := ValidateLogin(tampered, now); !errors.Is(err, ErrInvalidInitData) {
+		t.Fatalf("initData alterado: %v, quería ErrInvalidInitData", err)
+	}
+}
+
+// adminServer arma TelegramAdminAuth + CSRF sobre un handler que responde
+// con el token CSRF de la sesión
+func adminServer(st store.Store) http.Handler {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, CSRFToken(r))
+	})
+	return TelegramAdminAuth(st)(CSRF(echo))
+}
+
+// login abre una sesión para admin con la hora dada y devuelve su cookie
+func login(t *testing.T, st store.Store, admin models.Admin, now time.Time) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := StartSession(rec, st.Sessions(), admin, admin.Name, now); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == SessionCookie {
+			return c
+		}
+	}
+	t.Fatal("StartSession no envió la cookie de sesión")
+	return nil
+}
+
+func serve(h http.Handler, method string, cookie *http.Cookie, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/admin", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestSessionCookie(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "secreto-de-test")
+	st := memstore.New()
+	ana := models.Admin{TelegramID: 1, Name: "Ana", Role: models.RoleOwner}
+	if err := st.Admins().Save(ana); err != nil {
+		t.Fatal(err)
+	}
+	h := adminServer(st)
+
+	cookie := login(t, st, ana, time.Now())
+	if w := serve(h, "GET", cookie, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("sesión válida: %d", w.Code)
+	}
+	if w := serve(h, "GET", nil, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("sin cookie: %d, quería 401", w.Code)
+	}
+
+	// Un token cambiado ya no coincide con su firma
+	token, mac, _ := strings.Cut(cookie.Value, ".")
+	forged := *cookie
+	forged.Value = strings.Repeat("0", len(token)) + "." + mac
+	if w := serve(h, "GET", &forged, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("cookie alterada: %d, quería 401", w.Code)
+	}
+	// Firmada con otro secreto
+	t.Setenv("SESSION_SECRET", "otro-secreto")
+	if w := serve(h, "GET", cookie, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("cookie de otro secreto: %d, quería 401", w.Code)
+	}
+	t.Setenv("SESSION_SECRET", "secreto-de-test")
+
+	// La sesión vence a las SessionTTL aunque la cookie siga en el navegador
+	old := login(t, st, ana, time.Now().Add(-SessionTTL-time.Minute))
+	if w := serve(h, "GET", old, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("sesión vencida: %d, quería 401", w.Code)
+	}
+
+	// Revocar al admin corta sus sesiones abiertas
+	if err := st.Admins().Delete(ana.TelegramID); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(h, "GET", cookie, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("admin revocado: %d, quería 401", w.Code)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "secreto-de-test")
+	st := memstore.New()
+	ana := models.Admin{TelegramID: 1, Name: "Ana", Role: models.RoleOwner}
+	if err := st.Admins().Save(ana); err != nil {
+		t.Fatal(err)
+	}
+	h := adminServer(st)
+	cookie := login(t, st, ana, time.Now())
+	csrf := serve(h, "GET", cookie, "", nil).Body.String()
+	if csrf == "" {
+		t.Fatal("la sesión no tiene token CSRF")
+	}
+
+	cases := []struct {
+		name   string
+		body   string
+		header map[string]string
+		want   int
+	}{
+		{"sin token", "", nil, http.StatusForbidden},
+		{"header equivocado", "", map[string]string{"X-CSRF-Token": "otro"}, http.StatusForbidden},
+		{"campo equivocado", "csrf_token=otro", nil, http.StatusForbidden},
+		{"header", "", map[string]string{"X-CSRF-Token": csrf}, http.StatusOK},
+		{"campo", "csrf_token=" + csrf, nil, http.StatusOK},
+	}
+	for _, c := range cases {
+		if w := serve(h, "POST", cookie, c.body, c.header); w.Code != c.want {
+			t.Errorf("POST %s: %d, quería %d", c.name, w.Code, c.want)
+		}
+	}
+
+	// El token de una sesión no sirve en otra
+	other := login(t, st, ana, time.Now())
+	if w := serve(h, "POST", other, "", map[string]string{"X-CSRF-Token": csrf}); w.Code != http.StatusForbidden {
+		t.Fatalf("token de otra sesión: %d, quería 403", w.Code)
+	}
+}
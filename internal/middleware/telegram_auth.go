@@ -1,18 +1,22 @@
 package middleware
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
 )
 
 type TelegramUser struct {
@@ -22,92 +26,78 @@ type TelegramUser struct {
 	Username  string `json:"username"`
 }
 
-// TelegramAdminAuth verifica autenticación por Telegram O BasicAuth
-func TelegramAdminAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Método 1: Verificar BasicAuth (para acceso web normal)
-		if checkBasicAuth(r) {
-			next.ServeHTTP(w, r)
-			return
-		}
+type ctxKey int
 
-		// Método 2: Verificar Telegram initData
-		initData := r.Header.Get("X-Telegram-Init-Data")
-		log.Printf("[DEBUG] Header X-Telegram-Init-Data: %v", initData != "")
+const adminKey ctxKey = 0
 
-		if initData == "" {
-			initData = r.URL.Query().Get("tg_init_data")
-			log.Printf("[DEBUG] Query tg_init_data: %v", initData != "")
-		}
-		if initData == "" {
-			cookie, err := r.Cookie("tg_init_data")
-			if err == nil {
-				log.Printf("[DEBUG] Cookie raw: %s", cookie.Value[:min(50, len(cookie.Value))])
-				decoded, err := url.QueryUnescape(cookie.Value)
-				if err == nil {
-					initData = decoded
-					log.Printf("[DEBUG] Cookie decoded OK, len: %d", len(initData))
-				} else {
-					log.Printf("[DEBUG] Cookie decode error: %v", err)
-				}
-			} else {
-				log.Printf("[DEBUG] No cookie found: %v", err)
-			}
-		}
+// session es el admin autenticado en el request
+type session struct {
+	identity string
+	admin    models.Admin
+	csrf     string
+}
 
-		if initData != "" {
-			log.Printf("[DEBUG] initData len: %d, prefix: %s", len(initData), initData[:min(100, len(initData))])
-			user, valid := validateTelegramInitData(initData)
-			if valid {
-				adminIDs := os.Getenv("ADMIN_TELEGRAM_IDS")
-				log.Printf("[DEBUG] User ID: %d, AdminIDs: %s", user.ID, adminIDs)
-				if isAdmin(user.ID, adminIDs) {
-					log.Printf("Admin Telegram autenticado: %s (ID: %d)", user.FirstName, user.ID)
-					next.ServeHTTP(w, r)
-					return
-				}
-				log.Printf("Usuario Telegram no es admin: %d", user.ID)
-			} else {
-				log.Printf("[DEBUG] initData validation failed")
-			}
-		} else {
-			log.Printf("[DEBUG] No initData found")
-		}
+// AdminIdentity devuelve quién está autenticado en el request ("admin" o "tg:<id> <nombre>")
+func AdminIdentity(r *http.Request) string {
+	if s, ok := r.Context().Value(adminKey).(session); ok {
+		return s.identity
+	}
+	return ""
+}
 
-		// Si no hay autenticación válida, pedir BasicAuth
-		w.Header().Set("WWW-Authenticate", `Basic realm="Lotto Admin"`)
-		http.Error(w, "Acceso denegado: No autorizado", http.StatusUnauthorized)
-	})
+// CurrentAdmin devuelve el admin autenticado con su rol. Sin sesión devuelve
+// un Admin vacío, que no tiene ningún permiso.
+func CurrentAdmin(r *http.Request) models.Admin {
+	s, _ := r.Context().Value(adminKey).(session)
+	return s.admin
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+func withAdmin(r *http.Request, s session) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminKey, s))
 }
 
-func checkBasicAuth(r *http.Request) bool {
-	auth := r.Header.Get("Authorization")
-	if auth == "" || !strings.HasPrefix(auth, "Basic ") {
-		return false
-	}
+// TelegramAdminAuth verifica la sesión abierta desde la Mini App (cookie
+// admin_session, ver StartSession) O BasicAuth con el usuario y contraseña de
+// un admin. En los dos casos el admin tiene que seguir en la tabla admins; el
+// rol se controla después con RequireRole.
+func TelegramAdminAuth(st store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Método 1: Verificar BasicAuth (para acceso web normal)
+			s, ok, handled := checkBasicAuth(w, r, st)
+			if handled {
+				return
+			}
+			if ok {
+				next.ServeHTTP(w, withAdmin(r, s))
+				return
+			}
 
-	payload, err := base64.StdEncoding.DecodeString(auth[6:])
-	if err != nil {
-		return false
-	}
+			// Método 2: Sesión de Telegram
+			sess, admin, ok, err := loadSession(r, st, time.Now())
+			if err != nil {
+				log.Printf("Error leyendo sesión de admin: %v", err)
+				http.Error(w, "Error interno", http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				next.ServeHTTP(w, withAdmin(r, session{
+					identity: fmt.Sprintf("tg:%d %s", sess.TelegramID, sess.Name),
+					admin:    admin,
+					csrf:     sess.CSRFToken,
+				}))
+				return
+			}
 
-	pair := strings.SplitN(string(payload), ":", 2)
-	if len(pair) != 2 {
-		return false
+			// Si no hay autenticación válida, pedir BasicAuth
+			w.Header().Set("WWW-Authenticate", `Basic realm="Lotto Admin"`)
+			http.Error(w, "Acceso denegado: No autorizado. Desde Telegram, entra por /admin/login", http.StatusUnauthorized)
+		})
 	}
-
-	expectedPassword := os.Getenv("ADMIN_PASSWORD")
-	return pair[0] == "admin" && pair[1] == expectedPassword
 }
 
-func validateTelegramInitData(initData string) (*TelegramUser, bool) {
+// ValidateInitData verifica la firma del initData de la Mini App y devuelve el usuario
+func ValidateInitData(initData string) (*TelegramUser, bool) {
 	botToken := os.Getenv("TELEGRAM_TOKEN")
 	if botToken == "" {
 		return nil, false
@@ -167,19 +157,19 @@ func validateTelegramInitData(initData string) (*TelegramUser, bool) {
 	return &user, true
 }
 
-func isAdmin(userID int64, adminIDs string) bool {
-	if adminIDs == "" {
-		return false
+// TelegramUserFromRequest devuelve el usuario de Telegram si el request trae
+// un initData válido en el header X-Telegram-Init-Data (reservas desde la Mini
+// App). Con ese usuario se gasta el saldo a favor, así que el auth_date pasa
+// por el mismo límite que el login (ver ValidateLogin); con uno vencido la
+// reserva sigue, pero como si viniera de la web.
+func TelegramUserFromRequest(r *http.Request) *TelegramUser {
+	initData := r.Header.Get("X-Telegram-Init-Data")
+	if initData == "" {
+		return nil
 	}
-
-	ids := strings.Split(adminIDs, ",")
-	for _, id := range ids {
-		id = strings.TrimSpace(id)
-		if adminID, err := strconv.ParseInt(id, 10, 64); err == nil {
-			if adminID == userID {
-				return true
-			}
-		}
+	user, err := ValidateLogin(initData, time.Now())
+	if err != nil {
+		return nil
 	}
-	return false
+	return user
 }
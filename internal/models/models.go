@@ -10,42 +10,386 @@ type User struct {
 	TelegramID *int64 `json:"telegram_id"` // Pointer allowing null
 	Name       string `json:"name"`
 	Phone      string `json:"phone"`
+
+	// Virtual field (admin search): credit balance by currency, see CreditStore.Balances
+	Credit map[string]Money `json:"credit,omitempty"`
 }
 
 // Raffle represents a lottery event
 type Raffle struct {
-	ID            int64     `json:"id"`
-	Name          string    `json:"name"`
-	TotalNumbers  int       `json:"total_numbers"`
-	TicketPrice   float64   `json:"ticket_price"`
-	ReserveHours  int       `json:"reserve_hours"`
-	Status        string    `json:"status"` // 'active', 'finished'
-	CreatedAt     time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	TotalNumbers int       `json:"total_numbers"`
+	TicketPrice  Money     `json:"ticket_price"`
+	Currency     string    `json:"currency"` // Base currency of the price, balances and totals
+	ReserveHours int       `json:"reserve_hours"`
+	Status       string    `json:"status"` // 'active', 'closed', 'finished'
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 // Ticket represents a single lottery number
 type Ticket struct {
-	ID          int64   `json:"id"`
-	RaffleID    int64   `json:"raffle_id"`
-	Number      string  `json:"number"`
-	UserID      *int64  `json:"user_id"` // Pointer allowing null (if available)
-	Status      string  `json:"status"`  // 'available', 'reserved', 'paid'
-	ReservedAt  *time.Time `json:"reserved_at"`
-	
+	ID         int64      `json:"id"`
+	RaffleID   int64      `json:"raffle_id"`
+	Number     string     `json:"number"`
+	UserID     *int64     `json:"user_id"` // Pointer allowing null (if available)
+	Status     string     `json:"status"`  // 'available', 'reserved', 'paid'
+	ReservedAt *time.Time `json:"reserved_at"`
+	RemindedAt *time.Time `json:"reminded_at,omitempty"` // Expiry reminder sent to the customer
+
 	// Virtual fields (calculated via joins/queries)
-	UserName    string  `json:"user_name,omitempty"`
-	UserPhone   string  `json:"user_phone,omitempty"`
-	TotalPaid   float64 `json:"total_paid"`
-	Remaining   float64 `json:"remaining"`
+	UserName       string `json:"user_name,omitempty"`
+	UserPhone      string `json:"user_phone,omitempty"`
+	UserTelegramID *int64 `json:"user_telegram_id,omitempty"` // To message the customer
+	TotalPaid      Money  `json:"total_paid"`                 // Payments not rejected (verified or pending)
+	TotalVerified  Money  `json:"total_verified"`             // Only verified payments; decides 'paid'
+	Remaining      Money  `json:"remaining"`
 }
 
 // Payment represents a transaction for a ticket
 type Payment struct {
-	ID          int64     `json:"id"`
-	TicketID    int64     `json:"ticket_id"`
-	Amount      float64   `json:"amount"`
-	Method      string    `json:"method"` // 'cash', 'transfer'
-	Reference   string    `json:"reference"`
-	CreatedAt   time.Time `json:"created_at"`
-	IsVerified  bool      `json:"is_verified"`
+	ID           int64      `json:"id"`
+	TicketID     int64      `json:"ticket_id"`
+	Amount       Money      `json:"amount"`
+	Method       string     `json:"method"` // PaymentMethod.Code ('cash', 'transfer'...) or 'credit'
+	Reference    string     `json:"reference"`
+	CreatedAt    time.Time  `json:"created_at"`
+	IsVerified   bool       `json:"is_verified"`
+	Status       string     `json:"status"` // 'pending', 'verified', 'rejected'
+	ReviewedBy   string     `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	RejectReason string     `json:"reject_reason,omitempty"`
+	ReceiptKey   string     `json:"-"`                      // Key in the receipts store
+	ReceiptType  string     `json:"receipt_type,omitempty"` // MIME type of the uploaded receipt
+	Kind         string     `json:"kind"`                   // PaymentKind*; only 'payment' is money received
+	UserID       *int64     `json:"user_id,omitempty"`      // Customer the money belongs to
+	RelatedID    *int64     `json:"related_id,omitempty"`   // Payment cancelled by a reversal
+	Reason       string     `json:"reason,omitempty"`       // Why it was reversed, refunded or moved
+
+	// Money received in another currency is converted at the latest ExchangeRate
+	Currency       string  `json:"currency"`        // Currency the money came in
+	OriginalAmount Money   `json:"original_amount"` // Amount in Currency; Amount is in the raffle's currency
+	Rate           float64 `json:"rate"`            // Units of Currency per 1 of the raffle's currency
+
+	// Virtual fields (for the verification queue)
+	TicketNumber string `json:"ticket_number,omitempty"`
+	RaffleID     int64  `json:"raffle_id,omitempty"`
+	RaffleName   string `json:"raffle_name,omitempty"`
+	UserName     string `json:"user_name,omitempty"`
+	UserPhone    string `json:"user_phone,omitempty"`
+}
+
+// Money formats amount in the raffle's currency
+func (r Raffle) Money(amount Money) string {
+	return FormatMoney(amount, r.Currency)
+}
+
+// HasReceipt reports whether the customer uploaded a transfer receipt
+func (p Payment) HasReceipt() bool {
+	return p.ReceiptKey != ""
+}
+
+// Draw represents the result of a raffle draw
+type Draw struct {
+	ID            int64     `json:"id"`
+	RaffleID      int64     `json:"raffle_id"`
+	WinningNumber string    `json:"winning_number"`
+	TicketID      *int64    `json:"ticket_id"` // Null if the number was never generated
+	UserID        *int64    `json:"user_id"`   // Null if the number was not sold
+	TicketStatus  string    `json:"ticket_status"`
+	Method        string    `json:"method"` // 'manual', 'random'
+	Seed          string    `json:"seed,omitempty"`
+	Source        string    `json:"source"`
+	DrawnBy       string    `json:"drawn_by"`
+	DrawnAt       time.Time `json:"drawn_at"`
+
+	// Virtual fields
+	RaffleName  string `json:"raffle_name,omitempty"`
+	WinnerName  string `json:"winner_name,omitempty"`
+	WinnerPhone string `json:"winner_phone,omitempty"`
+}
+
+// HasWinner reports whether the winning number belonged to a customer
+func (d Draw) HasWinner() bool {
+	return d.UserID != nil
+}
+
+// Notification categories an admin can opt in or out of
+const (
+	NotifyBookings = "bookings" // New reservations
+	NotifyPayments = "payments" // Payments and receipts waiting for verification
+	NotifyExpiries = "expiries" // Reservations released by the reaper
+)
+
+// NotifyCategories lists the categories in the order shown to admins
+var NotifyCategories = []string{NotifyBookings, NotifyPayments, NotifyExpiries}
+
+// Recipient is an admin chat registered with /start to receive notifications
+type Recipient struct {
+	ChatID     int64     `json:"chat_id"`
+	TelegramID int64     `json:"telegram_id"`
+	Name       string    `json:"name"`
+	Bookings   bool      `json:"bookings"`
+	Payments   bool      `json:"payments"`
+	Expiries   bool      `json:"expiries"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Wants reports whether the recipient opted in to the category.
+// An empty category (e.g. draw results) goes to everyone.
+func (r Recipient) Wants(category string) bool {
+	switch category {
+	case NotifyBookings:
+		return r.Bookings
+	case NotifyPayments:
+		return r.Payments
+	case NotifyExpiries:
+		return r.Expiries
+	}
+	return true
+}
+
+// SetWants turns a category on or off; it returns false for unknown categories
+func (r *Recipient) SetWants(category string, on bool) bool {
+	switch category {
+	case NotifyBookings:
+		r.Bookings = on
+	case NotifyPayments:
+		r.Payments = on
+	case NotifyExpiries:
+		r.Expiries = on
+	default:
+		return false
+	}
+	return true
+}
+
+// Admin roles, from most to least privileged
+const (
+	RoleOwner   = "owner"   // Everything, including inviting and revoking admins
+	RoleCashier = "cashier" // Read access plus recording and reviewing payments
+	RoleViewer  = "viewer"  // Read-only
+)
+
+// AdminRoles lists the roles in the order they are offered in the panel
+var AdminRoles = []string{RoleOwner, RoleCashier, RoleViewer}
+
+var roleRank = map[string]int{RoleViewer: 1, RoleCashier: 2, RoleOwner: 3}
+
+// ValidRole reports whether role is one of AdminRoles
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// Admin is a Telegram user allowed into the admin panel and bot commands
+type Admin struct {
+	TelegramID int64     `json:"telegram_id"`
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	InvitedBy  string    `json:"invited_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Password login (BasicAuth); without PasswordHash only Telegram login works
+	Username     string `json:"username,omitempty"`
+	PasswordHash string `json:"-"` // bcrypt
+}
+
+// HasPassword reports whether the admin can log in with username and password
+func (a Admin) HasPassword() bool {
+	return a.Username != "" && a.PasswordHash != ""
+}
+
+// Can reports whether the admin's role includes the permissions of role
+func (a Admin) Can(role string) bool {
+	return ValidRole(role) && roleRank[a.Role] >= roleRank[role]
+}
+
+// LoginAttempt counts failed password logins for an IP ("ip:<addr>") or a
+// username ("user:<name>") within a window; past the limit it is locked
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	FirstFailedAt time.Time
+	LockedUntil   *time.Time
+}
+
+// AdminSession is a panel login. ID is the sha256 of the token in the
+// session cookie, so the table alone can't be used to log in.
+type AdminSession struct {
+	ID         string    `json:"-"`
+	TelegramID int64     `json:"telegram_id"`
+	Name       string    `json:"name"` // Telegram first name at login
+	CSRFToken  string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// OutboxMessage is a Telegram message waiting to be delivered by the outbox worker
+type OutboxMessage struct {
+	ID            int64      `json:"id"`
+	ChatID        int64      `json:"chat_id"`
+	Text          string     `json:"text"`
+	ReplyMarkup   string     `json:"-"` // Inline keyboard as JSON, empty for none
+	ReceiptKey    string     `json:"-"` // Receipt sent as photo/document with Text as caption
+	ReceiptType   string     `json:"receipt_type,omitempty"`
+	Status        string     `json:"status"` // pending, sent, failed
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// Audit log actions
+const (
+	AuditRaffleCreate   = "raffle.create"
+	AuditRaffleClose    = "raffle.close"
+	AuditRaffleDraw     = "raffle.draw"
+	AuditPaymentAdd     = "payment.add"
+	AuditPaymentVerify  = "payment.verify"
+	AuditPaymentReject  = "payment.reject"
+	AuditPaymentReverse = "payment.reverse"
+	AuditPaymentRefund  = "payment.refund"
+	AuditTicketRelease  = "ticket.release"
+	AuditTicketExpire   = "ticket.expire" // Released by the reaper, not an admin
+	AuditUserMerge      = "user.merge"
+	AuditOutboxRetry    = "outbox.retry"
+	AuditAdminInvite    = "admin.invite"
+	AuditAdminRole      = "admin.role"
+	AuditAdminRevoke    = "admin.revoke"
+	AuditAdminPassword  = "admin.password"
+	AuditRateAdd        = "rate.add"
+	AuditMethodSave     = "method.save"
+	AuditRaffleMethods  = "raffle.methods"
+)
+
+// AuditActions lists the actions in the order the audit filter offers them
+var AuditActions = []string{
+	AuditRaffleCreate, AuditRaffleClose, AuditRaffleDraw,
+	AuditPaymentAdd, AuditPaymentVerify, AuditPaymentReject, AuditPaymentReverse, AuditPaymentRefund,
+	AuditTicketRelease, AuditTicketExpire, AuditUserMerge, AuditOutboxRetry,
+	AuditAdminInvite, AuditAdminRole, AuditAdminRevoke, AuditAdminPassword,
+	AuditRateAdd, AuditMethodSave, AuditRaffleMethods,
+}
+
+// AuditEntry is one row of the append-only audit log: who changed what,
+// with the values before and after the change as JSON
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"` // "tg:<id> <name>" like AdminIdentity, or "reaper"
+	Action    string    `json:"action"`
+	RaffleID  *int64    `json:"raffle_id,omitempty"`
+	TicketID  *int64    `json:"ticket_id,omitempty"`
+	PaymentID *int64    `json:"payment_id,omitempty"`
+	Target    string    `json:"target,omitempty"` // Other targets: "admin:<id>", "user:<id>", "outbox:<id>"
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Payment kinds. Payments are never edited or deleted: a correction or money
+// leaving the ticket is a new entry with a negative amount.
+const (
+	PaymentKindPayment  = "payment"  // Money received
+	PaymentKindReversal = "reversal" // Cancels a mistyped payment (RelatedID); no money moves
+	PaymentKindRefund   = "refund"   // Money returned to the customer; Method is how
+	PaymentKindCredit   = "credit"   // Moved to (-) or paid from (+) the customer's credit balance
+	PaymentKindTransfer = "transfer" // Moved to or from another ticket of the same customer
+)
+
+// Credit is a movement of a customer's credit balance: positive when money
+// from a released ticket is kept, negative when it is used
+type Credit struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Amount    Money     `json:"amount"`
+	PaymentID *int64    `json:"payment_id,omitempty"` // Ledger entry the money came from or went to
+	Currency  string    `json:"currency"`             // Of the raffle the money came from
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Currencies accepted for payments, the first one is the default raffle currency
+var Currencies = []string{"USD", "VES", "USDT"}
+
+// DefaultCurrency is the currency of raffles created before multi-currency
+const DefaultCurrency = "USD"
+
+var currencySymbols = map[string]string{"USD": "$", "VES": "Bs "}
+
+// ValidCurrency reports whether currency is one of Currencies
+func ValidCurrency(currency string) bool {
+	for _, c := range Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatMoney formats amount with the currency symbol ($12.50, Bs 450.00) or
+// code (12.50 USDT)
+func FormatMoney(amount Money, currency string) string {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if symbol, ok := currencySymbols[currency]; ok {
+		return sign + symbol + amount.String()
+	}
+	return sign + amount.String() + " " + currency
+}
+
+// ExchangeRate says that 1 Base is worth Rate Currency from ValidFrom on
+type ExchangeRate struct {
+	ID        int64     `json:"id"`
+	Currency  string    `json:"currency"`
+	Base      string    `json:"base"`
+	Rate      float64   `json:"rate"`
+	ValidFrom time.Time `json:"valid_from"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaymentMethod is an entry of the payment methods catalog managed by the
+// owners; Payment.Method stores its Code
+type PaymentMethod struct {
+	Code         string    `json:"code"` // 'transfer', 'pago_movil', 'zelle', 'crypto', 'cash'...
+	Name         string    `json:"name"`
+	Instructions string    `json:"instructions,omitempty"` // Account number, phone, ID... shown to the customer
+	RefPattern   string    `json:"ref_pattern,omitempty"`  // Regexp the whole reference must match; empty accepts any
+	RefHint      string    `json:"ref_hint,omitempty"`     // The reference format explained to the customer
+	Public       bool      `json:"public"`                 // Offered in the Mini App; otherwise only admins record it
+	Position     int       `json:"position"`               // Display order
+	UpdatedBy    string    `json:"updated_by,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CurrencyTotal is the money received in one currency: Original in that
+// currency and Amount converted to the raffle's currency
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Original Money  `json:"original"`
+	Amount   Money  `json:"amount"`
+}
+
+// NormalizePhone reduces a phone number to digits in local format
+// (0414-123.45.67, +58 414 1234567 -> 04141234567) so the same customer
+// is recognized however they type it.
+func NormalizePhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	n := string(digits)
+	if len(n) == 12 && n[:2] == "58" {
+		n = "0" + n[2:]
+	}
+	return n
 }
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (cents) of its currency. Sums and
+// comparisons are exact, so many partial payments add up to the price. It is
+// read from forms with ParseMoney and written as a decimal (12.50) in JSON
+// and templates.
+type Money int64
+
+// ErrInvalidMoney is returned by ParseMoney for anything but a decimal amount
+// with up to two decimals
+var ErrInvalidMoney = errors.New("monto inválido")
+
+// Cents builds an amount from whole units and cents: Cents(12, 50) is 12.50
+func Cents(units, cents int64) Money {
+	return Money(units*100 + cents)
+}
+
+// MoneyFromFloat rounds f to the nearest cent. Only for amounts that are
+// already inexact, like a conversion with an exchange rate.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// ParseMoney reads "12", "12.5", "12,50" or "-3.75" without going through
+// float64; more than two decimals is an error rather than a rounding.
+func ParseMoney(s string) (Money, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	units, decimals, _ := strings.Cut(s, ".")
+	if units == "" && decimals == "" || len(decimals) > 2 {
+		return 0, ErrInvalidMoney
+	}
+	if units == "" {
+		units = "0"
+	}
+	decimals += strings.Repeat("0", 2-len(decimals))
+	for _, part := range []string{units, decimals} {
+		if strings.Trim(part, "0123456789") != "" {
+			return 0, ErrInvalidMoney
+		}
+	}
+	u, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || u > math.MaxInt64/100-1 {
+		return 0, ErrInvalidMoney
+	}
+	c, _ := strconv.ParseInt(decimals, 10, 64)
+	m := Cents(u, c)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// Float is the amount in units, for conversions and display only
+func (m Money) Float() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals and no currency (-12.50)
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return sign + strconv.FormatInt(int64(m/100), 10) + "." + strconv.FormatInt(int64(m%100)+100, 10)[1:]
+}
+
+// MarshalJSON writes the amount as a decimal number (12.5 is 12.50)
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a decimal number like MarshalJSON writes it
+func (m *Money) UnmarshalJSON(b []byte) error {
+	v, err := ParseMoney(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+	}{
+		{"12", Cents(12, 0)},
+		{"12.5", Cents(12, 50)},
+		{"12,50", Cents(12, 50)},
+		{" 0.01 ", 1},
+		{".75", 75},
+		{"+3", Cents(3, 0)},
+		{"-3.75", -Cents(3, 75)},
+		{"33.33", Cents(33, 33)},
+		{"1000000", Cents(1000000, 0)},
+	}
+	for _, c := range cases {
+		got, err := ParseMoney(c.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseMoney(%q) = %d, quería %d", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"", "-", ".", "1.234", "1.2.3", "abc", "1e3", "12 50", "--1", "99999999999999999999"} {
+		if got, err := ParseMoney(in); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q) = %d, %v, quería ErrInvalidMoney", in, got, err)
+		}
+	}
+}
+
+func TestMoneyRoundTrip(t *testing.T) {
+	for _, m := range []Money{0, 1, 10, 99, 100, 3333, -1, -375, Cents(1234567, 89)} {
+		s := m.String()
+		got, err := ParseMoney(s)
+		if err != nil || got != m {
+			t.Errorf("%d -> %q -> %d (%v)", m, s, got, err)
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != m {
+			t.Errorf("JSON %d -> %s -> %d (%v)", m, data, back, err)
+		}
+	}
+
+	for m, want := range map[Money]string{0: "0.00", 5: "0.05", -5: "-0.05", Cents(12, 50): "12.50"} {
+		if got := m.String(); got != want {
+			t.Errorf("%d.String() = %q, quería %q", m, got, want)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	// 0.1 + 0.2 no es 0.3 en float64; en centavos sí
+	if got := MoneyFromFloat(0.1 + 0.2); got != 30 {
+		t.Fatalf("MoneyFromFloat(0.1+0.2) = %d, quería 30", got)
+	}
+	if got := MoneyFromFloat(36.5 / 365); got != 10 {
+		t.Fatalf("MoneyFromFloat(0.1) = %d, quería 10", got)
+	}
+}
@@ -0,0 +1,91 @@
+// Package receipts guarda los comprobantes de pago que suben los clientes.
+// Hay dos implementaciones: Local (disco) y S3 (cualquier servicio compatible:
+// AWS, Cloudflare R2, MinIO...). Se elige con FromEnv.
+package receipts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// MaxSize es el tamaño máximo aceptado para un comprobante
+const MaxSize = 5 << 20 // 5 MB
+
+var (
+	// ErrNotFound se devuelve cuando el comprobante no existe
+	ErrNotFound = errors.New("receipts: not found")
+	// ErrUnsupported se devuelve si el archivo no es una imagen o PDF
+	ErrUnsupported = errors.New("solo se aceptan imágenes (JPG, PNG, WebP) o PDF")
+)
+
+// allowed son los tipos aceptados y la extensión con que se guardan
+var allowed = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Store guarda y lee comprobantes por clave (ej: "2024/05/3fa2c1....jpg")
+type Store interface {
+	Save(key, contentType string, data []byte) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// FromEnv usa S3 si RECEIPTS_S3_BUCKET está definido; si no, guarda en disco
+// en RECEIPTS_DIR (por defecto "uploads/receipts").
+func FromEnv() (Store, error) {
+	if bucket := os.Getenv("RECEIPTS_S3_BUCKET"); bucket != "" {
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("RECEIPTS_S3_ENDPOINT"),
+			Region:    os.Getenv("RECEIPTS_S3_REGION"),
+			AccessKey: os.Getenv("RECEIPTS_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("RECEIPTS_S3_SECRET_KEY"),
+			Bucket:    bucket,
+		})
+	}
+
+	dir := os.Getenv("RECEIPTS_DIR")
+	if dir == "" {
+		dir = "uploads/receipts"
+	}
+	return NewLocal(dir)
+}
+
+// Read lee un comprobante subido (máximo MaxSize) y detecta su tipo por el
+// contenido, no por el nombre ni la cabecera que manda el navegador.
+func Read(r io.Reader) (data []byte, contentType string, err error) {
+	data, err = io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxSize {
+		return nil, "", fmt.Errorf("el comprobante supera %d MB", MaxSize>>20)
+	}
+
+	contentType = http.DetectContentType(data)
+	if _, ok := allowed[contentType]; !ok {
+		return nil, "", ErrUnsupported
+	}
+	return data, contentType, nil
+}
+
+// NewKey genera una clave única agrupada por mes
+func NewKey(contentType string, now time.Time) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return path.Join(now.UTC().Format("2006/01"), hex.EncodeToString(b)+allowed[contentType])
+}
+
+// IsImage indica si el comprobante se puede mandar como foto por Telegram
+func IsImage(contentType string) bool {
+	return contentType != "application/pdf"
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Botones de acción en la notificación de reserva. El callback_data lleva
+// los IDs a tocar (Telegram lo limita a 64 bytes):
+//
+//	verify:<payment_id>,<payment_id>...
+//	release:<user_id>:<ticket_id>,<ticket_id>...
+//
+// release incluye el cliente para no liberar un número que, desde la
+// notificación, se liberó y volvió a reservar otra persona.
+const (
+	actionVerify  = "verify"
+	actionRelease = "release"
+
+	maxCallbackData = 64
+)
+
+// BookingAlert es la notificación de una reserva nueva con lo necesario
+// para armar los botones
+type BookingAlert struct {
+	Text       string
+	RaffleID   int64
+	UserID     int64
+	TicketIDs  []int64
+	PaymentIDs []int64 // Pagos por verificar; sin pagos no hay botón de verificar
+}
+
+// Keyboard arma los botones: verificar pago, liberar y abrir el panel (si
+// ADMIN_URL está configurada). Si una lista de IDs no entra en el
+// callback_data se omite ese botón y queda el panel.
+func (a BookingAlert) Keyboard() (tgbotapi.InlineKeyboardMarkup, bool) {
+	var actions []tgbotapi.InlineKeyboardButton
+	if data, ok := callbackData(actionVerify+":", a.PaymentIDs); ok {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("✅ Verificar pago", data))
+	}
+	if data, ok := callbackData(fmt.Sprintf("%s:%d:", actionRelease, a.UserID), a.TicketIDs); ok {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("🗑️ Liberar", data))
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(actions) > 0 {
+		rows = append(rows, actions)
+	}
+	if u := adminURL(a.RaffleID); u != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("🔗 Abrir en admin", u)))
+	}
+	if len(rows) == 0 {
+		return tgbotapi.InlineKeyboardMarkup{}, false
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...), true
+}
+
+func callbackData(prefix string, ids []int64) (string, bool) {
+	if len(ids) == 0 {
+		return "", false
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	data := prefix + strings.Join(parts, ",")
+	return data, len(data) <= maxCallbackData
+}
+
+func parseIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// adminURL devuelve el enlace al panel de la rifa, o "" sin ADMIN_URL
+func adminURL(raffleID int64) string {
+	base := strings.TrimSuffix(os.Getenv("ADMIN_URL"), "/")
+	if base == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s?raffle_id=%d", base, raffleID)
+}
+
+// ActionResult es la respuesta a un botón: Text se agrega al mensaje.
+// Después se quita el botón pulsado, o todos los de acción con RemoveAll
+// (la reserva ya no existe y no queda nada que verificar).
+type ActionResult struct {
+	Text      string
+	RemoveAll bool
+}
+
+// Action ejecuta el botón pulsado con la misma lógica que el panel
+// (VerifyPayment, ReleaseBooking) y avisa al cliente.
+func (c *Commands) Action(from Sender, data string) (ActionResult, error) {
+	admin := from.Identity()
+	kind, rest, _ := strings.Cut(data, ":")
+
+	switch kind {
+	case actionVerify:
+		if !from.Can(models.RoleCashier) {
+			return ActionResult{}, ErrForbidden
+		}
+		ids, err := parseIDs(rest)
+		if err != nil {
+			return ActionResult{}, fmt.Errorf("botón inválido %q: %w", data, err)
+		}
+		verified, reviewed := 0, 0
+		for _, id := range ids {
+			err := c.Store.Tx(func(tx store.Store) error {
+				if err := VerifyPayment(tx, id, admin, c.Now()); err != nil {
+					return err
+				}
+				c.Customers.In(tx).PaymentReviewed(id)
+				return nil
+			})
+			// Otro admin lo revisó antes (o se liberó la reserva)
+			if errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrNotFound) {
+				reviewed++
+				continue
+			}
+			if err != nil {
+				return ActionResult{}, err
+			}
+			verified++
+			log.Printf("Pago %d verificado por %s (bot)", id, admin)
+		}
+		text := fmt.Sprintf("✅ Pago verificado por %s", from.Name)
+		if verified == 0 {
+			text = "ℹ️ El pago ya había sido revisado"
+		} else if reviewed > 0 {
+			text += fmt.Sprintf(" (%d ya revisados)", reviewed)
+		}
+		return ActionResult{Text: text}, nil
+
+	case actionRelease:
+		if !from.Can(models.RoleOwner) {
+			return ActionResult{}, ErrForbidden
+		}
+		user, list, _ := strings.Cut(rest, ":")
+		userID, err := strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			return ActionResult{}, fmt.Errorf("botón inválido %q: %w", data, err)
+		}
+		ids, err := parseIDs(list)
+		if err != nil {
+			return ActionResult{}, fmt.Errorf("botón inválido %q: %w", data, err)
+		}
+		var released []models.Ticket
+		err = c.Store.Tx(func(tx store.Store) error {
+			var err error
+			if released, err = ReleaseBooking(tx, userID, ids, admin, c.Now()); err != nil {
+				return err
+			}
+			for _, t := range released {
+				c.Customers.In(tx).Released(t, false, ReleaseCredit)
+			}
+			return nil
+		})
+		if err != nil {
+			return ActionResult{}, err
+		}
+		if len(released) == 0 {
+			return ActionResult{Text: "ℹ️ La reserva ya no estaba a nombre del cliente", RemoveAll: true}, nil
+		}
+		numbers := make([]string, len(released))
+		for i, t := range released {
+			numbers[i] = t.Number
+		}
+		log.Printf("Tickets %v liberados por %s (bot)", ids, admin)
+		return ActionResult{Text: fmt.Sprintf("🗑️ #%s liberado por %s", strings.Join(numbers, ", #"), from.Name), RemoveAll: true}, nil
+	}
+	return ActionResult{}, fmt.Errorf("botón desconocido %q", data)
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+var (
+	// ErrLastOwner se devuelve al revocar o degradar al único owner
+	ErrLastOwner = errors.New("tiene que quedar al menos un owner")
+	// ErrInvalidRole se devuelve con un rol que no está en models.AdminRoles
+	ErrInvalidRole = errors.New("rol inválido")
+	// ErrForbidden se devuelve cuando el rol del admin no permite la acción
+	ErrForbidden = errors.New("tu rol no permite esta acción")
+)
+
+// BootstrapAdmins crea como owners los IDs de ADMIN_TELEGRAM_IDS mientras no
+// haya ningún owner, para poder entrar la primera vez (o recuperar el acceso).
+// Con owners en la tabla la variable se ignora y los admins se gestionan
+// desde el panel.
+func BootstrapAdmins(st store.Store, ids string) (int, error) {
+	created := 0
+	err := st.Tx(func(tx store.Store) error {
+		admins, err := tx.Admins().List()
+		if err != nil {
+			return err
+		}
+		for _, a := range admins {
+			if a.Role == models.RoleOwner {
+				return nil
+			}
+		}
+
+		for _, part := range strings.Split(ids, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return fmt.Errorf("ADMIN_TELEGRAM_IDS: ID inválido %q", part)
+			}
+			a := models.Admin{TelegramID: id, Role: models.RoleOwner, InvitedBy: "ADMIN_TELEGRAM_IDS"}
+			e := adminEntry("ADMIN_TELEGRAM_IDS", models.AuditAdminInvite, id)
+			var before interface{}
+			// Conserva el nombre si ya era admin con otro rol
+			if old, err := tx.Admins().Get(id); err == nil {
+				a.Name = old.Name
+				e.Action = models.AuditAdminRole
+				before = old
+			} else if !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+			if err := tx.Admins().Save(a); err != nil {
+				return err
+			}
+			if err := auditAdmin(tx, e, id, before); err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	return created, err
+}
+
+// SaveAdmin invita a un admin o le cambia el rol. by es quién lo hace
+// (middleware.AdminIdentity) y queda como invited_by en los nuevos.
+func SaveAdmin(st store.Store, a models.Admin, by string) error {
+	if !models.ValidRole(a.Role) {
+		return ErrInvalidRole
+	}
+	return st.Tx(func(tx store.Store) error {
+		e := adminEntry(by, models.AuditAdminRole, a.TelegramID)
+		var before interface{}
+		old, err := tx.Admins().Get(a.TelegramID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			a.InvitedBy = by
+			e.Action = models.AuditAdminInvite
+		case err != nil:
+			return err
+		case old.Role == models.RoleOwner && a.Role != models.RoleOwner:
+			if err := keepOwner(tx, a.TelegramID); err != nil {
+				return err
+			}
+		}
+		if err == nil {
+			before = old
+		}
+		if a.Name == "" {
+			a.Name = old.Name
+		}
+		if err := tx.Admins().Save(a); err != nil {
+			return err
+		}
+		return auditAdmin(tx, e, a.TelegramID, before)
+	})
+}
+
+// RevokeAdmin quita el acceso al panel y al bot y cierra sus sesiones. Sus
+// chats registrados dejan de recibir notificaciones (notifyTargets solo
+// entrega a admins). by es quién lo revoca.
+func RevokeAdmin(st store.Store, telegramID int64, by string) (models.Admin, error) {
+	var revoked models.Admin
+	err := st.Tx(func(tx store.Store) error {
+		a, err := tx.Admins().Get(telegramID)
+		if err != nil {
+			return err
+		}
+		if a.Role == models.RoleOwner {
+			if err := keepOwner(tx, telegramID); err != nil {
+				return err
+			}
+		}
+		revoked = a
+		if err := tx.Sessions().DeleteByAdmin(telegramID); err != nil {
+			return err
+		}
+		if err := tx.Admins().Delete(telegramID); err != nil {
+			return err
+		}
+		return Audit(tx, adminEntry(by, models.AuditAdminRevoke, telegramID), a, nil)
+	})
+	return revoked, err
+}
+
+// auditAdmin registra e con el admin como quedó guardado
+func auditAdmin(tx store.Store, e models.AuditEntry, telegramID int64, before interface{}) error {
+	after, err := tx.Admins().Get(telegramID)
+	if err != nil {
+		return err
+	}
+	return Audit(tx, e, before, after)
+}
+
+// keepOwner devuelve ErrLastOwner si telegramID es el único owner
+func keepOwner(tx store.Store, telegramID int64) error {
+	admins, err := tx.Admins().List()
+	if err != nil {
+		return err
+	}
+	for _, a := range admins {
+		if a.Role == models.RoleOwner && a.TelegramID != telegramID {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+// lookupAdmin busca al usuario de Telegram entre los admins
+func lookupAdmin(st store.Store, telegramID int64) (models.Admin, bool) {
+	a, err := st.Admins().Get(telegramID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Error buscando admin %d: %v", telegramID, err)
+		}
+		return a, false
+	}
+	return a, true
+}
+
+// Reglas del login con contraseña
+const (
+	minPasswordLen = 10
+	maxPasswordLen = 72 // Límite de bcrypt
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,32}$`)
+
+	// ErrInvalidUsername se devuelve con un usuario fuera de usernamePattern
+	ErrInvalidUsername = errors.New("el usuario debe tener de 3 a 32 letras minúsculas, números, '.', '_' o '-'")
+	// ErrWeakPassword se devuelve con una contraseña muy corta o muy larga
+	ErrWeakPassword = fmt.Errorf("la contraseña debe tener de %d a %d caracteres", minPasswordLen, maxPasswordLen)
+	// ErrUsernameTaken se devuelve si otro admin ya usa el usuario
+	ErrUsernameTaken = errors.New("ese usuario ya lo usa otro admin")
+)
+
+// SetAdminPassword habilita el login con usuario y contraseña del admin
+// guardando el hash bcrypt. Con usuario y contraseña vacíos lo deshabilita.
+// by es quién hace el cambio.
+func SetAdminPassword(st store.Store, telegramID int64, username, password, by string) error {
+	var hash []byte
+	if username != "" || password != "" {
+		if !usernamePattern.MatchString(username) {
+			return ErrInvalidUsername
+		}
+		if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+			return ErrWeakPassword
+		}
+		var err error
+		if hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost); err != nil {
+			return err
+		}
+	}
+	err := st.Tx(func(tx store.Store) error {
+		return setPassword(tx, by, telegramID, username, string(hash))
+	})
+	if errors.Is(err, store.ErrConflict) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// passwordAudit es lo que queda en el audit log de un cambio de
+// contraseña: nunca el hash
+type passwordAudit struct {
+	Username    string `json:"username"`
+	HasPassword bool   `json:"has_password"`
+}
+
+func setPassword(tx store.Store, by string, telegramID int64, username, hash string) error {
+	old, err := tx.Admins().Get(telegramID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Admins().SetPassword(telegramID, username, hash); err != nil {
+		return err
+	}
+	return Audit(tx, adminEntry(by, models.AuditAdminPassword, telegramID),
+		passwordAudit{old.Username, old.HasPassword()},
+		passwordAudit{username, username != "" && hash != ""})
+}
+
+// passwordMigrationActor es el actor del audit log con el que
+// BootstrapPassword deja registrado que la migración ya corrió
+const passwordMigrationActor = "ADMIN_PASSWORD"
+
+// BootstrapPassword migra una sola vez el ADMIN_PASSWORD de versiones
+// anteriores: si ningún admin tiene contraseña, se la asigna al owner más
+// antiguo con el usuario "admin". La entrada en el audit log marca que ya
+// corrió, así que después la variable se ignora aunque se borren las
+// contraseñas (y se puede quitar).
+func BootstrapPassword(st store.Store, password string) (bool, error) {
+	if password == "" {
+		return false, nil
+	}
+	done, err := st.Audit().List(store.AuditFilter{Actor: passwordMigrationActor, Action: models.AuditAdminPassword, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(done) > 0 {
+		return false, nil
+	}
+	admins, err := st.Admins().List()
+	if err != nil {
+		return false, err
+	}
+	var owner *models.Admin
+	for i, a := range admins {
+		if a.HasPassword() {
+			return false, nil
+		}
+		if owner == nil && a.Role == models.RoleOwner {
+			owner = &admins[i]
+		}
+	}
+	if owner == nil {
+		return false, errors.New("ADMIN_PASSWORD necesita un owner (ADMIN_TELEGRAM_IDS) para asignarle la contraseña")
+	}
+	// Se hashea tal cual aunque no cumpla el largo de SetAdminPassword, para
+	// no dejar afuera a quien ya la usaba; esto pasa solo en esta migración y
+	// se puede cambiar desde /admin/admins
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	err = st.Tx(func(tx store.Store) error {
+		return setPassword(tx, passwordMigrationActor, owner.TelegramID, "admin", string(hash))
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"lotto-tg-app/internal/models"
+)
+
+func TestLastOwner(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ana := models.Admin{TelegramID: 1, Name: "Ana", Role: models.RoleOwner}
+			if err := SaveAdmin(st, ana, "test"); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveAdmin(st, models.Admin{TelegramID: 2, Name: "Beto", Role: models.RoleCashier}, "tg:1 Ana"); err != nil {
+				t.Fatal(err)
+			}
+
+			// Ana es el único owner: no se la puede degradar ni revocar
+			for _, role := range []string{models.RoleCashier, models.RoleViewer} {
+				if err := SaveAdmin(st, models.Admin{TelegramID: 1, Role: role}, "tg:1 Ana"); !errors.Is(err, ErrLastOwner) {
+					t.Fatalf("degradar al único owner a %s: %v, quería ErrLastOwner", role, err)
+				}
+			}
+			if _, err := RevokeAdmin(st, 1, "tg:1 Ana"); !errors.Is(err, ErrLastOwner) {
+				t.Fatalf("revocar al único owner: %v, quería ErrLastOwner", err)
+			}
+			if got, err := st.Admins().Get(1); err != nil || got.Role != models.RoleOwner || got.Name != "Ana" {
+				t.Fatalf("Ana quedó %+v (%v), quería owner", got, err)
+			}
+
+			// Con otro owner sí
+			if err := SaveAdmin(st, models.Admin{TelegramID: 2, Role: models.RoleOwner}, "tg:1 Ana"); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveAdmin(st, models.Admin{TelegramID: 1, Role: models.RoleViewer}, "tg:2 Beto"); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := st.Admins().Get(1); got.Role != models.RoleViewer || got.Name != "Ana" {
+				t.Fatalf("Ana quedó %+v, quería viewer con su nombre", got)
+			}
+			if _, err := RevokeAdmin(st, 1, "tg:2 Beto"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := RevokeAdmin(st, 2, "tg:2 Beto"); !errors.Is(err, ErrLastOwner) {
+				t.Fatalf("revocar al último owner que queda: %v, quería ErrLastOwner", err)
+			}
+
+			if err := SaveAdmin(st, models.Admin{TelegramID: 3, Role: "superadmin"}, "tg:2 Beto"); !errors.Is(err, ErrInvalidRole) {
+				t.Fatalf("rol inventado: %v, quería ErrInvalidRole", err)
+			}
+		})
+	}
+}
+
+func TestBootstrapPassword(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := BootstrapPassword(st, "vieja"); err == nil {
+				t.Fatal("migró la contraseña sin ningún owner")
+			}
+			if _, err := BootstrapAdmins(st, "1"); err != nil {
+				t.Fatal(err)
+			}
+
+			// La contraseña vieja se guarda aunque sea corta, para no dejar afuera al owner
+			if ok, err := BootstrapPassword(st, "vieja"); err != nil || !ok {
+				t.Fatalf("primera migración: %v (%v), quería true", ok, err)
+			}
+			owner, err := st.Admins().Get(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner.Username != "admin" || bcrypt.CompareHashAndPassword([]byte(owner.PasswordHash), []byte("vieja")) != nil {
+				t.Fatalf("owner quedó con usuario %q y otra contraseña", owner.Username)
+			}
+
+			// Ya corrió: aunque se quite la contraseña, reiniciar con la
+			// variable puesta no la vuelve a poner
+			if err := SetAdminPassword(st, 1, "", "", "tg:1"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := BootstrapPassword(st, "vieja"); err != nil || ok {
+				t.Fatalf("segunda migración: %v (%v), quería false", ok, err)
+			}
+			if owner, _ := st.Admins().Get(1); owner.HasPassword() {
+				t.Fatal("ADMIN_PASSWORD se volvió a aplicar")
+			}
+		})
+	}
+}
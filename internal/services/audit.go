@@ -0,0 +1,69 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// ActorReaper es el actor de las reservas que libera el Reaper por vencidas
+const ActorReaper = "reaper"
+
+// Audit guarda e en el audit log con before y after como JSON (nil los deja
+// vacíos). Se llama con el tx del cambio: si la transacción falla no queda
+// la entrada, y si no se puede guardar la entrada no se hace el cambio.
+func Audit(tx store.Store, e models.AuditEntry, before, after interface{}) error {
+	var err error
+	if e.Before, err = auditJSON(before); err != nil {
+		return err
+	}
+	if e.After, err = auditJSON(after); err != nil {
+		return err
+	}
+	return tx.Audit().Append(&e)
+}
+
+func auditJSON(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("audit log: %w", err)
+	}
+	return string(data), nil
+}
+
+// ticketEntry arma la entrada de una acción sobre el ticket t
+func ticketEntry(actor, action string, t models.Ticket) models.AuditEntry {
+	return models.AuditEntry{Actor: actor, Action: action, RaffleID: &t.RaffleID, TicketID: &t.ID}
+}
+
+// paymentEntry arma la entrada de una acción sobre el pago p y su ticket
+func paymentEntry(tx store.Store, actor, action string, p models.Payment) (models.AuditEntry, error) {
+	t, err := tx.Tickets().Get(p.TicketID)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	e := ticketEntry(actor, action, t)
+	e.PaymentID = &p.ID
+	return e, nil
+}
+
+// adminEntry arma la entrada de una acción sobre otro admin
+func adminEntry(actor, action string, telegramID int64) models.AuditEntry {
+	return models.AuditEntry{Actor: actor, Action: action, Target: fmt.Sprintf("admin:%d", telegramID)}
+}
+
+// ticketSnapshot es el estado de un ticket con sus pagos, para el audit log
+type ticketSnapshot struct {
+	Ticket   models.Ticket    `json:"ticket"`
+	Payments []models.Payment `json:"payments"`
+}
+
+func snapshotTicket(tx store.Store, t models.Ticket) (ticketSnapshot, error) {
+	payments, err := tx.Payments().ListByTicket(t.ID)
+	return ticketSnapshot{Ticket: t, Payments: payments}, err
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+func TestAuditedActionsWriteEntries(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	const actor = "tg:1 Ana"
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, []string{"01"}); err != nil {
+				t.Fatal(err)
+			}
+			ticket, err := st.Tickets().GetByNumber(raffle.ID, "01")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ticket, err = AddPayment(st, PaymentInput{TicketID: ticket.ID, Amount: models.Cents(10, 0), Method: "cash", Name: "Beto", Phone: "0414-0000001", AdminID: actor}, now); err != nil {
+				t.Fatal(err)
+			}
+			payments, err := st.Payments().ListByTicket(ticket.ID)
+			if err != nil || len(payments) != 1 {
+				t.Fatalf("%d pagos (%v), quería 1", len(payments), err)
+			}
+			if _, err := ReversePayment(st, payments[0].ID, actor, "monto equivocado", now); err != nil {
+				t.Fatal(err)
+			}
+			// Lo que falla no deja entrada: se deshace con su transacción
+			if _, err := ReversePayment(st, payments[0].ID, actor, "otra vez", now); !errors.Is(err, ErrAlreadyReversed) {
+				t.Fatalf("segunda reversión: %v, quería ErrAlreadyReversed", err)
+			}
+			if _, err := ReleaseTicket(st, ticket.ID, actor, ReleaseOptions{}, now); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := AddRate(st, models.ExchangeRate{Currency: "VES", Base: raffle.Currency, Rate: 40}, actor, now); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveAdmin(st, models.Admin{TelegramID: 2, Name: "Caro", Role: models.RoleCashier}, actor); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range []struct {
+				action       string
+				target       string // Sin ticket
+				before, paid bool
+			}{
+				{models.AuditPaymentAdd, "", true, true},
+				{models.AuditPaymentReverse, "", true, true},
+				{models.AuditTicketRelease, "", true, false},
+				{models.AuditRateAdd, "rate:VES/" + raffle.Currency, false, false},
+				{models.AuditAdminInvite, "admin:2", false, false},
+			} {
+				entries, err := st.Audit().List(store.AuditFilter{Action: c.action})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) != 1 {
+					t.Fatalf("%s: %d entradas, quería 1", c.action, len(entries))
+				}
+				e := entries[0]
+				if e.Actor != actor || e.After == "" {
+					t.Errorf("%s: %+v, quería actor %q y el después", c.action, e, actor)
+				}
+				if c.target != "" && e.Target != c.target {
+					t.Errorf("%s: destino %q, quería %q", c.action, e.Target, c.target)
+				}
+				if c.target == "" && (!e.CreatedAt.Equal(now) || e.TicketID == nil || *e.TicketID != ticket.ID || e.RaffleID == nil || *e.RaffleID != raffle.ID) {
+					t.Errorf("%s: %+v, quería el ticket #%d de la rifa %d a las %v", c.action, e, ticket.ID, raffle.ID, now)
+				}
+				if c.before && e.Before == "" {
+					t.Errorf("%s: sin el antes", c.action)
+				}
+				if c.paid && (e.PaymentID == nil || *e.PaymentID != payments[0].ID) {
+					t.Errorf("%s: pago %v, quería %d", c.action, e.PaymentID, payments[0].ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+const commandsHelp = `Comandos de administración:
+/raffles — rifas activas con vendidos y totales
+/ticket <rifa> <número> — ver un ticket
+/pay <rifa> <número> <monto> <método> [moneda] [ref] — registrar un pago; método es un código del catálogo (cash, transfer, pago_movil...) o saldo, que usa el saldo a favor del cliente; moneda (USD, VES, USDT) si no es la de la rifa (cashier)
+/release <rifa> <número> [devolver <método>|saldo|pasar <rifa> <número>] — liberar un ticket; si tiene pagos verificados, qué hacer con ese dinero (owner)
+/stats — resumen general
+/notify [categoría on|off] — elegir qué notificaciones recibir
+/stop — dejar de recibir notificaciones en este chat
+<rifa> es el ID que muestra /raffles`
+
+// categoryNames son las categorías de notificación como se muestran en el chat
+var categoryNames = map[string]string{
+	models.NotifyBookings: "reservas nuevas",
+	models.NotifyPayments: "pagos por verificar",
+	models.NotifyExpiries: "reservas vencidas",
+}
+
+// commandRoles es el rol mínimo de los comandos que modifican datos; el
+// resto solo consulta o toca las notificaciones del propio chat.
+var commandRoles = map[string]string{
+	"pay":     models.RoleCashier,
+	"release": models.RoleOwner,
+}
+
+// Sender es el admin que envía el comando (ya buscado en la tabla admins)
+type Sender struct {
+	ChatID     int64
+	TelegramID int64
+	Name       string
+	Role       string
+}
+
+// Can indica si el rol del admin alcanza para role
+func (s Sender) Can(role string) bool {
+	return models.Admin{Role: s.Role}.Can(role)
+}
+
+// Identity devuelve el formato de middleware.AdminIdentity ("tg:<id> <nombre>")
+func (s Sender) Identity() string {
+	return fmt.Sprintf("tg:%d %s", s.TelegramID, s.Name)
+}
+
+// Commands ejecuta los comandos de administración del bot con la misma
+// lógica que el panel web (AddPayment, ReleaseTicket, Stats).
+type Commands struct {
+	Store     store.Store
+	Now       func() time.Time
+	Customers *Customers // Avisa al cliente de /pay y /release; nil no avisa
+}
+
+// Run ejecuta el comando (sin "/") y devuelve la respuesta para el chat
+func (c *Commands) Run(from Sender, command, args string) string {
+	fields := strings.Fields(args)
+	admin := from.Identity()
+
+	if role, ok := commandRoles[command]; ok && !from.Can(role) {
+		log.Printf("Comando /%s rechazado para %s (rol %s)", command, admin, from.Role)
+		return commandError(command, ErrForbidden)
+	}
+
+	var reply string
+	var err error
+	switch command {
+	case "start":
+		reply, err = c.start(from)
+	case "stop":
+		reply, err = c.stop(from)
+	case "notify":
+		reply, err = c.notify(from, fields)
+	case "raffles":
+		reply, err = c.raffles()
+	case "ticket":
+		reply, err = c.ticket(fields)
+	case "pay":
+		reply, err = c.pay(admin, fields)
+	case "release":
+		reply, err = c.release(admin, fields)
+	case "stats":
+		reply, err = c.stats()
+	default:
+		return commandsHelp
+	}
+	if err != nil {
+		return commandError(command, err)
+	}
+	return reply
+}
+
+// usageError se devuelve cuando faltan argumentos o no tienen el formato esperado
+type usageError string
+
+func (e usageError) Error() string { return "Uso: " + string(e) }
+
+func commandError(command string, err error) string {
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		return usage.Error()
+	case errors.Is(err, store.ErrNotFound):
+		return "❌ No encontrado"
+	case errors.Is(err, store.ErrConflict):
+		return "❌ El ticket cambió de estado, vuelve a consultarlo"
+	case errors.Is(err, ErrSalesClosed), errors.Is(err, ErrNoCustomer), errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrReleaseMode), errors.Is(err, ErrReleaseHasMoney), errors.Is(err, ErrTransferTarget), errors.Is(err, ErrRefundMethod),
+		errors.Is(err, ErrNoCredit), errors.Is(err, ErrCurrency), errors.Is(err, ErrNoRate),
+		errors.Is(err, ErrCurrencyMismatch), errors.Is(err, ErrMethod):
+		return "❌ " + err.Error()
+	}
+	log.Printf("Error en comando /%s: %v", command, err)
+	return "❌ Error interno, revisa los logs"
+}
+
+// start registra el chat para recibir notificaciones. Si ya estaba
+// registrado conserva sus preferencias.
+func (c *Commands) start(from Sender) (string, error) {
+	r, err := c.Store.Recipients().Get(from.ChatID)
+	if errors.Is(err, store.ErrNotFound) {
+		r = models.Recipient{ChatID: from.ChatID, Bookings: true, Payments: true, Expiries: true}
+	} else if err != nil {
+		return "", err
+	}
+	r.TelegramID = from.TelegramID
+	r.Name = from.Name
+	if err := c.Store.Recipients().Save(r); err != nil {
+		return "", err
+	}
+	log.Printf("Chat %d registrado para notificaciones por %s", from.ChatID, from.Identity())
+
+	return fmt.Sprintf("¡Hola %s! Este chat recibirá las notificaciones.\n\n%s\n\n%s", from.Name, notifySettings(r), commandsHelp), nil
+}
+
+func (c *Commands) stop(from Sender) (string, error) {
+	if err := c.Store.Recipients().Delete(from.ChatID); err != nil {
+		return "", err
+	}
+	log.Printf("Chat %d dado de baja de notificaciones por %s", from.ChatID, from.Identity())
+	return "🔕 Este chat ya no recibirá notificaciones. Usa /start para volver a activarlas.", nil
+}
+
+// notify muestra o cambia las categorías que recibe el chat
+func (c *Commands) notify(from Sender, args []string) (string, error) {
+	r, err := c.Store.Recipients().Get(from.ChatID)
+	if errors.Is(err, store.ErrNotFound) {
+		return "Este chat no recibe notificaciones. Usa /start primero.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return notifySettings(r), nil
+	}
+
+	usage := usageError("/notify <" + strings.Join(models.NotifyCategories, "|") + "> on|off")
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return "", usage
+	}
+	if !r.SetWants(args[0], args[1] == "on") {
+		return "", usage
+	}
+	if err := c.Store.Recipients().Save(r); err != nil {
+		return "", err
+	}
+	return notifySettings(r), nil
+}
+
+func notifySettings(r models.Recipient) string {
+	var b strings.Builder
+	b.WriteString("🔔 Notificaciones de este chat:")
+	for _, category := range models.NotifyCategories {
+		mark := "❌"
+		if r.Wants(category) {
+			mark = "✅"
+		}
+		fmt.Fprintf(&b, "\n%s %s (%s)", mark, categoryNames[category], category)
+	}
+	return b.String()
+}
+
+// findTicket resuelve "<rifa> <número>"; el número se completa con ceros (7 -> 07)
+func (c *Commands) findTicket(raffleArg, numberArg string) (models.Raffle, models.Ticket, error) {
+	raffleID, err := strconv.ParseInt(raffleArg, 10, 64)
+	if err != nil {
+		return models.Raffle{}, models.Ticket{}, store.ErrNotFound
+	}
+	return FindTicket(c.Store, raffleID, numberArg)
+}
+
+func (c *Commands) raffles() (string, error) {
+	raffles, err := c.Store.Raffles().List("active", "closed")
+	if err != nil {
+		return "", err
+	}
+	if len(raffles) == 0 {
+		return "No hay rifas activas", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("🎟️ Rifas activas\n")
+	for _, raffle := range raffles {
+		s, err := Stats(c.Store, raffle)
+		if err != nil {
+			return "", err
+		}
+		closed := ""
+		if raffle.Status == "closed" {
+			closed = " 🔒"
+		}
+		fmt.Fprintf(&b, "\n#%d %s%s — %s\n", raffle.ID, raffle.Name, closed, raffle.Money(raffle.TicketPrice))
+		fmt.Fprintf(&b, "Vendidos %d/%d (%d pagados) · Cobrado %s · Por cobrar %s",
+			s.Sold, len(s.Tickets), s.Paid, raffle.Money(s.Collected), raffle.Money(s.Pending))
+		if s.Unverified > 0 {
+			fmt.Fprintf(&b, " · Por verificar %s", raffle.Money(s.Unverified))
+		}
+		for _, t := range s.ByCurrency {
+			if t.Currency != raffle.Currency && t.Original != 0 {
+				fmt.Fprintf(&b, "\n  En %s: %s = %s", t.Currency, models.FormatMoney(t.Original, t.Currency), raffle.Money(t.Amount))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func (c *Commands) ticket(args []string) (string, error) {
+	if len(args) != 2 {
+		return "", usageError("/ticket <rifa> <número>")
+	}
+	raffle, ticket, err := c.findTicket(args[0], args[1])
+	if err != nil {
+		return "", err
+	}
+	payments, err := c.Store.Payments().ListByTicket(ticket.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎟️ %s #%s — %s\n", raffle.Name, ticket.Number, ticket.Status)
+	if ticket.Status == "available" {
+		return b.String(), nil
+	}
+	fmt.Fprintf(&b, "👤 %s (%s)\n", ticket.UserName, ticket.UserPhone)
+	fmt.Fprintf(&b, "💰 Abonado %s de %s (verificado %s)\n",
+		raffle.Money(ticket.TotalPaid), raffle.Money(raffle.TicketPrice), raffle.Money(ticket.TotalVerified))
+	credit, err := c.Store.Credits().Balance(*ticket.UserID, raffle.Currency)
+	if err != nil {
+		return "", err
+	}
+	if credit > 0 {
+		fmt.Fprintf(&b, "💵 Saldo a favor del cliente: %s\n", raffle.Money(credit))
+	}
+	for _, p := range payments {
+		fmt.Fprintf(&b, "• %s %s %s %s", paymentMoney(raffle, p), p.Method, p.Status, p.Reference)
+		if p.Kind != models.PaymentKindPayment {
+			fmt.Fprintf(&b, " [%s: %s]", p.Kind, p.Reason)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// methodAliases traduce los nombres en español de los métodos de siempre a su
+// código; el resto se escribe con el código del catálogo (pago_movil,
+// zelle...). Qué métodos valen lo decide el catálogo, no esta lista.
+var methodAliases = map[string]string{
+	"cash":          "cash",
+	"efectivo":      "cash",
+	"transfer":      "transfer",
+	"transferencia": "transfer",
+	"credit":        MethodCredit,
+	"saldo":         MethodCredit,
+}
+
+// commandMethod es el código del método que escribió el admin
+func commandMethod(s string) string {
+	s = strings.ToLower(s)
+	if code, ok := methodAliases[s]; ok {
+		return code
+	}
+	return s
+}
+
+func (c *Commands) pay(admin string, args []string) (string, error) {
+	const usage = usageError("/pay <rifa> <número> <monto> <método> [moneda] [ref]")
+	if len(args) < 4 {
+		return "", usage
+	}
+	amount, err := models.ParseMoney(args[2])
+	if err != nil || amount <= 0 {
+		return "", usage
+	}
+	// AddPayment revisa que el método esté en el catálogo
+	method := commandMethod(args[3])
+	// La moneda es opcional: si el siguiente argumento no es una, es la referencia
+	currency := ""
+	reference := args[4:]
+	if len(reference) > 0 && models.ValidCurrency(strings.ToUpper(reference[0])) {
+		currency = strings.ToUpper(reference[0])
+		reference = reference[1:]
+	}
+
+	raffle, ticket, err := c.findTicket(args[0], args[1])
+	if err != nil {
+		return "", err
+	}
+	// Desde el chat no hay datos del cliente: solo tickets ya asignados
+	if ticket.Status == "available" {
+		return "", ErrNoCustomer
+	}
+
+	err = c.Store.Tx(func(tx store.Store) error {
+		var err error
+		ticket, err = AddPayment(tx, PaymentInput{
+			TicketID:  ticket.ID,
+			Amount:    amount,
+			Method:    method,
+			Reference: strings.Join(reference, " "),
+			Currency:  currency,
+			AdminID:   admin,
+		}, c.Now())
+		if err != nil {
+			return err
+		}
+		c.Customers.In(tx).PaymentAdded(ticket.ID, amount, currency)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if currency == "" {
+		currency = raffle.Currency
+	}
+	log.Printf("Pago de %s al ticket %d registrado por %s (bot)", models.FormatMoney(amount, currency), ticket.ID, admin)
+
+	return fmt.Sprintf("✅ Pago registrado: %s #%s — verificado %s de %s (%s)",
+		raffle.Name, ticket.Number, raffle.Money(ticket.TotalVerified), raffle.Money(raffle.TicketPrice), ticket.Status), nil
+}
+
+// releaseModes acepta qué hacer con lo cobrado en español o como en el panel
+var releaseModes = map[string]string{
+	"refund":   ReleaseRefund,
+	"devolver": ReleaseRefund,
+	"credit":   ReleaseCredit,
+	"saldo":    ReleaseCredit,
+	"transfer": ReleaseTransfer,
+	"pasar":    ReleaseTransfer,
+}
+
+func (c *Commands) release(admin string, args []string) (string, error) {
+	const usage = usageError("/release <rifa> <número> [devolver <método> | saldo | pasar <rifa> <número>]")
+	if len(args) < 2 {
+		return "", usage
+	}
+	raffle, ticket, err := c.findTicket(args[0], args[1])
+	if err != nil {
+		return "", err
+	}
+	if ticket.Status == "available" {
+		return fmt.Sprintf("%s #%s ya está disponible", raffle.Name, ticket.Number), nil
+	}
+
+	var opts ReleaseOptions
+	if len(args) > 2 {
+		mode, ok := releaseModes[strings.ToLower(args[2])]
+		if !ok {
+			return "", usage
+		}
+		opts.Mode = mode
+		switch {
+		case mode == ReleaseRefund && len(args) == 4:
+			// ReleaseTicket revisa que el método esté en el catálogo
+			opts.Method = commandMethod(args[3])
+		case mode == ReleaseCredit && len(args) == 3:
+		case mode == ReleaseTransfer && len(args) == 5:
+			_, to, err := c.findTicket(args[3], args[4])
+			if err != nil {
+				return "", err
+			}
+			opts.ToTicket = to.ID
+		default:
+			return "", usage
+		}
+	}
+
+	err = c.Store.Tx(func(tx store.Store) error {
+		released, err := ReleaseTicket(tx, ticket.ID, admin, opts, c.Now())
+		if err != nil {
+			return err
+		}
+		c.Customers.In(tx).Released(released, false, opts.Mode)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Ticket %d liberado por %s (bot)", ticket.ID, admin)
+
+	return fmt.Sprintf("🗑️ %s #%s liberado (era de %s)", raffle.Name, ticket.Number, ticket.UserName), nil
+}
+
+func (c *Commands) stats() (string, error) {
+	raffles, err := c.Store.Raffles().List("active", "closed")
+	if err != nil {
+		return "", err
+	}
+	pendingPayments, err := c.Store.Payments().ListPending()
+	if err != nil {
+		return "", err
+	}
+
+	// Los montos solo se suman entre rifas de la misma moneda
+	var total RaffleStats
+	var numbers int
+	collected := map[string]models.Money{}
+	pending := map[string]models.Money{}
+	unverified := map[string]models.Money{}
+	for _, raffle := range raffles {
+		s, err := Stats(c.Store, raffle)
+		if err != nil {
+			return "", err
+		}
+		numbers += len(s.Tickets)
+		total.Sold += s.Sold
+		total.Paid += s.Paid
+		collected[raffle.Currency] += s.Collected
+		unverified[raffle.Currency] += s.Unverified
+		pending[raffle.Currency] += s.Pending
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 Resumen (%d rifas en curso)\n", len(raffles))
+	fmt.Fprintf(&b, "Vendidos: %d/%d (%d pagados)\n", total.Sold, numbers, total.Paid)
+	fmt.Fprintf(&b, "Cobrado: %s\n", moneyByCurrency(collected))
+	fmt.Fprintf(&b, "Por cobrar: %s\n", moneyByCurrency(pending))
+	fmt.Fprintf(&b, "Por verificar: %s (%d pagos)", moneyByCurrency(unverified), len(pendingPayments))
+	return b.String(), nil
+}
+
+// moneyByCurrency lista los montos por moneda ("$120.00 · Bs 4500.00")
+func moneyByCurrency(amounts map[string]models.Money) string {
+	var parts []string
+	for _, currency := range models.Currencies {
+		if amount, ok := amounts[currency]; ok {
+			parts = append(parts, models.FormatMoney(amount, currency))
+		}
+	}
+	if len(parts) == 0 {
+		return models.FormatMoney(0, models.DefaultCurrency)
+	}
+	return strings.Join(parts, " · ")
+}
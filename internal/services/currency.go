@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Los precios, saldos y totales de una rifa están en su moneda (Raffle.Currency).
+// Un pago en otra moneda guarda el monto original y se convierte con la última
+// tasa cargada vigente a la hora del pago.
+
+var (
+	// ErrCurrency se devuelve con una moneda que no está en models.Currencies
+	ErrCurrency = fmt.Errorf("moneda no aceptada (usa %s)", strings.Join(models.Currencies, ", "))
+	// ErrNoRate se devuelve si no hay tasa para convertir el pago
+	ErrNoRate = errors.New("no hay tasa de cambio cargada")
+	// ErrInvalidRate se devuelve con una tasa que no es positiva o entre la misma moneda
+	ErrInvalidRate = errors.New("tasa inválida")
+	// ErrCurrencyMismatch se devuelve al pasar dinero entre rifas de distinta moneda
+	ErrCurrencyMismatch = errors.New("el ticket destino es de una rifa en otra moneda")
+	// ErrRatesFile se devuelve si el CSV de tasas no tiene el formato esperado
+	ErrRatesFile = errors.New("archivo de tasas inválido")
+)
+
+// Convert pasa amount de currency a base con la tasa vigente en at. Devuelve
+// el monto en base redondeado al centavo y la tasa usada (unidades de
+// currency por 1 de base).
+func Convert(st store.Store, amount models.Money, currency, base string, at time.Time) (models.Money, float64, error) {
+	if currency == base {
+		return amount, 1, nil
+	}
+	if !models.ValidCurrency(currency) {
+		return 0, 0, ErrCurrency
+	}
+	rate, err := findRate(st, currency, base, at)
+	if err != nil {
+		return 0, 0, err
+	}
+	return models.MoneyFromFloat(amount.Float() / rate), rate, nil
+}
+
+// findRate busca cuántas currency vale 1 base; si solo está cargada la
+// inversa (1 currency = x base) usa esa
+func findRate(st store.Store, currency, base string, at time.Time) (float64, error) {
+	r, err := st.Rates().Find(currency, base, at)
+	if err == nil {
+		return r.Rate, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return 0, err
+	}
+	r, err = st.Rates().Find(base, currency, at)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, fmt.Errorf("%w de %s a %s", ErrNoRate, currency, base)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1 / r.Rate, nil
+}
+
+// AddRate guarda la tasa 1 Base = Rate Currency desde ValidFrom (sin
+// ValidFrom, desde now) y la registra en el audit log
+func AddRate(st store.Store, r models.ExchangeRate, by string, now time.Time) (models.ExchangeRate, error) {
+	err := st.Tx(func(tx store.Store) error {
+		return addRate(tx, &r, by, now)
+	})
+	return r, err
+}
+
+func addRate(tx store.Store, r *models.ExchangeRate, by string, now time.Time) error {
+	if !models.ValidCurrency(r.Currency) || !models.ValidCurrency(r.Base) {
+		return ErrCurrency
+	}
+	if r.Currency == r.Base || r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) {
+		return ErrInvalidRate
+	}
+	if r.ValidFrom.IsZero() {
+		r.ValidFrom = now
+	}
+	r.CreatedBy = by
+	r.CreatedAt = now
+	if err := tx.Rates().Add(r); err != nil {
+		return err
+	}
+	e := models.AuditEntry{Actor: by, Action: models.AuditRateAdd, Target: "rate:" + r.Currency + "/" + r.Base, CreatedAt: now}
+	return Audit(tx, e, nil, r)
+}
+
+// ImportRates carga las tasas de un CSV con columnas fecha (AAAA-MM-DD),
+// moneda, tasa y opcionalmente la base (por defecto la moneda por defecto).
+// Una primera fila que no empieza con una fecha se toma como encabezado. Se
+// guardan todas o ninguna; devuelve cuántas se cargaron.
+func ImportRates(st store.Store, r io.Reader, by string, now time.Time) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // La base es opcional en cada línea
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRatesFile, err)
+	}
+
+	var rates []models.ExchangeRate
+	for i, row := range rows {
+		if len(row) < 3 || len(row) > 4 {
+			return 0, fmt.Errorf("%w: línea %d: se esperan fecha, moneda, tasa y base opcional", ErrRatesFile, i+1)
+		}
+		date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(row[0]), time.Local)
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return 0, fmt.Errorf("%w: línea %d: fecha inválida %q", ErrRatesFile, i+1, row[0])
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("línea %d: %w", i+1, ErrInvalidRate)
+		}
+		base := models.DefaultCurrency
+		if len(row) == 4 && strings.TrimSpace(row[3]) != "" {
+			base = strings.ToUpper(strings.TrimSpace(row[3]))
+		}
+		rates = append(rates, models.ExchangeRate{
+			Currency:  strings.ToUpper(strings.TrimSpace(row[1])),
+			Base:      base,
+			Rate:      rate,
+			ValidFrom: date,
+		})
+	}
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("%w: no tiene tasas", ErrRatesFile)
+	}
+
+	err = st.Tx(func(tx store.Store) error {
+		for i := range rates {
+			if err := addRate(tx, &rates[i], by, now); err != nil {
+				return fmt.Errorf("%s %s: %w", rates[i].ValidFrom.Format("2006-01-02"), rates[i].Currency, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
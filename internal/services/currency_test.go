@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestAddRateRejectsInvalidRates(t *testing.T) {
+	st := memstore.New()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, rate := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		_, err := AddRate(st, models.ExchangeRate{Currency: "VES", Base: "USD", Rate: rate}, "tg:1 Ana", now)
+		if !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("tasa %v: %v, quería ErrInvalidRate", rate, err)
+		}
+	}
+
+	// ParseFloat acepta "NaN": el CSV tampoco puede colarla
+	_, err := ImportRates(st, strings.NewReader("2026-10-01,VES,NaN\n"), "tg:1 Ana", now)
+	if !errors.Is(err, ErrInvalidRate) {
+		t.Fatalf("CSV con NaN: %v, quería ErrInvalidRate", err)
+	}
+	if rates, _ := st.Rates().List(10); len(rates) != 0 {
+		t.Fatalf("se guardaron %d tasas inválidas", len(rates))
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Customers avisa a los clientes con Telegram sobre sus tickets. Los clientes
+// cargados a mano por el admin (sin telegram_id) se ignoran. Un *Customers
+// nil no envía nada. El bot solo puede escribirle a quien abrió el chat con
+// él alguna vez; si no, el mensaje queda fallido en el outbox.
+type Customers struct {
+	Store store.Store
+	Send  func(st store.Store, telegramID int64, text string) // Por defecto QueueCustomer en st
+}
+
+// NewCustomers crea el notificador que encola los mensajes en el outbox
+func NewCustomers(st store.Store) *Customers {
+	return &Customers{Store: st, Send: func(st store.Store, telegramID int64, text string) {
+		if err := QueueCustomer(st, telegramID, text); err != nil {
+			log.Printf("Error encolando mensaje al cliente %d: %v", telegramID, err)
+		}
+	}}
+}
+
+// In devuelve el notificador que lee y encola dentro de la transacción tx:
+// el aviso se guarda junto con el cambio que anuncia, o se descarta con él
+func (c *Customers) In(tx store.Store) *Customers {
+	if c == nil {
+		return nil
+	}
+	in := *c
+	in.Store = tx
+	return &in
+}
+
+func (c *Customers) send(telegramID *int64, text string) {
+	if c == nil || c.Send == nil || telegramID == nil {
+		return
+	}
+	c.Send(c.Store, *telegramID, text)
+}
+
+// ticket relee el ticket para tener el telegram_id y los totales actuales
+func (c *Customers) ticket(id int64) (models.Ticket, bool) {
+	t, err := c.Store.Tickets().Get(id)
+	if err != nil {
+		log.Printf("Error leyendo ticket %d para avisar al cliente: %v", id, err)
+		return t, false
+	}
+	return t, t.UserTelegramID != nil
+}
+
+// Booked confirma la reserva recién hecha desde la Mini App: amount es el
+// pago reportado en currency y credit lo que se pagó con el saldo a favor
+func (c *Customers) Booked(telegramID *int64, raffle models.Raffle, numbers []string, amount models.Money, currency string, credit models.Money, at time.Time) {
+	if c == nil || telegramID == nil {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎟️ Reserva confirmada en %s\nNúmeros: #%s\nPrecio por número: %s",
+		raffle.Name, strings.Join(numbers, ", #"), raffle.Money(raffle.TicketPrice))
+	if amount > 0 {
+		fmt.Fprintf(&b, "\n💰 Pago reportado: %s (pendiente de verificación)", models.FormatMoney(amount, currency))
+	}
+	if credit > 0 {
+		fmt.Fprintf(&b, "\n💵 Saldo a favor usado: %s", raffle.Money(credit))
+	}
+	if raffle.ReserveHours > 0 {
+		deadline := at.Add(time.Duration(raffle.ReserveHours) * time.Hour)
+		fmt.Fprintf(&b, "\n⏰ Completa el pago antes del %s o la reserva se libera", deadline.Format("02/01 15:04"))
+	}
+	c.send(telegramID, b.String())
+}
+
+// PaymentAdded avisa del pago registrado por un admin (panel o /pay); amount
+// está en la moneda en que se recibió (vacía es la de la rifa)
+func (c *Customers) PaymentAdded(ticketID int64, amount models.Money, currency string) {
+	if c == nil {
+		return
+	}
+	t, ok := c.ticket(ticketID)
+	if !ok {
+		return
+	}
+	raffle, err := c.Store.Raffles().Get(t.RaffleID)
+	if err != nil {
+		log.Printf("Error leyendo rifa %d para avisar al cliente: %v", t.RaffleID, err)
+		return
+	}
+	if currency == "" {
+		currency = raffle.Currency
+	}
+	c.send(t.UserTelegramID, paymentText("✅ Recibimos tu pago de "+models.FormatMoney(amount, currency), raffle, t))
+}
+
+// PaymentReviewed avisa si la transferencia reportada se aprobó o se rechazó
+func (c *Customers) PaymentReviewed(paymentID int64) {
+	if c == nil {
+		return
+	}
+	p, err := c.Store.Payments().Get(paymentID)
+	if err != nil {
+		log.Printf("Error leyendo pago %d para avisar al cliente: %v", paymentID, err)
+		return
+	}
+	t, ok := c.ticket(p.TicketID)
+	if !ok {
+		return
+	}
+	raffle, err := c.Store.Raffles().Get(t.RaffleID)
+	if err != nil {
+		log.Printf("Error leyendo rifa %d para avisar al cliente: %v", t.RaffleID, err)
+		return
+	}
+
+	switch p.Status {
+	case "verified":
+		c.send(t.UserTelegramID, paymentText("✅ Verificamos tu pago de "+paymentMoney(raffle, p), raffle, t))
+	case "rejected":
+		c.send(t.UserTelegramID, fmt.Sprintf("❌ Tu pago de %s por %s #%s fue rechazado: %s\nEscríbenos si crees que es un error.",
+			paymentMoney(raffle, p), raffle.Name, t.Number, p.RejectReason))
+	}
+}
+
+// paymentMoney muestra el pago en la moneda en que se recibió y, si no es la de
+// la rifa, también convertido
+func paymentMoney(raffle models.Raffle, p models.Payment) string {
+	if p.Currency == "" || p.Currency == raffle.Currency {
+		return raffle.Money(p.Amount)
+	}
+	return fmt.Sprintf("%s (%s)", models.FormatMoney(p.OriginalAmount, p.Currency), raffle.Money(p.Amount))
+}
+
+func paymentText(title string, raffle models.Raffle, t models.Ticket) string {
+	text := fmt.Sprintf("%s por %s #%s\nVerificado: %s de %s", title, raffle.Name, t.Number, raffle.Money(t.TotalVerified), raffle.Money(raffle.TicketPrice))
+	if t.Status == "paid" {
+		text += "\n🎉 ¡Tu número está pagado! Suerte en el sorteo."
+	}
+	return text
+}
+
+// Reminder avisa que la reserva vence en breve sin estar pagada
+func (c *Customers) Reminder(t models.Ticket, raffle models.Raffle, deadline time.Time) {
+	if c == nil {
+		return
+	}
+	c.send(t.UserTelegramID, fmt.Sprintf("⏰ Tu reserva de %s #%s vence el %s.\nAbonado: %s de %s. Completa el pago para no perder el número.",
+		raffle.Name, t.Number, deadline.Format("02/01 15:04"), raffle.Money(t.TotalPaid), raffle.Money(raffle.TicketPrice)))
+}
+
+// Released avisa que el ticket t (como estaba antes de liberarse) dejó de ser
+// del cliente: expired si lo liberó el Reaper por falta de pago, si no fue un
+// admin. mode (ReleaseRefund, ReleaseCredit o ReleaseTransfer) dice qué se
+// hizo con lo que tenía verificado.
+func (c *Customers) Released(t models.Ticket, expired bool, mode string) {
+	if c == nil || t.UserTelegramID == nil {
+		return
+	}
+	raffle, err := c.Store.Raffles().Get(t.RaffleID)
+	if err != nil {
+		log.Printf("Error leyendo rifa %d para avisar al cliente: %v", t.RaffleID, err)
+		return
+	}
+	reason := "fue liberada por un administrador"
+	if expired {
+		reason = "se liberó porque venció el plazo de pago"
+	}
+	text := fmt.Sprintf("🗑️ Tu reserva de %s #%s %s. El número ya no está a tu nombre.", raffle.Name, t.Number, reason)
+	if t.TotalVerified > 0 {
+		switch mode {
+		case ReleaseRefund:
+			text += fmt.Sprintf("\n💸 Te devolvemos lo abonado (%s).", raffle.Money(t.TotalVerified))
+		case ReleaseCredit:
+			text += fmt.Sprintf("\n💰 Lo abonado (%s) queda como saldo a favor para tu próxima compra.", raffle.Money(t.TotalVerified))
+		case ReleaseTransfer:
+			text += fmt.Sprintf("\n🔁 Lo abonado (%s) pasó a otro de tus números.", raffle.Money(t.TotalVerified))
+		}
+	}
+	c.send(t.UserTelegramID, text)
+}
+
+// DrawResult envía el resultado a cada cliente con tickets en la rifa
+func (c *Customers) DrawResult(d models.Draw) {
+	if c == nil {
+		return
+	}
+	tickets, err := c.Store.Tickets().List(d.RaffleID, "")
+	if err != nil {
+		log.Printf("Error leyendo tickets de la rifa %d para avisar el sorteo: %v", d.RaffleID, err)
+		return
+	}
+
+	// Un mensaje por cliente con todos sus números
+	byCustomer := map[int64][]string{}
+	for _, t := range tickets {
+		if t.Status == "available" || t.UserTelegramID == nil {
+			continue
+		}
+		byCustomer[*t.UserTelegramID] = append(byCustomer[*t.UserTelegramID], t.Number)
+	}
+
+	ids := make([]int64, 0, len(byCustomer))
+	for id := range byCustomer {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		numbers := byCustomer[id]
+		text := fmt.Sprintf("🏆 Sorteo %s\nNúmero ganador: #%s\nTus números: #%s\n", d.RaffleName, d.WinningNumber, strings.Join(numbers, ", #"))
+		won := false
+		for _, n := range numbers {
+			if n == d.WinningNumber {
+				won = true
+			}
+		}
+		if won {
+			text += "🎉 ¡Felicidades, ganaste! Te contactaremos para entregarte el premio."
+		} else {
+			text += "Esta vez no ganaste. ¡Gracias por participar!"
+		}
+		c.send(&id, text)
+	}
+}
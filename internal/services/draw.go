@@ -0,0 +1,75 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// NewDrawSeed genera una semilla aleatoria (crypto/rand) de 32 bytes en hex
+func NewDrawSeed() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// DrawNumber deriva el número ganador a partir de la semilla de forma
+// determinista, para que cualquiera pueda auditar el resultado:
+//
+//	número = SHA-256("<seed>:<raffleID>") mod totalNumbers
+func DrawNumber(seed string, raffleID int64, totalNumbers int) (string, error) {
+	if totalNumbers <= 0 {
+		return "", fmt.Errorf("total de números inválido: %d", totalNumbers)
+	}
+	if _, err := hex.DecodeString(seed); err != nil || seed == "" {
+		return "", fmt.Errorf("semilla inválida")
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", seed, raffleID)))
+	n := new(big.Int).SetBytes(sum[:])
+	n.Mod(n, big.NewInt(int64(totalNumbers)))
+
+	return FormatNumber(int(n.Int64()), totalNumbers), nil
+}
+
+// FormatNumber rellena con ceros según el tamaño de la rifa (00-99, 000-999)
+func FormatNumber(n, totalNumbers int) string {
+	width := len(fmt.Sprintf("%d", totalNumbers-1))
+	return fmt.Sprintf("%0*d", width, n)
+}
+
+// RecordDraw guarda el resultado del sorteo de una rifa cerrada con el ticket
+// ganador y su dueño (si se vendió), pasa la rifa a 'finished' y lo registra
+// en el audit log. Una rifa se sortea una sola vez: si ya tiene resultado o
+// no está cerrada devuelve store.ErrConflict. Se llama con el tx donde se
+// encolan los avisos; devuelve el sorteo con los datos del ganador.
+func RecordDraw(tx store.Store, draw models.Draw) (models.Draw, error) {
+	ticket, err := tx.Tickets().GetByNumber(draw.RaffleID, draw.WinningNumber)
+	if err == nil {
+		draw.TicketID = &ticket.ID
+		draw.UserID = ticket.UserID
+		draw.TicketStatus = ticket.Status
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return models.Draw{}, err
+	}
+
+	if err := tx.Draws().Create(&draw); err != nil {
+		return models.Draw{}, err
+	}
+	if err := tx.Raffles().UpdateStatus(draw.RaffleID, "closed", "finished"); err != nil {
+		return models.Draw{}, err
+	}
+	e := models.AuditEntry{Actor: draw.DrawnBy, Action: models.AuditRaffleDraw, RaffleID: &draw.RaffleID, TicketID: draw.TicketID, CreatedAt: draw.DrawnAt}
+	if err := Audit(tx, e, map[string]string{"status": "closed"}, draw); err != nil {
+		return models.Draw{}, err
+	}
+	return tx.Draws().Get(draw.RaffleID)
+}
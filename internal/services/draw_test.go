@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+const testSeed = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+func TestDrawNumber(t *testing.T) {
+	// SHA-256("<seed>:<raffleID>") mod totalNumbers, calculado aparte
+	for _, c := range []struct {
+		raffleID int64
+		total    int
+		want     string
+	}{
+		{1, 100, "05"},
+		{7, 1000, "249"},
+		{1, 10, "5"},
+	} {
+		for range 2 {
+			got, err := DrawNumber(testSeed, c.raffleID, c.total)
+			if err != nil || got != c.want {
+				t.Fatalf("DrawNumber(rifa %d, %d) = %q (%v), quería %q", c.raffleID, c.total, got, err, c.want)
+			}
+		}
+	}
+
+	for _, seed := range []string{"", "no-es-hex"} {
+		if _, err := DrawNumber(seed, 1, 100); err == nil {
+			t.Errorf("aceptó la semilla %q", seed)
+		}
+	}
+	if _, err := DrawNumber(testSeed, 1, 0); err == nil {
+		t.Error("aceptó una rifa sin números")
+	}
+}
+
+func TestRecordDraw(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			numbers := make([]string, 100)
+			for i := range numbers {
+				numbers[i] = FormatNumber(i, 100)
+			}
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, numbers); err != nil {
+				t.Fatal(err)
+			}
+			winner, err := DrawNumber(testSeed, raffle.ID, raffle.TotalNumbers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ticket := reserveAt(t, st, raffle.ID, winner, "0414-0000001", nil, now)
+
+			record := func(number string) (models.Draw, error) {
+				var d models.Draw
+				err := st.Tx(func(tx store.Store) error {
+					var err error
+					d, err = RecordDraw(tx, models.Draw{RaffleID: raffle.ID, WinningNumber: number, Method: "random", Seed: testSeed, DrawnBy: "tg:1 Ana", DrawnAt: now})
+					return err
+				})
+				return d, err
+			}
+
+			// Con las ventas abiertas no se sortea
+			if _, err := record(winner); !errors.Is(err, store.ErrConflict) {
+				t.Fatalf("sorteo con la rifa activa: %v, quería ErrConflict", err)
+			}
+			if _, err := st.Draws().Get(raffle.ID); !errors.Is(err, store.ErrNotFound) {
+				t.Fatalf("quedó guardado el sorteo de la rifa activa (%v)", err)
+			}
+
+			if err := st.Raffles().UpdateStatus(raffle.ID, "active", "closed"); err != nil {
+				t.Fatal(err)
+			}
+			d, err := record(winner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.WinningNumber != winner || d.TicketID == nil || *d.TicketID != ticket.ID || d.UserID == nil || *d.UserID != *ticket.UserID || d.TicketStatus != "reserved" {
+				t.Fatalf("sorteo %+v, quería el #%s reservado por %d", d, winner, *ticket.UserID)
+			}
+			if got, _ := st.Raffles().Get(raffle.ID); got.Status != "finished" {
+				t.Fatalf("la rifa quedó %s, quería finished", got.Status)
+			}
+
+			// Una rifa se sortea una sola vez
+			other := "00"
+			if winner == other {
+				other = "01"
+			}
+			if _, err := record(other); !errors.Is(err, store.ErrConflict) {
+				t.Fatalf("segundo sorteo: %v, quería ErrConflict", err)
+			}
+			if got, err := st.Draws().Get(raffle.ID); err != nil || got.WinningNumber != winner {
+				t.Fatalf("el sorteo quedó con el #%s (%v), quería #%s", got.WinningNumber, err, winner)
+			}
+			if again := (models.Draw{RaffleID: raffle.ID, WinningNumber: other}); st.Draws().Create(&again) == nil {
+				t.Fatal("el store guardó un segundo sorteo de la rifa")
+			}
+
+			entries, err := st.Audit().List(store.AuditFilter{Action: models.AuditRaffleDraw})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].TicketID == nil || *entries[0].TicketID != ticket.ID {
+				t.Fatalf("auditoría %+v, quería una entrada del sorteo", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Los pagos son un libro: nunca se editan ni se borran. Corregir un monto mal
+// cargado es una reversión (y el pago correcto aparte); devolver dinero, pasarlo
+// al saldo a favor del cliente o a otro ticket son entradas con monto negativo.
+// El saldo de un ticket es la suma de las entradas verificadas de su cliente.
+
+var (
+	// ErrNotReversible se devuelve al revertir algo que no es un pago verificado
+	ErrNotReversible = errors.New("solo se revierten pagos verificados; los pendientes se rechazan")
+	// ErrAlreadyReversed se devuelve al revertir dos veces el mismo pago
+	ErrAlreadyReversed = errors.New("ese pago ya fue revertido")
+	// ErrInvalidAmount se devuelve con un monto que no es positivo
+	ErrInvalidAmount = errors.New("monto inválido")
+	// ErrOverBalance se devuelve al sacar del ticket más de lo cobrado
+	ErrOverBalance = errors.New("el monto supera lo cobrado en el ticket")
+	// ErrReasonRequired se devuelve sin el motivo de una reversión o devolución
+	ErrReasonRequired = errors.New("indica el motivo")
+	// ErrRefundMethod se devuelve si el método de una devolución no está en el catálogo
+	ErrRefundMethod = errors.New("indica cómo se devuelve el dinero (un método de pago del catálogo)")
+	// ErrReleaseMode se devuelve al liberar un ticket con dinero cobrado sin
+	// decir qué hacer con él
+	ErrReleaseMode = errors.New("el ticket tiene dinero cobrado: indica si se devuelve, queda como saldo a favor o pasa a otro ticket")
+	// ErrReleaseHasMoney se devuelve al liberar desde el botón del bot un
+	// ticket con dinero cobrado, que no tiene cómo preguntar qué hacer con él
+	ErrReleaseHasMoney = errors.New("el ticket tiene dinero cobrado: libéralo desde el panel o con /release")
+	// ErrTransferTarget se devuelve si el ticket destino de un traspaso es el
+	// mismo o de otro cliente
+	ErrTransferTarget = errors.New("el ticket destino tiene que estar disponible o ser del mismo cliente")
+	// ErrNoCredit se devuelve al usar más saldo a favor del que tiene el cliente
+	ErrNoCredit = errors.New("el cliente no tiene ese saldo a favor")
+)
+
+// MethodCredit es el método de AddPayment (y /pay saldo) que paga con el
+// saldo a favor del cliente en vez de dinero recibido
+const MethodCredit = "credit"
+
+// ActorBooking revisa el saldo a favor que se aplica solo al reservar
+const ActorBooking = "booking"
+
+// Qué hacer con lo cobrado al liberar un ticket
+const (
+	ReleaseRefund   = "refund"   // Se le devuelve al cliente
+	ReleaseCredit   = "credit"   // Queda como saldo a favor del cliente
+	ReleaseTransfer = "transfer" // Pasa a otro ticket del cliente (o uno disponible que se le asigna)
+)
+
+// ReleaseOptions dice qué hacer con lo cobrado (pagos verificados) al liberar.
+// Sin saldo no hace falta.
+type ReleaseOptions struct {
+	Mode     string // ReleaseRefund, ReleaseCredit o ReleaseTransfer
+	Method   string // Cómo se devuelve, con ReleaseRefund
+	ToTicket int64  // Ticket destino, con ReleaseTransfer
+	Reason   string
+}
+
+// ReversePayment anula un pago verificado cargado por error con una entrada
+// por el monto contrario; el pago original queda en el historial. Devuelve la
+// reversión.
+func ReversePayment(st store.Store, paymentID int64, by, reason string, now time.Time) (models.Payment, error) {
+	var reversal models.Payment
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return reversal, ErrReasonRequired
+	}
+	err := st.Tx(func(tx store.Store) error {
+		p, err := tx.Payments().Get(paymentID)
+		if err != nil {
+			return err
+		}
+		if p.Kind != models.PaymentKindPayment || p.Status != "verified" {
+			return ErrNotReversible
+		}
+		ticket, err := tx.Tickets().Get(p.TicketID)
+		if err != nil {
+			return err
+		}
+		entries, err := tx.Payments().ListByTicket(ticket.ID)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Kind == models.PaymentKindReversal && e.RelatedID != nil && *e.RelatedID == p.ID {
+				return ErrAlreadyReversed
+			}
+		}
+		// Un pago de una reserva anterior del número no es del cliente actual
+		if !samePtr(p.UserID, ticket.UserID) {
+			return ErrNotReversible
+		}
+		if p.Amount > ticket.TotalVerified {
+			return ErrOverBalance
+		}
+
+		reversal = models.Payment{
+			TicketID:   ticket.ID,
+			Amount:     -p.Amount,
+			Method:     p.Method,
+			Reference:  p.Reference,
+			Status:     "verified",
+			ReviewedBy: by,
+			ReviewedAt: &now,
+			Kind:       models.PaymentKindReversal,
+			RelatedID:  &p.ID,
+			Reason:     reason,
+			// En la misma moneda y con la misma tasa que el pago
+			Currency:       p.Currency,
+			OriginalAmount: -p.OriginalAmount,
+			Rate:           p.Rate,
+		}
+		if err := tx.Payments().Create(&reversal); err != nil {
+			return err
+		}
+		if err := SettleTicket(tx, ticket.ID); err != nil {
+			return err
+		}
+		e := ticketEntry(by, models.AuditPaymentReverse, ticket)
+		e.PaymentID = &p.ID
+		e.CreatedAt = now
+		return Audit(tx, e, p, reversal)
+	})
+	return reversal, err
+}
+
+// RefundTicket devuelve al cliente parte o todo lo cobrado en el ticket, que
+// sigue a su nombre (ej: pagó de más). Devuelve las entradas de la
+// devolución, una por moneda en que había entrado el dinero (ver moveOut).
+func RefundTicket(st store.Store, ticketID int64, amount models.Money, method, reason, by string, now time.Time) ([]models.Payment, error) {
+	var refund []models.Payment
+	reason = strings.TrimSpace(reason)
+	switch {
+	case amount <= 0:
+		return refund, ErrInvalidAmount
+	case reason == "":
+		return refund, ErrReasonRequired
+	}
+	err := st.Tx(func(tx store.Store) error {
+		if err := checkRefundMethod(tx, method); err != nil {
+			return err
+		}
+		ticket, err := tx.Tickets().Get(ticketID)
+		if err != nil {
+			return err
+		}
+		if ticket.UserID == nil || amount > ticket.TotalVerified {
+			return ErrOverBalance
+		}
+		refund, err = moveOut(tx, ticket, models.PaymentKindRefund, amount, method, reason, by, now)
+		if err != nil {
+			return err
+		}
+		if err := SettleTicket(tx, ticket.ID); err != nil {
+			return err
+		}
+		e := ticketEntry(by, models.AuditPaymentRefund, ticket)
+		e.PaymentID = &refund[0].ID
+		e.CreatedAt = now
+		return Audit(tx, e, ticket, refund)
+	})
+	return refund, err
+}
+
+// moveOut registra la salida de amount del ticket como kind. La salida sale
+// en las monedas en que entró el dinero (ver outflow), con una entrada por
+// moneda, para que los totales por moneda de la rifa sigan cuadrando.
+func moveOut(tx store.Store, ticket models.Ticket, kind string, amount models.Money, method, reason, by string, now time.Time) ([]models.Payment, error) {
+	parts, err := outflow(tx, ticket, amount)
+	if err != nil {
+		return nil, err
+	}
+	for i := range parts {
+		p := &parts[i]
+		p.TicketID = ticket.ID
+		p.Method = method
+		p.Status = "verified"
+		p.ReviewedBy = by
+		p.ReviewedAt = &now
+		p.Kind = kind
+		p.UserID = ticket.UserID
+		p.Reason = reason
+		if err := tx.Payments().Create(p); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// outflow reparte amount entre las monedas de lo cobrado en el ticket,
+// primero la de la rifa y después las demás por código, y devuelve una
+// entrada negativa por moneda con su monto original a la tasa promedio con
+// que entró. Lo que sale completo de una moneda sale con su original exacto.
+func outflow(tx store.Store, ticket models.Ticket, amount models.Money) ([]models.Payment, error) {
+	base, err := ticketCurrency(tx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := tx.Payments().ListByTicket(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	held := map[string]*models.CurrencyTotal{}
+	var currencies []string
+	for _, e := range entries {
+		if e.Status != "verified" {
+			continue
+		}
+		t, ok := held[e.Currency]
+		if !ok {
+			t = &models.CurrencyTotal{Currency: e.Currency}
+			held[e.Currency] = t
+			currencies = append(currencies, e.Currency)
+		}
+		t.Original += e.OriginalAmount
+		t.Amount += e.Amount
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		if (currencies[i] == base) != (currencies[j] == base) {
+			return currencies[i] == base
+		}
+		return currencies[i] < currencies[j]
+	})
+
+	var parts []models.Payment
+	for _, c := range currencies {
+		if amount <= 0 {
+			break
+		}
+		t := held[c]
+		if t.Amount <= 0 {
+			continue
+		}
+		part, original := t.Amount, t.Original
+		if amount < t.Amount {
+			part = amount
+			original = models.MoneyFromFloat(part.Float() * t.Original.Float() / t.Amount.Float())
+		}
+		parts = append(parts, models.Payment{
+			Amount:         -part,
+			Currency:       c,
+			OriginalAmount: -original,
+			Rate:           t.Original.Float() / t.Amount.Float(),
+		})
+		amount -= part
+	}
+	// Lo que no alcanzan a cubrir las entradas (no debería pasar: amount
+	// nunca supera lo verificado) sale en la moneda de la rifa
+	if amount > 0 {
+		parts = append(parts, models.Payment{Amount: -amount, Currency: base, OriginalAmount: -amount, Rate: 1})
+	}
+	return parts, nil
+}
+
+// checkRefundMethod revisa, como checkMethod, que el dinero se devuelva con un
+// método del catálogo; el saldo a favor no es una forma de devolverlo
+func checkRefundMethod(tx store.Store, code string) error {
+	if code == "" || code == MethodCredit {
+		return ErrRefundMethod
+	}
+	if err := checkMethod(tx, code); errors.Is(err, ErrMethod) {
+		return ErrRefundMethod
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkRelease valida las opciones antes de liberar un ticket con saldo
+func checkRelease(tx store.Store, opts ReleaseOptions) error {
+	switch opts.Mode {
+	case ReleaseRefund:
+		return checkRefundMethod(tx, opts.Method)
+	case ReleaseCredit:
+	case ReleaseTransfer:
+		if opts.ToTicket == 0 {
+			return ErrTransferTarget
+		}
+	default:
+		return ErrReleaseMode
+	}
+	return nil
+}
+
+// release cierra la reserva del ticket: rechaza los pagos pendientes, saca lo
+// cobrado según opts y lo devuelve a 'available'. action es la del audit log.
+func release(tx store.Store, ticket models.Ticket, by, action string, opts ReleaseOptions, now time.Time) error {
+	before, err := snapshotTicket(tx, ticket)
+	if err != nil {
+		return err
+	}
+	for _, p := range before.Payments {
+		if p.Status == "pending" {
+			if err := tx.Payments().Reject(p.ID, by, "Reserva liberada", now); err != nil {
+				return err
+			}
+		}
+	}
+
+	var moved []models.Payment
+	if balance := ticket.TotalVerified; balance > 0 {
+		if err := checkRelease(tx, opts); err != nil {
+			return err
+		}
+		reason := strings.TrimSpace(opts.Reason)
+		if reason == "" {
+			reason = "Reserva liberada"
+		}
+		switch opts.Mode {
+		case ReleaseRefund:
+			moved, err = moveOut(tx, ticket, models.PaymentKindRefund, balance, opts.Method, reason, by, now)
+		case ReleaseCredit:
+			moved, err = creditOut(tx, ticket, balance, reason, by, now)
+		case ReleaseTransfer:
+			moved, err = transferOut(tx, ticket, opts.ToTicket, balance, reason, by, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Tickets().Release(ticket.ID, ticket.Status); err != nil {
+		return err
+	}
+	after, err := tx.Tickets().Get(ticket.ID)
+	if err != nil {
+		return err
+	}
+	e := ticketEntry(by, action, ticket)
+	e.CreatedAt = now
+	return Audit(tx, e, before, map[string]interface{}{"ticket": after, "mode": opts.Mode, "moved": moved})
+}
+
+// creditOut pasa amount del ticket al saldo a favor de su cliente, que se
+// lleva en la moneda de la rifa
+func creditOut(tx store.Store, ticket models.Ticket, amount models.Money, reason, by string, now time.Time) ([]models.Payment, error) {
+	currency, err := ticketCurrency(tx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	out, err := moveOut(tx, ticket, models.PaymentKindCredit, amount, "", reason, by, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range out {
+		err := tx.Credits().Add(&models.Credit{
+			UserID:    *ticket.UserID,
+			Amount:    -p.Amount,
+			PaymentID: &p.ID,
+			Currency:  currency,
+			Reason:    reason,
+			CreatedBy: by,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// applyCredit paga amount del ticket con el saldo a favor de su cliente
+func applyCredit(tx store.Store, ticket models.Ticket, amount models.Money, by string, now time.Time) (models.Payment, error) {
+	if ticket.UserID == nil {
+		return models.Payment{}, ErrNoCustomer
+	}
+	if amount <= 0 {
+		return models.Payment{}, ErrInvalidAmount
+	}
+	currency, err := ticketCurrency(tx, ticket)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	balance, err := tx.Credits().Balance(*ticket.UserID, currency)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if amount > balance {
+		return models.Payment{}, ErrNoCredit
+	}
+
+	// El saldo a favor no guarda en qué moneda entró el dinero: se usa en la
+	// moneda de la rifa, la misma en que se acreditó
+	p := models.Payment{
+		TicketID:       ticket.ID,
+		Amount:         amount,
+		Method:         MethodCredit,
+		Reference:      "Saldo a favor",
+		Status:         "verified",
+		ReviewedBy:     by,
+		ReviewedAt:     &now,
+		Kind:           models.PaymentKindCredit,
+		UserID:         ticket.UserID,
+		Currency:       currency,
+		OriginalAmount: amount,
+		Rate:           1,
+	}
+	if err := tx.Payments().Create(&p); err != nil {
+		return p, err
+	}
+	return p, tx.Credits().Add(&models.Credit{
+		UserID:    *ticket.UserID,
+		Amount:    -amount,
+		PaymentID: &p.ID,
+		Currency:  currency,
+		Reason:    "Usado en el ticket #" + ticket.Number,
+		CreatedBy: by,
+		CreatedAt: now,
+	})
+}
+
+// UseCredit paga hasta upTo del ticket con el saldo a favor que tenga su
+// cliente y lo marca pagado si se cubre el precio. Devuelve cuánto se usó.
+func UseCredit(tx store.Store, ticketID int64, upTo models.Money, by string, now time.Time) (models.Money, error) {
+	ticket, err := tx.Tickets().Get(ticketID)
+	if err != nil || ticket.UserID == nil || upTo <= 0 {
+		return 0, err
+	}
+	currency, err := ticketCurrency(tx, ticket)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := tx.Credits().Balance(*ticket.UserID, currency)
+	if err != nil || balance <= 0 {
+		return 0, err
+	}
+	amount := min(balance, upTo)
+	if _, err := applyCredit(tx, ticket, amount, by, now); err != nil {
+		return 0, err
+	}
+	return amount, SettleTicket(tx, ticket.ID)
+}
+
+// transferOut pasa amount del ticket al ticket toID, que tiene que ser del
+// mismo cliente o estar disponible en una rifa activa (se le asigna). Cada
+// entrada del destino lleva la moneda y la tasa de su salida.
+func transferOut(tx store.Store, ticket models.Ticket, toID int64, amount models.Money, reason, by string, now time.Time) ([]models.Payment, error) {
+	to, err := tx.Tickets().Get(toID)
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == ticket.ID {
+		return nil, ErrTransferTarget
+	}
+	raffle, err := tx.Raffles().Get(to.RaffleID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := ticketCurrency(tx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	if currency != raffle.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	switch {
+	case to.Status == "available":
+		if raffle.Status != "active" {
+			return nil, ErrSalesClosed
+		}
+		if err := tx.Tickets().Reserve(to.ID, *ticket.UserID, now); err != nil {
+			return nil, err
+		}
+	case !samePtr(to.UserID, ticket.UserID):
+		return nil, ErrTransferTarget
+	}
+
+	out, err := moveOut(tx, ticket, models.PaymentKindTransfer, amount, "", reason, by, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		in := models.Payment{
+			TicketID:       to.ID,
+			Amount:         -out[i].Amount,
+			Reference:      fmt.Sprintf("Desde el ticket #%s", ticket.Number),
+			Status:         "verified",
+			ReviewedBy:     by,
+			ReviewedAt:     &now,
+			Kind:           models.PaymentKindTransfer,
+			UserID:         ticket.UserID,
+			RelatedID:      &out[i].ID,
+			Reason:         reason,
+			Currency:       out[i].Currency,
+			OriginalAmount: -out[i].OriginalAmount,
+			Rate:           out[i].Rate,
+		}
+		if err := tx.Payments().Create(&in); err != nil {
+			return nil, err
+		}
+	}
+	return out, SettleTicket(tx, to.ID)
+}
+
+// ticketCurrency devuelve la moneda de la rifa del ticket
+func ticketCurrency(tx store.Store, ticket models.Ticket) (string, error) {
+	raffle, err := tx.Raffles().Get(ticket.RaffleID)
+	return raffle.Currency, err
+}
+
+func samePtr(a, b *int64) bool {
+	return a != nil && b != nil && *a == *b
+}
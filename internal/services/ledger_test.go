@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestRefundMethodsComeFromTheCatalog(t *testing.T) {
+	st := memstore.New()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+	if err := st.Raffles().Create(&raffle, []string{"01", "02"}); err != nil {
+		t.Fatal(err)
+	}
+	var tickets []models.Ticket
+	for _, number := range []string{"01", "02"} {
+		ticket := reserveAt(t, st, raffle.ID, number, "0414-1234567", nil, now)
+		if _, err := AddPayment(st, PaymentInput{TicketID: ticket.ID, Amount: models.Cents(10, 0), Method: "cash", AdminID: "test"}, now); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	for _, method := range []string{"", "paypal", MethodCredit} {
+		if _, err := RefundTicket(st, tickets[0].ID, models.Cents(1, 0), method, "pagó de más", "tg:1 Ana", now); !errors.Is(err, ErrRefundMethod) {
+			t.Errorf("devolución por %q: %v, quería ErrRefundMethod", method, err)
+		}
+	}
+	// Cualquier método del catálogo sirve, no solo cash y transfer
+	refund, err := RefundTicket(st, tickets[0].ID, models.Cents(1, 0), "zelle", "pagó de más", "tg:1 Ana", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refund) != 1 || refund[0].Method != "zelle" || refund[0].Amount != -models.Cents(1, 0) {
+		t.Fatalf("devolución = %+v", refund)
+	}
+
+	opts := ReleaseOptions{Mode: ReleaseRefund, Method: "paypal", Reason: "cambio de idea"}
+	if _, err := ReleaseTicket(st, tickets[1].ID, "tg:1 Ana", opts, now); !errors.Is(err, ErrRefundMethod) {
+		t.Fatalf("liberar devolviendo por paypal: %v, quería ErrRefundMethod", err)
+	}
+	opts.Method = "pago_movil"
+	if _, err := ReleaseTicket(st, tickets[1].ID, "tg:1 Ana", opts, now); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.Tickets().Get(tickets[1].ID); got.Status != "available" {
+		t.Fatalf("#02 quedó %s, quería disponible", got.Status)
+	}
+}
+
+func TestOutgoingEntriesKeepTheirCurrency(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, []string{"01", "02", "03"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := AddRate(st, models.ExchangeRate{Currency: "VES", Base: raffle.Currency, Rate: 40, ValidFrom: now.Add(-time.Hour)}, "test", now); err != nil {
+				t.Fatal(err)
+			}
+			pay := func(ticketID int64, amount models.Money, currency string) {
+				t.Helper()
+				if _, err := AddPayment(st, PaymentInput{TicketID: ticketID, Amount: amount, Currency: currency, Method: "cash", AdminID: "test"}, now); err != nil {
+					t.Fatal(err)
+				}
+			}
+			totals := func() map[string]models.CurrencyTotal {
+				t.Helper()
+				list, err := st.Payments().TotalsByCurrency(raffle.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				byCurrency := map[string]models.CurrencyTotal{}
+				for _, c := range list {
+					byCurrency[c.Currency] = c
+				}
+				return byCurrency
+			}
+
+			// 01: Bs 400 (= 10.00); 02: 5.00 y Bs 200 (= 5.00)
+			ana := reserveAt(t, st, raffle.ID, "01", "0414-0000001", nil, now)
+			pay(ana.ID, models.Cents(400, 0), "VES")
+			beto := reserveAt(t, st, raffle.ID, "02", "0414-0000002", nil, now)
+			pay(beto.ID, models.Cents(5, 0), "")
+			pay(beto.ID, models.Cents(200, 0), "VES")
+
+			// Devolver 2.00 de lo que entró en bolívares sale en bolívares
+			refund, err := RefundTicket(st, ana.ID, models.Cents(2, 0), "pago_movil", "pagó de más", "test", now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(refund) != 1 || refund[0].Currency != "VES" || refund[0].OriginalAmount != -models.Cents(80, 0) || refund[0].Rate != 40 {
+				t.Fatalf("devolución %+v, quería Bs -80 a 40", refund)
+			}
+			got := totals()
+			if ves := got["VES"]; ves.Original != models.Cents(520, 0) || ves.Amount != models.Cents(13, 0) {
+				t.Fatalf("en VES %+v, quería Bs 520 = 13.00", ves)
+			}
+
+			// Liberar 01 como saldo a favor vacía su parte en bolívares
+			if _, err := ReleaseTicket(st, ana.ID, "test", ReleaseOptions{Mode: ReleaseCredit}, now); err != nil {
+				t.Fatal(err)
+			}
+			if balance, _ := st.Credits().Balance(*ana.UserID, raffle.Currency); balance != models.Cents(8, 0) {
+				t.Fatalf("saldo a favor %s, quería 8.00", balance)
+			}
+
+			// Pasar 02 al 03 mueve cada moneda con su tasa: los totales no cambian
+			before := totals()
+			target, err := st.Tickets().GetByNumber(raffle.ID, "03")
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := ReleaseOptions{Mode: ReleaseTransfer, ToTicket: target.ID, Reason: "cambió de número"}
+			if _, err := ReleaseTicket(st, beto.ID, "test", opts, now); err != nil {
+				t.Fatal(err)
+			}
+			got = totals()
+			for _, c := range []string{raffle.Currency, "VES"} {
+				if got[c] != before[c] {
+					t.Errorf("en %s quedó %+v, quería %+v", c, got[c], before[c])
+				}
+			}
+			if got[raffle.Currency].Amount != models.Cents(5, 0) || got["VES"].Original != models.Cents(200, 0) || got["VES"].Amount != models.Cents(5, 0) {
+				t.Fatalf("totales %+v, quería 5.00 y Bs 200 = 5.00", got)
+			}
+			var collected models.Money
+			for _, c := range got {
+				collected += c.Amount
+			}
+			if collected != models.Cents(10, 0) {
+				t.Fatalf("la suma por moneda da %s, quería lo cobrado: 10.00", collected)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Los métodos de pago son un catálogo que administran los owners. La Mini App
+// ofrece los públicos habilitados en la rifa (todos si la rifa no elige) con
+// sus instrucciones, y la referencia del cliente debe cumplir el formato del
+// método. Los admins registran pagos con cualquier método del catálogo.
+
+var (
+	// ErrMethod se devuelve con un método que no está en el catálogo o que la rifa no acepta
+	ErrMethod = errors.New("método de pago no aceptado")
+	// ErrMethodCode se devuelve al guardar un método con un código inválido
+	ErrMethodCode = errors.New("código de método inválido (minúsculas, números y _)")
+	// ErrMethodName se devuelve al guardar un método sin nombre
+	ErrMethodName = errors.New("indica el nombre del método")
+	// ErrRefPattern se devuelve al guardar un formato de referencia que no es una expresión regular
+	ErrRefPattern = errors.New("formato de referencia inválido")
+	// ErrReference se devuelve si la referencia del cliente no cumple el formato del método
+	ErrReference = errors.New("referencia inválida")
+)
+
+var methodCode = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// SaveMethod crea o actualiza el método m.Code del catálogo y lo registra en
+// el audit log
+func SaveMethod(st store.Store, m models.PaymentMethod, by string, now time.Time) (models.PaymentMethod, error) {
+	m.Code = strings.ToLower(strings.TrimSpace(m.Code))
+	m.Name = strings.TrimSpace(m.Name)
+	m.Instructions = strings.TrimSpace(m.Instructions)
+	m.RefPattern = strings.TrimSpace(m.RefPattern)
+	m.RefHint = strings.TrimSpace(m.RefHint)
+	switch {
+	case !methodCode.MatchString(m.Code) || m.Code == MethodCredit:
+		return m, ErrMethodCode
+	case m.Name == "":
+		return m, ErrMethodName
+	}
+	if _, err := referencePattern(m); err != nil {
+		return m, err
+	}
+	m.UpdatedBy = by
+	m.UpdatedAt = now
+
+	err := st.Tx(func(tx store.Store) error {
+		var before interface{}
+		old, err := tx.Methods().Get(m.Code)
+		switch {
+		case err == nil:
+			before = old
+		case !errors.Is(err, store.ErrNotFound):
+			return err
+		}
+		if err := tx.Methods().Save(m); err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: by, Action: models.AuditMethodSave, Target: "method:" + m.Code, CreatedAt: now}
+		return Audit(tx, e, before, m)
+	})
+	return m, err
+}
+
+// SetRaffleMethods elige qué métodos públicos ofrece la rifa; sin códigos
+// ofrece todos
+func SetRaffleMethods(st store.Store, raffleID int64, codes []string, by string, now time.Time) error {
+	return st.Tx(func(tx store.Store) error {
+		if _, err := tx.Raffles().Get(raffleID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if _, err := tx.Methods().Get(code); errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("%w: %s", ErrMethod, code)
+			} else if err != nil {
+				return err
+			}
+		}
+		before, err := tx.Methods().ForRaffle(raffleID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Methods().SetForRaffle(raffleID, codes); err != nil {
+			return err
+		}
+		after, err := tx.Methods().ForRaffle(raffleID)
+		if err != nil {
+			return err
+		}
+		e := models.AuditEntry{Actor: by, Action: models.AuditRaffleMethods, RaffleID: &raffleID, CreatedAt: now}
+		return Audit(tx, e, before, after)
+	})
+}
+
+// RaffleMethods devuelve los métodos que la Mini App ofrece en la rifa, en el
+// orden del catálogo
+func RaffleMethods(st store.Store, raffleID int64) ([]models.PaymentMethod, error) {
+	all, err := st.Methods().List()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := st.Methods().ForRaffle(raffleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []models.PaymentMethod
+	for _, m := range all {
+		if !m.Public {
+			continue
+		}
+		if len(enabled) > 0 && !slices.Contains(enabled, m.Code) {
+			continue
+		}
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// PublicMethod devuelve el método code si la Mini App lo ofrece en la rifa;
+// si no, ErrMethod
+func PublicMethod(st store.Store, raffleID int64, code string) (models.PaymentMethod, error) {
+	methods, err := RaffleMethods(st, raffleID)
+	if err != nil {
+		return models.PaymentMethod{}, err
+	}
+	for _, m := range methods {
+		if m.Code == code {
+			return m, nil
+		}
+	}
+	return models.PaymentMethod{}, ErrMethod
+}
+
+// CheckReference revisa que la referencia cumpla el formato del método. Con
+// formato la referencia es obligatoria; el error explica el formato (RefHint).
+func CheckReference(m models.PaymentMethod, ref string) error {
+	re, err := referencePattern(m)
+	if err != nil || re == nil {
+		return err
+	}
+	if re.MatchString(strings.TrimSpace(ref)) {
+		return nil
+	}
+	if m.RefHint != "" {
+		return fmt.Errorf("%w para %s: %s", ErrReference, m.Name, m.RefHint)
+	}
+	return fmt.Errorf("%w para %s", ErrReference, m.Name)
+}
+
+// referencePattern compila el formato del método para que cubra toda la
+// referencia; sin formato devuelve nil
+func referencePattern(m models.PaymentMethod) (*regexp.Regexp, error) {
+	if m.RefPattern == "" {
+		return nil, nil
+	}
+	if _, err := regexp.Compile(m.RefPattern); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRefPattern, err)
+	}
+	return regexp.MustCompile(`^(?:` + m.RefPattern + `)$`), nil
+}
+
+// checkMethod revisa que un pago registrado por un admin use un método del catálogo
+func checkMethod(tx store.Store, code string) error {
+	if code == MethodCredit {
+		return nil
+	}
+	_, err := tx.Methods().Get(code)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrMethod
+	}
+	return err
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/receipts"
+	"lotto-tg-app/internal/store"
+)
+
+// Las notificaciones no se envían en el request: se encolan en la tabla
+// outbox (en la misma transacción que la reserva o el pago) y el worker
+// Outbox las entrega con reintentos. Se encola un mensaje por chat.
+
+// QueueAdmin encola el texto para cada admin suscrito a la categoría
+// (models.NotifyBookings, ...). Una categoría vacía llega a todos.
+func QueueAdmin(st store.Store, category, text string) error {
+	return queueAdmins(st, category, models.OutboxMessage{Text: text})
+}
+
+// QueueAdminBooking encola la reserva nueva con los botones de BookingAlert
+func QueueAdminBooking(st store.Store, a BookingAlert) error {
+	m := models.OutboxMessage{Text: a.Text}
+	if markup, ok := a.Keyboard(); ok {
+		data, err := json.Marshal(markup)
+		if err != nil {
+			return err
+		}
+		m.ReplyMarkup = string(data)
+	}
+	return queueAdmins(st, models.NotifyBookings, m)
+}
+
+// QueueAdminReceipt encola para los admins de pagos el comprobante subido por
+// un cliente: se envía como foto si es imagen, como documento si es PDF.
+func QueueAdminReceipt(st store.Store, caption, receiptKey, receiptType string) error {
+	return queueAdmins(st, models.NotifyPayments, models.OutboxMessage{
+		Text:        caption,
+		ReceiptKey:  receiptKey,
+		ReceiptType: receiptType,
+	})
+}
+
+// QueueCustomer encola un mensaje privado al cliente (users.telegram_id)
+func QueueCustomer(st store.Store, telegramID int64, text string) error {
+	return st.Outbox().Enqueue(&models.OutboxMessage{ChatID: telegramID, Text: text})
+}
+
+func queueAdmins(st store.Store, category string, m models.OutboxMessage) error {
+	targets, err := notifyTargets(st, category)
+	if err != nil {
+		return err
+	}
+	for _, r := range targets {
+		msg := m
+		msg.ChatID = r.ChatID
+		if err := st.Outbox().Enqueue(&msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyTargets devuelve los chats que quieren la categoría
+func notifyTargets(st store.Store, category string) ([]models.Recipient, error) {
+	all, err := st.Recipients().List()
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		log.Println("Ningún admin registrado con /start, notificación descartada")
+		return nil, nil
+	}
+
+	var targets []models.Recipient
+	for _, r := range all {
+		if !r.Wants(category) {
+			continue
+		}
+		// Un admin revocado deja de recibir aunque su chat siga registrado
+		if _, err := st.Admins().Get(r.TelegramID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		targets = append(targets, r)
+	}
+	return targets, nil
+}
+
+// Límites de Telegram: ~30 mensajes por segundo en total, 1 por segundo al
+// mismo chat y 20 por minuto a un grupo.
+const (
+	outboxBatch     = 50
+	outboxLease     = 2 * time.Minute // Si la instancia muere enviando, otra lo retoma después
+	outboxGap       = 40 * time.Millisecond
+	privateChatGap  = time.Second
+	groupChatGap    = 3 * time.Second
+	outboxBaseDelay = 15 * time.Second
+	outboxMaxDelay  = time.Hour
+	// OutboxMaxAttempts es cuántas veces se intenta antes de dejarlo 'failed'
+	OutboxMaxAttempts = 8
+)
+
+// Outbox entrega en segundo plano los mensajes encolados. Los errores
+// temporales se reintentan con espera exponencial; los definitivos (el
+// usuario bloqueó al bot, el chat no existe) o los que agotan los intentos
+// quedan 'failed' para reintentarlos desde el panel.
+type Outbox struct {
+	Store    store.Store
+	Receipts receipts.Store // Para adjuntar comprobantes; nil los envía como texto
+	Interval time.Duration
+	Now      func() time.Time                 // Reloj inyectable (tests)
+	Send     func(c tgbotapi.Chattable) error // Por defecto Bot.Send
+	Sleep    func(time.Duration)              // Pausa entre envíos (tests)
+
+	lastSent map[int64]time.Time // Último envío por chat
+	stop     chan struct{}
+}
+
+// NewOutbox crea el worker que envía por el bot
+func NewOutbox(st store.Store, rs receipts.Store, interval time.Duration) *Outbox {
+	return &Outbox{
+		Store:    st,
+		Receipts: rs,
+		Interval: interval,
+		Now:      time.Now,
+		Send: func(c tgbotapi.Chattable) error {
+			if Bot == nil {
+				return errors.New("bot no iniciado")
+			}
+			_, err := Bot.Send(c)
+			return err
+		},
+		Sleep: time.Sleep,
+	}
+}
+
+// Start corre Flush en segundo plano cada Interval
+func (o *Outbox) Start() {
+	o.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(o.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := o.Flush(); err != nil {
+				log.Printf("Outbox: error enviando notificaciones: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-o.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop detiene el loop iniciado con Start
+func (o *Outbox) Stop() {
+	if o.stop != nil {
+		close(o.stop)
+	}
+}
+
+// Flush intenta enviar los mensajes vencidos. Devuelve cuántos se enviaron.
+func (o *Outbox) Flush() (int, error) {
+	if o.lastSent == nil {
+		o.lastSent = map[int64]time.Time{}
+	}
+	due, err := o.Store.Outbox().ListDue(o.Now(), outboxBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, m := range due {
+		now := o.Now()
+		// Queda para la próxima pasada sin contar como intento
+		if last, ok := o.lastSent[m.ChatID]; ok && now.Sub(last) < chatGap(m.ChatID) {
+			continue
+		}
+		err := o.Store.Outbox().Claim(m.ID, now, now.Add(outboxLease))
+		if errors.Is(err, store.ErrConflict) {
+			continue // Lo tomó otra instancia
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		if sendErr := o.deliver(m); sendErr != nil {
+			if err := o.failed(m, sendErr); err != nil {
+				return sent, err
+			}
+		} else {
+			if err := o.Store.Outbox().MarkSent(m.ID, o.Now()); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		o.lastSent[m.ChatID] = o.Now()
+		o.Sleep(outboxGap)
+	}
+	return sent, nil
+}
+
+// chatGap es la espera mínima entre mensajes al mismo chat (los grupos
+// tienen IDs negativos)
+func chatGap(chatID int64) time.Duration {
+	if chatID < 0 {
+		return groupChatGap
+	}
+	return privateChatGap
+}
+
+// failed reprograma el mensaje o lo deja 'failed' según el error
+func (o *Outbox) failed(m models.OutboxMessage, sendErr error) error {
+	attempts := m.Attempts + 1
+	now := o.Now()
+
+	var tgErr *tgbotapi.Error
+	if errors.As(sendErr, &tgErr) {
+		switch {
+		case tgErr.RetryAfter > 0:
+			// Flood control: esperar lo que pide Telegram sin gastar un intento
+			next := now.Add(time.Duration(tgErr.RetryAfter) * time.Second)
+			return o.Store.Outbox().Reschedule(m.ID, m.Attempts, sendErr.Error(), next)
+		case tgErr.Code == 400 || tgErr.Code == 403:
+			// Chat inexistente, bot bloqueado o expulsado: reintentar no sirve
+			log.Printf("Outbox: mensaje %d al chat %d descartado: %v", m.ID, m.ChatID, sendErr)
+			return o.Store.Outbox().Fail(m.ID, attempts, sendErr.Error())
+		}
+	}
+
+	if attempts >= OutboxMaxAttempts {
+		log.Printf("Outbox: mensaje %d al chat %d falló %d veces: %v", m.ID, m.ChatID, attempts, sendErr)
+		return o.Store.Outbox().Fail(m.ID, attempts, sendErr.Error())
+	}
+	return o.Store.Outbox().Reschedule(m.ID, attempts, sendErr.Error(), now.Add(backoff(attempts)))
+}
+
+// backoff duplica la espera en cada intento: 15s, 30s, 1m, 2m... hasta 1h
+func backoff(attempts int) time.Duration {
+	d := outboxBaseDelay
+	for i := 1; i < attempts && d < outboxMaxDelay; i++ {
+		d *= 2
+	}
+	if d > outboxMaxDelay {
+		d = outboxMaxDelay
+	}
+	return d
+}
+
+// deliver arma el mensaje de Telegram y lo envía
+func (o *Outbox) deliver(m models.OutboxMessage) error {
+	var markup interface{}
+	if m.ReplyMarkup != "" {
+		var kb tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(m.ReplyMarkup), &kb); err != nil {
+			return fmt.Errorf("teclado inválido: %w", err)
+		}
+		markup = kb
+	}
+
+	if m.ReceiptKey != "" && o.Receipts != nil {
+		data, err := o.openReceipt(m.ReceiptKey)
+		if err != nil {
+			return err
+		}
+		file := tgbotapi.FileBytes{Name: path.Base(m.ReceiptKey), Bytes: data}
+		if receipts.IsImage(m.ReceiptType) {
+			photo := tgbotapi.NewPhoto(m.ChatID, file)
+			photo.Caption = m.Text
+			photo.ReplyMarkup = markup
+			return o.Send(photo)
+		}
+		doc := tgbotapi.NewDocument(m.ChatID, file)
+		doc.Caption = m.Text
+		doc.ReplyMarkup = markup
+		return o.Send(doc)
+	}
+
+	msg := tgbotapi.NewMessage(m.ChatID, m.Text)
+	msg.ReplyMarkup = markup
+	return o.Send(msg)
+}
+
+func (o *Outbox) openReceipt(key string) ([]byte, error) {
+	f, err := o.Receipts.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("comprobante %s: %w", key, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
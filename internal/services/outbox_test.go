@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// fakeOutbox arma un Outbox con reloj fijo que se adelanta a mano y un Send
+// que devuelve lo que diga sendErr
+func fakeOutbox(st store.Store, now *time.Time, sendErr *error) *Outbox {
+	return &Outbox{
+		Store: st,
+		Now:   func() time.Time { return *now },
+		Send:  func(tgbotapi.Chattable) error { return *sendErr },
+		Sleep: func(time.Duration) {},
+	}
+}
+
+func enqueue(t *testing.T, st store.Store, chatID int64, at time.Time) models.OutboxMessage {
+	t.Helper()
+	m := models.OutboxMessage{ChatID: chatID, Text: "hola", NextAttemptAt: at}
+	if err := st.Outbox().Enqueue(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// pending devuelve el mensaje si está pendiente a la hora at
+func pending(t *testing.T, st store.Store, id int64, at time.Time) (models.OutboxMessage, bool) {
+	t.Helper()
+	due, err := st.Outbox().ListDue(at, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range due {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return models.OutboxMessage{}, false
+}
+
+func failedMessage(t *testing.T, st store.Store, id int64) (models.OutboxMessage, bool) {
+	t.Helper()
+	failed, err := st.Outbox().ListFailed(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range failed {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return models.OutboxMessage{}, false
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			sendErr := errors.New("timeout")
+			o := fakeOutbox(st, &now, &sendErr)
+			m := enqueue(t, st, 1, now)
+
+			// 15s, 30s, 1m... y al octavo intento queda 'failed'
+			delay := outboxBaseDelay
+			for attempt := 1; attempt < OutboxMaxAttempts; attempt++ {
+				if sent, err := o.Flush(); err != nil || sent != 0 {
+					t.Fatalf("intento %d: enviados %d (%v)", attempt, sent, err)
+				}
+				if _, ok := pending(t, st, m.ID, now.Add(delay-time.Second)); ok {
+					t.Fatalf("intento %d: pendiente antes de %v", attempt, delay)
+				}
+				got, ok := pending(t, st, m.ID, now.Add(delay))
+				if !ok {
+					t.Fatalf("intento %d: no quedó pendiente a los %v", attempt, delay)
+				}
+				if got.Attempts != attempt || !got.NextAttemptAt.Equal(now.Add(delay)) || got.LastError != "timeout" {
+					t.Fatalf("intento %d: %+v, quería next_attempt_at %v", attempt, got, now.Add(delay))
+				}
+				now = now.Add(delay)
+				delay *= 2
+			}
+			if _, err := o.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := failedMessage(t, st, m.ID)
+			if !ok || got.Attempts != OutboxMaxAttempts {
+				t.Fatalf("después de %d intentos: %+v, quería 'failed'", OutboxMaxAttempts, got)
+			}
+			if _, ok := pending(t, st, m.ID, now.Add(24*time.Hour)); ok {
+				t.Fatal("el mensaje fallido sigue pendiente")
+			}
+		})
+	}
+
+	if got := backoff(20); got != outboxMaxDelay {
+		t.Fatalf("backoff(20) = %v, quería %v", got, outboxMaxDelay)
+	}
+}
+
+func TestOutboxTelegramErrors(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			var sendErr error = &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 30}}
+			o := fakeOutbox(st, &now, &sendErr)
+
+			// Flood control: espera lo que pide Telegram sin gastar un intento
+			flood := enqueue(t, st, 1, now)
+			if _, err := o.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := pending(t, st, flood.ID, now.Add(30*time.Second))
+			if !ok || got.Attempts != 0 || !got.NextAttemptAt.Equal(now.Add(30*time.Second)) {
+				t.Fatalf("con RetryAfter: %+v, quería pendiente a los 30s sin intentos", got)
+			}
+
+			// Bot bloqueado: no se reintenta
+			now = now.Add(time.Minute)
+			sendErr = &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+			if _, err := o.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := failedMessage(t, st, flood.ID); !ok || got.Attempts != 1 {
+				t.Fatalf("con 403: %+v, quería 'failed' al primer intento", got)
+			}
+
+			now = now.Add(time.Minute)
+			sendErr = &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}
+			missing := enqueue(t, st, 2, now)
+			if _, err := o.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := failedMessage(t, st, missing.ID); !ok {
+				t.Fatal("con 400 el mensaje no quedó 'failed'")
+			}
+		})
+	}
+}
+
+func TestOutboxChatGap(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			var sendErr error
+			o := fakeOutbox(st, &now, &sendErr)
+			enqueue(t, st, 1, now)
+			second := enqueue(t, st, 1, now)
+			enqueue(t, st, -100, now)
+			group := enqueue(t, st, -100, now)
+
+			// Uno por chat en cada pasada; el resto espera sin gastar intentos
+			if sent, err := o.Flush(); err != nil || sent != 2 {
+				t.Fatalf("primera pasada: %d enviados (%v), quería 2", sent, err)
+			}
+			if got, ok := pending(t, st, second.ID, now); !ok || got.Attempts != 0 {
+				t.Fatalf("segundo mensaje al chat: %+v, quería pendiente sin intentos", got)
+			}
+
+			now = now.Add(privateChatGap)
+			if sent, err := o.Flush(); err != nil || sent != 1 {
+				t.Fatalf("al segundo: %d enviados (%v), quería 1", sent, err)
+			}
+			if _, ok := pending(t, st, group.ID, now); !ok {
+				t.Fatal("el grupo recibió dos mensajes en menos de 3s")
+			}
+
+			now = now.Add(groupChatGap)
+			if sent, err := o.Flush(); err != nil || sent != 1 {
+				t.Fatalf("a los 3s: %d enviados (%v), quería 1", sent, err)
+			}
+		})
+	}
+}
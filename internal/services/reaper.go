@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// ExpiredTicket es una reserva vencida que el Reaper devolvió a 'available'
+type ExpiredTicket struct {
+	TicketID       int64
+	Number         string
+	RaffleID       int64
+	RaffleName     string
+	UserName       string
+	UserPhone      string
+	UserTelegramID *int64
+	TotalPaid      models.Money
+	TotalVerified  models.Money // Queda como saldo a favor del cliente
+	Price          models.Money
+	Currency       string // De la rifa
+}
+
+// Reaper libera periódicamente los tickets reservados cuyo plazo
+// (reserved_at + raffles.reserve_hours) ya pasó sin completar el pago.
+// Lo abonado y verificado queda como saldo a favor del cliente (credits).
+// Antes de liberar, RemindBefore antes del vencimiento, le recuerda al cliente
+// que complete el pago.
+type Reaper struct {
+	Store        store.Store
+	Interval     time.Duration
+	RemindBefore time.Duration    // 0 desactiva los recordatorios
+	Now          func() time.Time // Reloj inyectable (tests)
+	Notify       func(string)     // Por defecto QueueAdmin (categoría expiries)
+	Customers    *Customers       // Avisos al cliente; nil no avisa
+
+	stop chan struct{}
+}
+
+// NewReaper crea un Reaper con reloj real, notificación al admin y a los clientes
+func NewReaper(st store.Store, interval time.Duration) *Reaper {
+	return &Reaper{
+		Store:     st,
+		Interval:  interval,
+		Now:       time.Now,
+		Customers: NewCustomers(st),
+		Notify: func(text string) {
+			if err := QueueAdmin(st, models.NotifyExpiries, text); err != nil {
+				log.Printf("Reaper: error encolando notificación: %v", err)
+			}
+		},
+	}
+}
+
+// Start corre Sweep en segundo plano cada Interval
+func (rp *Reaper) Start() {
+	rp.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(rp.Interval)
+		defer ticker.Stop()
+
+		rp.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				rp.runOnce()
+			case <-rp.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop detiene el loop iniciado con Start
+func (rp *Reaper) Stop() {
+	if rp.stop != nil {
+		close(rp.stop)
+	}
+}
+
+func (rp *Reaper) runOnce() {
+	if _, err := rp.Remind(); err != nil {
+		log.Printf("Reaper: error enviando recordatorios: %v", err)
+	}
+
+	released, err := rp.Sweep()
+	if err != nil {
+		log.Printf("Reaper: error liberando reservas vencidas: %v", err)
+	}
+	if len(released) > 0 && rp.Notify != nil {
+		rp.Notify(ExpirySummary(released))
+	}
+}
+
+// Remind avisa a los clientes cuyas reservas vencen dentro de RemindBefore.
+// Cada reserva se recuerda una sola vez (tickets.reminded_at). Devuelve
+// cuántos recordatorios se marcaron.
+func (rp *Reaper) Remind() (int, error) {
+	if rp.RemindBefore <= 0 {
+		return 0, nil
+	}
+	now := rp.Now()
+
+	expiring, err := rp.Store.Tickets().ListExpiring(now, rp.RemindBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	raffles := map[int64]models.Raffle{}
+	sent := 0
+	for _, t := range expiring {
+		raffle, ok := raffles[t.RaffleID]
+		if !ok {
+			if raffle, err = rp.Store.Raffles().Get(t.RaffleID); err != nil {
+				return sent, err
+			}
+			raffles[t.RaffleID] = raffle
+		}
+
+		// Igual que Sweep: no se molesta a quien ya reportó el total
+		if t.TotalPaid >= raffle.TicketPrice {
+			continue
+		}
+
+		// Se marca aunque el cliente no tenga Telegram para no revisarlo otra
+		// vez; el aviso se encola con la marca
+		err := rp.Store.Tx(func(tx store.Store) error {
+			if err := tx.Tickets().MarkReminded(t.ID, now); err != nil {
+				return err
+			}
+			deadline := t.ReservedAt.Add(time.Duration(raffle.ReserveHours) * time.Hour)
+			rp.Customers.In(tx).Reminder(t, raffle, deadline)
+			return nil
+		})
+		if err != nil {
+			return sent, fmt.Errorf("ticket %d: %w", t.ID, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Sweep busca las reservas vencidas y las libera. Devuelve los tickets liberados.
+func (rp *Reaper) Sweep() ([]ExpiredTicket, error) {
+	now := rp.Now()
+
+	expired, err := rp.Store.Tickets().ListExpired(now)
+	if err != nil {
+		return nil, err
+	}
+
+	raffles := map[int64]models.Raffle{}
+	var released []ExpiredTicket
+	for _, t := range expired {
+		raffle, ok := raffles[t.RaffleID]
+		if !ok {
+			if raffle, err = rp.Store.Raffles().Get(t.RaffleID); err != nil {
+				return released, err
+			}
+			raffles[t.RaffleID] = raffle
+		}
+
+		// Solo se liberan las que aún deben dinero
+		if t.TotalPaid >= raffle.TicketPrice {
+			continue
+		}
+
+		// Lo abonado queda como saldo a favor del cliente
+		var current models.Ticket
+		err := rp.Store.Tx(func(tx store.Store) error {
+			var err error
+			if current, err = tx.Tickets().Get(t.ID); err != nil {
+				return err
+			}
+			if current.Status != "reserved" {
+				return store.ErrConflict
+			}
+			opts := ReleaseOptions{Mode: ReleaseCredit, Reason: "Reserva vencida"}
+			if err := release(tx, current, ActorReaper, models.AuditTicketExpire, opts, now); err != nil {
+				return err
+			}
+			rp.Customers.In(tx).Released(current, true, opts.Mode)
+			return nil
+		})
+		// El ticket cambió de estado mientras tanto (ej: lo pagaron)
+		if errors.Is(err, store.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return released, fmt.Errorf("ticket %d: %w", t.ID, err)
+		}
+
+		released = append(released, ExpiredTicket{
+			TicketID:       t.ID,
+			Number:         t.Number,
+			RaffleID:       raffle.ID,
+			RaffleName:     raffle.Name,
+			UserName:       t.UserName,
+			UserPhone:      t.UserPhone,
+			UserTelegramID: t.UserTelegramID,
+			TotalPaid:      t.TotalPaid,
+			TotalVerified:  current.TotalVerified,
+			Price:          raffle.TicketPrice,
+			Currency:       raffle.Currency,
+		})
+	}
+	return released, nil
+}
+
+// ExpirySummary arma el mensaje para el admin con las reservas liberadas
+func ExpirySummary(released []ExpiredTicket) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏰ Reservas vencidas liberadas: %d\n", len(released))
+	for _, t := range released {
+		fmt.Fprintf(&b, "\n• %s #%s — %s (%s) abonó %s de %s", t.RaffleName, t.Number, t.UserName, t.UserPhone,
+			models.FormatMoney(t.TotalPaid, t.Currency), models.FormatMoney(t.Price, t.Currency))
+		if t.TotalVerified > 0 {
+			fmt.Fprintf(&b, ", %s quedan como saldo a favor", models.FormatMoney(t.TotalVerified, t.Currency))
+		}
+	}
+	return b.String()
+}
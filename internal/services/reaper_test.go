@@ -0,0 +1,144 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/db"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+	"lotto-tg-app/internal/store/memstore"
+	"lotto-tg-app/internal/store/sqlstore"
+)
+
+// testStores devuelve las dos implementaciones del store, la SQL sobre un
+// archivo SQLite nuevo con todas las migraciones
+func testStores(t *testing.T) map[string]store.Store {
+	t.Helper()
+	if err := db.Init("file:"+filepath.Join(t.TempDir(), "test.db"), ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	return map[string]store.Store{
+		"memstore": memstore.New(),
+		"sqlite":   sqlstore.New(db.DB),
+	}
+}
+
+// reserveAt reserva el número para un cliente nuevo con la hora dada
+func reserveAt(t *testing.T, st store.Store, raffleID int64, number, phone string, telegramID *int64, at time.Time) models.Ticket {
+	t.Helper()
+	user, err := FindOrCreateUser(st, "Cliente "+number, phone, telegramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := st.Tickets().GetByNumber(raffleID, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Tickets().Reserve(ticket.ID, user.ID, at); err != nil {
+		t.Fatal(err)
+	}
+	if ticket, err = st.Tickets().Get(ticket.ID); err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+func TestReaper(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, []string{"01", "02", "03", "04"}); err != nil {
+				t.Fatal(err)
+			}
+
+			owner, reminded := int64(101), int64(103)
+
+			// 01: vencida con 4.00 verificados
+			owing := reserveAt(t, st, raffle.ID, "01", "0414-0000001", &owner, now.Add(-25*time.Hour))
+			if _, err := AddPayment(st, PaymentInput{TicketID: owing.ID, Amount: models.Cents(4, 0), Method: "cash", AdminID: "test"}, now.Add(-24*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			// 02: vencida pero con el total reportado, pendiente de verificar
+			reported := reserveAt(t, st, raffle.ID, "02", "0414-0000002", nil, now.Add(-25*time.Hour))
+			pending := models.Payment{TicketID: reported.ID, Amount: raffle.TicketPrice, Method: "transfer", Status: "pending", Currency: raffle.Currency, OriginalAmount: raffle.TicketPrice}
+			if err := st.Payments().Create(&pending); err != nil {
+				t.Fatal(err)
+			}
+			// 03: vence en una hora; 04: vence en diez
+			soon := reserveAt(t, st, raffle.ID, "03", "0414-0000003", &reminded, now.Add(-23*time.Hour))
+			later := reserveAt(t, st, raffle.ID, "04", "0414-0000004", nil, now.Add(-14*time.Hour))
+
+			rp := &Reaper{Store: st, RemindBefore: 2 * time.Hour, Now: func() time.Time { return now }, Customers: NewCustomers(st)}
+
+			// Solo se recuerda la que vence dentro de RemindBefore, y una sola vez
+			sent, err := rp.Remind()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent != 1 {
+				t.Fatalf("Remind marcó %d, quería 1", sent)
+			}
+			if got, _ := st.Tickets().Get(soon.ID); got.RemindedAt == nil || !got.RemindedAt.Equal(now) {
+				t.Fatalf("reminded_at = %v, quería %v", got.RemindedAt, now)
+			}
+			if sent, err = rp.Remind(); err != nil || sent != 0 {
+				t.Fatalf("segundo Remind marcó %d (%v), quería 0", sent, err)
+			}
+
+			released, err := rp.Sweep()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(released) != 1 || released[0].TicketID != owing.ID {
+				t.Fatalf("Sweep liberó %+v, quería solo el #01", released)
+			}
+			if released[0].TotalVerified != models.Cents(4, 0) {
+				t.Fatalf("verificado %s, quería 4.00", released[0].TotalVerified)
+			}
+
+			// Lo verificado queda como saldo a favor del cliente
+			if got, _ := st.Tickets().Get(owing.ID); got.Status != "available" || got.UserID != nil {
+				t.Fatalf("#01 quedó %s de %v, quería disponible", got.Status, got.UserID)
+			}
+			balance, err := st.Credits().Balance(*owing.UserID, raffle.Currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance != models.Cents(4, 0) {
+				t.Fatalf("saldo %s, quería 4.00", balance)
+			}
+			for _, id := range []int64{reported.ID, soon.ID, later.ID} {
+				if got, _ := st.Tickets().Get(id); got.Status != "reserved" {
+					t.Fatalf("#%s quedó %s, quería reservado", got.Number, got.Status)
+				}
+			}
+
+			entries, err := st.Audit().List(store.AuditFilter{Action: models.AuditTicketExpire})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Actor != ActorReaper || !entries[0].CreatedAt.Equal(now) {
+				t.Fatalf("auditoría %+v, quería una entrada del reaper a las %v", entries, now)
+			}
+
+			// Con el reloj fijo una segunda pasada no encuentra nada
+			if released, err = rp.Sweep(); err != nil || len(released) != 0 {
+				t.Fatalf("segunda pasada liberó %d (%v), quería 0", len(released), err)
+			}
+
+			// Un aviso por recordatorio y por liberación, a cada cliente con Telegram
+			queued, err := st.Outbox().ListDue(time.Now().Add(time.Hour), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queued) != 2 || queued[0].ChatID != reminded || queued[1].ChatID != owner {
+				t.Fatalf("outbox = %+v, quería el recordatorio a %d y la liberación a %d", queued, reminded, owner)
+			}
+		})
+	}
+}
@@ -1,62 +1,140 @@
 package services
 
 import (
-	"fmt"
 	"log"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"lotto-tg-app/internal/store"
 )
 
 var Bot *tgbotapi.BotAPI
-var AdminChatID int64 // Este lo guardaremos cuando nos escribas /start
 
-func InitBot(token string) error {
+// commands atiende las actualizaciones, lleguen por polling o por webhook
+var commands *Commands
+
+// InitBot conecta el bot. Para recibir comandos hay que llamar después a
+// StartPolling o StartWebhook.
+func InitBot(token string, st store.Store) error {
 	var err error
 	Bot, err = tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return err
 	}
+	commands = &Commands{Store: st, Now: time.Now, Customers: NewCustomers(st)}
 
 	log.Printf("Bot autorizado en la cuenta %s", Bot.Self.UserName)
-	
-	// Correr un listener en segundo plano para captar el Admin ID
-	go listenForCommands()
-	
 	return nil
 }
 
-func listenForCommands() {
+// StartPolling recibe los comandos con long polling en segundo plano. Borra
+// antes el webhook, si quedó uno registrado, porque Telegram no permite ambos.
+func StartPolling() {
+	if _, err := Bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Error borrando webhook: %v", err)
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-
 	updates := Bot.GetUpdatesChan(u)
 
-	for update := range updates {
-		if update.Message == nil {
-			continue
+	go func() {
+		for update := range updates {
+			handleUpdate(commands, update)
 		}
+	}()
+	log.Println("Bot recibiendo comandos por long polling")
+}
 
-		if update.Message.IsCommand() {
-			switch update.Message.Command() {
-			case "start":
-				AdminChatID = update.Message.Chat.ID
-				msg := tgbotapi.NewMessage(AdminChatID, fmt.Sprintf("¡Hola Admin! Tu ID ha sido registrado: %d. Ahora recibirás notificaciones aquí.", AdminChatID))
-				Bot.Send(msg)
-				log.Printf("Admin Chat ID registrado: %d", AdminChatID)
-			}
-		}
+func handleUpdate(cmds *Commands, update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		handleCallback(cmds, update.CallbackQuery)
+		return
+	}
+	if update.Message == nil || !update.Message.IsCommand() {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	from := update.Message.From
+	// Solo los admins de la tabla admins pueden usar el bot
+	if from == nil {
+		Bot.Send(tgbotapi.NewMessage(chatID, "No autorizado"))
+		return
+	}
+	admin, ok := lookupAdmin(cmds.Store, from.ID)
+	if !ok {
+		log.Printf("Comando /%s rechazado: %d no es admin", update.Message.Command(), from.ID)
+		Bot.Send(tgbotapi.NewMessage(chatID, "No autorizado"))
+		return
+	}
+
+	sender := Sender{ChatID: chatID, TelegramID: from.ID, Name: from.FirstName, Role: admin.Role}
+	reply := cmds.Run(sender, update.Message.Command(), update.Message.CommandArguments())
+	if _, err := Bot.Send(tgbotapi.NewMessage(chatID, reply)); err != nil {
+		log.Printf("Error respondiendo comando: %v", err)
 	}
 }
 
-func NotifyAdmin(text string) {
-	if Bot == nil || AdminChatID == 0 {
-		log.Println("Bot no iniciado o AdminChatID desconocido")
+// handleCallback ejecuta un botón de una notificación y edita el mensaje
+// para que se vea quién actuó y el resultado
+func handleCallback(cmds *Commands, q *tgbotapi.CallbackQuery) {
+	if q.From == nil {
+		Bot.Request(tgbotapi.NewCallbackWithAlert(q.ID, "No autorizado"))
+		return
+	}
+	admin, ok := lookupAdmin(cmds.Store, q.From.ID)
+	if !ok {
+		Bot.Request(tgbotapi.NewCallbackWithAlert(q.ID, "No autorizado"))
+		return
+	}
+	if q.Message == nil {
+		Bot.Request(tgbotapi.NewCallback(q.ID, ""))
 		return
 	}
 
-	msg := tgbotapi.NewMessage(AdminChatID, text)
-	_, err := Bot.Send(msg)
+	sender := Sender{ChatID: q.Message.Chat.ID, TelegramID: q.From.ID, Name: q.From.FirstName, Role: admin.Role}
+	result, err := cmds.Action(sender, q.Data)
 	if err != nil {
-		log.Printf("Error enviando notificación: %v", err)
+		// commandError registra en el log los errores inesperados
+		Bot.Request(tgbotapi.NewCallbackWithAlert(q.ID, commandError("botón "+q.Data, err)))
+		return
+	}
+	Bot.Request(tgbotapi.NewCallback(q.ID, result.Text))
+
+	text := q.Message.Text + "\n\n" + result.Text
+	var edit tgbotapi.EditMessageTextConfig
+	if markup, ok := remainingButtons(q.Message.ReplyMarkup, q.Data, result.RemoveAll); ok {
+		edit = tgbotapi.NewEditMessageTextAndMarkup(sender.ChatID, q.Message.MessageID, text, markup)
+	} else {
+		edit = tgbotapi.NewEditMessageText(sender.ChatID, q.Message.MessageID, text)
+	}
+	if _, err := Bot.Send(edit); err != nil {
+		log.Printf("Error editando notificación %d: %v", q.Message.MessageID, err)
+	}
+}
+
+// remainingButtons quita del teclado el botón pulsado (o todos los de acción)
+// y deja los enlaces
+func remainingButtons(markup *tgbotapi.InlineKeyboardMarkup, pressed string, removeAll bool) (tgbotapi.InlineKeyboardMarkup, bool) {
+	if markup == nil {
+		return tgbotapi.InlineKeyboardMarkup{}, false
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, row := range markup.InlineKeyboard {
+		var kept []tgbotapi.InlineKeyboardButton
+		for _, b := range row {
+			if b.CallbackData != nil && (removeAll || *b.CallbackData == pressed) {
+				continue
+			}
+			kept = append(kept, b)
+		}
+		if len(kept) > 0 {
+			rows = append(rows, kept)
+		}
+	}
+	if len(rows) == 0 {
+		return tgbotapi.InlineKeyboardMarkup{}, false
 	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...), true
 }
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// Operaciones de administración compartidas por el panel web (handlers) y
+// los comandos del bot, para que ambos apliquen las mismas reglas.
+
+var (
+	// ErrSalesClosed se devuelve al vender un número de una rifa que no está activa
+	ErrSalesClosed = errors.New("las ventas de esta rifa están cerradas")
+	// ErrNoCustomer se devuelve al asignar un ticket disponible sin datos del cliente
+	ErrNoCustomer = errors.New("indica el nombre y teléfono del cliente")
+)
+
+// PaymentInput es un pago recibido por un admin (efectivo o transferencia ya
+// confirmada) o pagado con el saldo a favor del cliente (MethodCredit)
+type PaymentInput struct {
+	TicketID  int64
+	Amount    models.Money
+	Method    string
+	Reference string
+	// Moneda en que se recibió Amount; vacía es la de la rifa. El saldo a
+	// favor siempre se usa en la moneda de la rifa.
+	Currency string
+	// Cliente, solo para tickets disponibles
+	Name  string
+	Phone string
+	// Admin que registra el pago (middleware.AdminIdentity o el usuario del bot)
+	AdminID string
+}
+
+// FindOrCreateUser reutiliza el cliente existente en vez de crear uno por reserva.
+// Primero busca por Telegram ID (initData validado), luego por teléfono
+// normalizado. El teléfono lo escribe quien reserva, así que no vincula su
+// Telegram a un cliente que no lo tenía: se crea otro con el mismo teléfono,
+// que aparece en los duplicados para que un admin los una.
+func FindOrCreateUser(tx store.Store, name, phone string, telegramID *int64) (models.User, error) {
+	name = strings.TrimSpace(name)
+	phone = strings.TrimSpace(phone)
+
+	if telegramID != nil {
+		u, err := tx.Users().FindByTelegramID(*telegramID)
+		if err == nil {
+			if u.Phone == "" && phone != "" {
+				u.Phone = phone
+				return u, tx.Users().Update(u)
+			}
+			return u, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return u, err
+		}
+	}
+
+	u, err := tx.Users().FindByPhone(phone)
+	if err == nil {
+		// Con Telegram solo se reutiliza el cliente de esa misma cuenta
+		if telegramID == nil || (u.TelegramID != nil && *u.TelegramID == *telegramID) {
+			return u, nil
+		}
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return u, err
+	}
+
+	u = models.User{Name: name, Phone: phone, TelegramID: telegramID}
+	return u, tx.Users().Create(&u)
+}
+
+// FindTicket busca el número de la rifa; acepta el número sin los ceros a la
+// izquierda (ej: "7" en una rifa 00-99).
+func FindTicket(st store.Store, raffleID int64, number string) (models.Raffle, models.Ticket, error) {
+	raffle, err := st.Raffles().Get(raffleID)
+	if err != nil {
+		return raffle, models.Ticket{}, err
+	}
+	if n, err := strconv.Atoi(number); err == nil && n >= 0 {
+		number = FormatNumber(n, raffle.TotalNumbers)
+	}
+	ticket, err := st.Tickets().GetByNumber(raffle.ID, number)
+	return raffle, ticket, err
+}
+
+// SettleTicket marca el ticket como pagado cuando los pagos verificados
+// cubren el precio, y lo vuelve a reservado si una reversión o devolución lo
+// dejó debiendo. Los pagos pendientes no cuentan.
+func SettleTicket(tx store.Store, ticketID int64) error {
+	ticket, err := tx.Tickets().Get(ticketID)
+	if err != nil {
+		return err
+	}
+	if ticket.Status != "reserved" && ticket.Status != "paid" {
+		return nil
+	}
+	raffle, err := tx.Raffles().Get(ticket.RaffleID)
+	if err != nil {
+		return err
+	}
+	verified, err := tx.Payments().TotalByTicket(ticket.ID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ticket.Status == "reserved" && verified >= raffle.TicketPrice:
+		return tx.Tickets().MarkPaid(ticket.ID)
+	case ticket.Status == "paid" && verified < raffle.TicketPrice:
+		return tx.Tickets().MarkUnpaid(ticket.ID)
+	}
+	return nil
+}
+
+// AddPayment registra un pago verificado. Si el ticket está disponible lo
+// asigna primero al cliente (solo en rifas activas). Devuelve el ticket actualizado.
+func AddPayment(st store.Store, in PaymentInput, now time.Time) (models.Ticket, error) {
+	var ticket models.Ticket
+	err := st.Tx(func(tx store.Store) error {
+		var err error
+		if ticket, err = tx.Tickets().Get(in.TicketID); err != nil {
+			return err
+		}
+		raffle, err := tx.Raffles().Get(ticket.RaffleID)
+		if err != nil {
+			return err
+		}
+		before, err := snapshotTicket(tx, ticket)
+		if err != nil {
+			return err
+		}
+		if err := checkMethod(tx, in.Method); err != nil {
+			return err
+		}
+
+		// 1. If ticket is available, we need to assign a user first
+		if ticket.Status == "available" {
+			// New sales are only allowed while the raffle is active
+			if raffle.Status != "active" {
+				return ErrSalesClosed
+			}
+			if strings.TrimSpace(in.Name) == "" && strings.TrimSpace(in.Phone) == "" {
+				return ErrNoCustomer
+			}
+
+			// Reuse the customer if the phone is already registered
+			user, err := FindOrCreateUser(tx, in.Name, in.Phone, nil)
+			if err != nil {
+				return err
+			}
+			// Conditional on 'available': a customer may have booked it meanwhile
+			if err := tx.Tickets().Reserve(ticket.ID, user.ID, now); err != nil {
+				return err
+			}
+		}
+
+		// 2. Insert Payment (received by the admin, so already verified)
+		var payment models.Payment
+		if in.Method == MethodCredit {
+			// Paid from the customer's credit balance (the ticket may have just been reserved)
+			if ticket, err = tx.Tickets().Get(ticket.ID); err != nil {
+				return err
+			}
+			if payment, err = applyCredit(tx, ticket, in.Amount, in.AdminID, now); err != nil {
+				return err
+			}
+		} else {
+			currency := in.Currency
+			if currency == "" {
+				currency = raffle.Currency
+			}
+			amount, rate, err := Convert(tx, in.Amount, currency, raffle.Currency, now)
+			if err != nil {
+				return err
+			}
+			// Less than a cent once converted to the raffle's currency
+			if amount <= 0 {
+				return ErrInvalidAmount
+			}
+			payment = models.Payment{
+				TicketID:       ticket.ID,
+				Amount:         amount,
+				Method:         in.Method,
+				Reference:      in.Reference,
+				Status:         "verified",
+				ReviewedBy:     in.AdminID,
+				ReviewedAt:     &now,
+				Currency:       currency,
+				OriginalAmount: in.Amount,
+				Rate:           rate,
+			}
+			if err := tx.Payments().Create(&payment); err != nil {
+				return err
+			}
+		}
+
+		// 3. Check if fully paid
+		if err := SettleTicket(tx, ticket.ID); err != nil {
+			return err
+		}
+		if ticket, err = tx.Tickets().Get(ticket.ID); err != nil {
+			return err
+		}
+
+		after, err := snapshotTicket(tx, ticket)
+		if err != nil {
+			return err
+		}
+		e := ticketEntry(in.AdminID, models.AuditPaymentAdd, ticket)
+		e.PaymentID = &payment.ID
+		e.CreatedAt = now
+		return Audit(tx, e, before, after)
+	})
+	return ticket, err
+}
+
+// VerifyPayment aprueba un pago 'pending' y marca el ticket pagado si ya se cubrió el precio
+func VerifyPayment(st store.Store, paymentID int64, by string, now time.Time) error {
+	return st.Tx(func(tx store.Store) error {
+		p, err := tx.Payments().Get(paymentID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Payments().Verify(p.ID, by, now); err != nil {
+			return err
+		}
+		if err := SettleTicket(tx, p.TicketID); err != nil {
+			return err
+		}
+		return auditReview(tx, by, models.AuditPaymentVerify, p, now)
+	})
+}
+
+// RejectPayment rechaza un pago 'pending'. Queda en el historial con el
+// motivo y no cuenta para el saldo.
+func RejectPayment(st store.Store, paymentID int64, by, reason string, now time.Time) error {
+	return st.Tx(func(tx store.Store) error {
+		p, err := tx.Payments().Get(paymentID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Payments().Reject(p.ID, by, reason, now); err != nil {
+			return err
+		}
+		return auditReview(tx, by, models.AuditPaymentReject, p, now)
+	})
+}
+
+// auditReview registra la revisión del pago before con su estado nuevo
+func auditReview(tx store.Store, by, action string, before models.Payment, now time.Time) error {
+	after, err := tx.Payments().Get(before.ID)
+	if err != nil {
+		return err
+	}
+	e, err := paymentEntry(tx, by, action, after)
+	if err != nil {
+		return err
+	}
+	e.CreatedAt = now
+	return Audit(tx, e, before, after)
+}
+
+// ReleaseTicket devuelve el ticket a 'available'. Los pagos pendientes se
+// rechazan y lo cobrado se devuelve, queda como saldo a favor o pasa a otro
+// ticket según opts; todo queda en el libro de pagos y en el audit log.
+func ReleaseTicket(st store.Store, ticketID int64, by string, opts ReleaseOptions, now time.Time) (models.Ticket, error) {
+	var ticket models.Ticket
+	err := st.Tx(func(tx store.Store) error {
+		var err error
+		if ticket, err = tx.Tickets().Get(ticketID); err != nil {
+			return err
+		}
+		return release(tx, ticket, by, models.AuditTicketRelease, opts, now)
+	})
+	return ticket, err
+}
+
+// ReleaseBooking libera los tickets de una reserva que siguen a nombre de
+// userID. Los que ya se liberaron o pasaron a otro cliente se saltan; si
+// alguno tiene pagos verificados no libera ninguno (ErrReleaseHasMoney), hay
+// que decidir qué hacer con ese dinero con ReleaseTicket. Devuelve los
+// tickets liberados, como estaban antes de liberarse.
+func ReleaseBooking(st store.Store, userID int64, ticketIDs []int64, by string, now time.Time) ([]models.Ticket, error) {
+	var released []models.Ticket
+	err := st.Tx(func(tx store.Store) error {
+		released = nil
+		for _, id := range ticketIDs {
+			ticket, err := tx.Tickets().Get(id)
+			if err != nil {
+				return err
+			}
+			if ticket.UserID == nil || *ticket.UserID != userID {
+				continue
+			}
+			if ticket.TotalVerified > 0 {
+				return fmt.Errorf("#%s: %w", ticket.Number, ErrReleaseHasMoney)
+			}
+			opts := ReleaseOptions{Mode: ReleaseCredit, Reason: "Reserva liberada"}
+			if err := release(tx, ticket, by, models.AuditTicketRelease, opts, now); err != nil {
+				return err
+			}
+			released = append(released, ticket)
+		}
+		return nil
+	})
+	return released, err
+}
+
+// RaffleStats resume la venta de una rifa
+type RaffleStats struct {
+	Raffle     models.Raffle
+	Tickets    []models.Ticket // Con Remaining calculado
+	Sold       int             // Reservados o pagados
+	Paid       int
+	Collected  models.Money // Pagos verificados
+	Unverified models.Money // Reportados por clientes, por verificar
+	Pending    models.Money // Lo que falta cobrar de los vendidos
+	// Lo verificado según la moneda en que se recibió
+	ByCurrency []models.CurrencyTotal
+}
+
+// Stats calcula los totales de la rifa a partir de sus tickets
+func Stats(st store.Store, raffle models.Raffle) (RaffleStats, error) {
+	tickets, err := st.Tickets().List(raffle.ID, "")
+	if err != nil {
+		return RaffleStats{}, err
+	}
+
+	s := RaffleStats{Raffle: raffle, Tickets: tickets}
+	for i := range tickets {
+		t := &tickets[i]
+		t.Remaining = raffle.TicketPrice - t.TotalPaid
+		if t.Status == "available" {
+			continue
+		}
+		s.Sold++
+		if t.Status == "paid" {
+			s.Paid++
+		}
+		s.Collected += t.TotalVerified
+		s.Unverified += t.TotalPaid - t.TotalVerified
+		s.Pending += t.Remaining
+	}
+	s.ByCurrency, err = st.Payments().TotalsByCurrency(raffle.ID)
+	return s, err
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store/memstore"
+)
+
+func TestFindOrCreateUserDoesNotTakeOverByPhone(t *testing.T) {
+	st := memstore.New()
+	victim := models.User{Name: "Ana", Phone: "0414-1234567"}
+	if err := st.Users().Create(&victim); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin Telegram (admin, bot) el teléfono basta
+	u, err := FindOrCreateUser(st, "Ana", "+58 414 1234567", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != victim.ID {
+		t.Fatalf("sin Telegram: cliente %d, quería %d", u.ID, victim.ID)
+	}
+
+	// Desde la Mini App el teléfono de otro no le pasa el Telegram
+	booker := int64(777)
+	u, err = FindOrCreateUser(st, "Bo", "04141234567", &booker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID == victim.ID {
+		t.Fatal("reutilizó el cliente de otro por el teléfono")
+	}
+	if got, _ := st.Users().Get(victim.ID); got.TelegramID != nil {
+		t.Fatalf("el cliente original quedó con telegram_id %d", *got.TelegramID)
+	}
+
+	// La misma cuenta vuelve a su cliente, y el par queda en duplicados
+	again, err := FindOrCreateUser(st, "Bo", "04141234567", &booker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != u.ID {
+		t.Fatalf("la misma cuenta creó otro cliente: %d y %d", u.ID, again.ID)
+	}
+	groups, err := st.Users().Duplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("duplicados = %v, quería un par", groups)
+	}
+}
+
+func TestReleaseBookingKeepsTicketsWithMoney(t *testing.T) {
+	st := memstore.New()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+	if err := st.Raffles().Create(&raffle, []string{"01", "02"}); err != nil {
+		t.Fatal(err)
+	}
+	unpaid := reserveAt(t, st, raffle.ID, "01", "0414-1234567", nil, now)
+	paid := reserveAt(t, st, raffle.ID, "02", "0414-1234567", nil, now)
+	if _, err := AddPayment(st, PaymentInput{TicketID: paid.ID, Amount: models.Cents(4, 0), Method: "cash", AdminID: "test"}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// El botón no sabe qué hacer con lo cobrado: no libera nada
+	_, err := ReleaseBooking(st, *unpaid.UserID, []int64{unpaid.ID, paid.ID}, "tg:1 Ana", now)
+	if !errors.Is(err, ErrReleaseHasMoney) {
+		t.Fatalf("error %v, quería ErrReleaseHasMoney", err)
+	}
+	for _, id := range []int64{unpaid.ID, paid.ID} {
+		if got, _ := st.Tickets().Get(id); got.Status != "reserved" {
+			t.Fatalf("#%s quedó %s, quería reservado", got.Number, got.Status)
+		}
+	}
+
+	released, err := ReleaseBooking(st, *unpaid.UserID, []int64{unpaid.ID}, "tg:1 Ana", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0].ID != unpaid.ID {
+		t.Fatalf("liberó %+v, quería solo el #01", released)
+	}
+}
+
+func TestAddPaymentInstallmentsAddUpExactly(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			raffle := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(100, 0), ReserveHours: 24}
+			if err := st.Raffles().Create(&raffle, []string{"07"}); err != nil {
+				t.Fatal(err)
+			}
+			ticket := reserveAt(t, st, raffle.ID, "07", "0414-1234567", nil, now)
+
+			// Tres cuotas de 33.33 dejan el ticket a un centavo
+			for i := 0; i < 3; i++ {
+				got, err := AddPayment(st, PaymentInput{TicketID: ticket.ID, Amount: models.Cents(33, 33), Method: "cash", AdminID: "test"}, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != "reserved" {
+					t.Fatalf("cuota %d: ticket %s, quería reservado", i+1, got.Status)
+				}
+			}
+			got, err := AddPayment(st, PaymentInput{TicketID: ticket.ID, Amount: 1, Method: "cash", AdminID: "test"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != "paid" || got.TotalVerified != raffle.TicketPrice {
+				t.Fatalf("ticket %s con %s verificado, quería pagado con 100.00", got.Status, got.TotalVerified)
+			}
+		})
+	}
+}
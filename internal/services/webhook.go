@@ -0,0 +1,76 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// WebhookPath es la ruta donde Telegram envía las actualizaciones en modo webhook
+const WebhookPath = "/telegram/webhook"
+
+// secretHeader lo agrega Telegram a cada actualización con el secret_token
+// registrado en setWebhook
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookSecret devuelve el secreto configurado o, si está vacío, uno
+// derivado del token del bot: así todas las instancias usan el mismo sin
+// configurar nada más. Telegram solo acepta A-Z, a-z, 0-9, _ y -.
+func WebhookSecret(configured, token string) string {
+	if configured != "" {
+		return configured
+	}
+	sum := sha256.Sum256([]byte("webhook:" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartWebhook registra publicURL+WebhookPath en Telegram y devuelve el
+// handler que hay que montar en esa ruta. Si falla, usar StartPolling.
+func StartWebhook(publicURL, secret string) (http.Handler, error) {
+	if Bot == nil {
+		return nil, errors.New("bot no iniciado")
+	}
+	if !strings.HasPrefix(publicURL, "https://") {
+		return nil, fmt.Errorf("la URL pública debe ser https: %q", publicURL)
+	}
+	hookURL := strings.TrimSuffix(publicURL, "/") + WebhookPath
+
+	// La librería no soporta secret_token, se arma el setWebhook a mano
+	params := tgbotapi.Params{"url": hookURL, "secret_token": secret}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return nil, err
+	}
+	if _, err := Bot.MakeRequest("setWebhook", params); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Bot recibiendo comandos por webhook en %s", hookURL)
+	return webhookHandler(commands, secret), nil
+}
+
+// webhookHandler valida el secreto y procesa la actualización. Responde 200
+// aunque el comando falle: cualquier otro código hace que Telegram la reenvíe.
+func webhookHandler(cmds *Commands, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(secretHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			return
+		}
+
+		update, err := Bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, "Actualización inválida", http.StatusBadRequest)
+			return
+		}
+		handleUpdate(cmds, *update)
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,94 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type adminStore struct{ s *Store }
+
+func (as adminStore) List() ([]models.Admin, error) {
+	as.s.lock()
+	defer as.s.unlock()
+
+	var admins []models.Admin
+	for _, a := range as.s.d.admins {
+		admins = append(admins, a)
+	}
+	sort.Slice(admins, func(i, j int) bool {
+		if !admins[i].CreatedAt.Equal(admins[j].CreatedAt) {
+			return admins[i].CreatedAt.Before(admins[j].CreatedAt)
+		}
+		return admins[i].TelegramID < admins[j].TelegramID
+	})
+	return admins, nil
+}
+
+func (as adminStore) Get(telegramID int64) (models.Admin, error) {
+	as.s.lock()
+	defer as.s.unlock()
+
+	a, ok := as.s.d.admins[telegramID]
+	if !ok {
+		return models.Admin{}, store.ErrNotFound
+	}
+	return a, nil
+}
+
+func (as adminStore) GetByUsername(username string) (models.Admin, error) {
+	as.s.lock()
+	defer as.s.unlock()
+
+	for _, a := range as.s.d.admins {
+		if username != "" && a.Username == username {
+			return a, nil
+		}
+	}
+	return models.Admin{}, store.ErrNotFound
+}
+
+func (as adminStore) Save(a models.Admin) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	if old, ok := as.s.d.admins[a.TelegramID]; ok {
+		a.CreatedAt = old.CreatedAt
+		a.InvitedBy = old.InvitedBy
+		a.Username = old.Username
+		a.PasswordHash = old.PasswordHash
+	} else if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	as.s.d.admins[a.TelegramID] = a
+	return nil
+}
+
+func (as adminStore) SetPassword(telegramID int64, username, passwordHash string) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	a, ok := as.s.d.admins[telegramID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	for _, other := range as.s.d.admins {
+		if username != "" && other.Username == username && other.TelegramID != telegramID {
+			return store.ErrConflict
+		}
+	}
+	a.Username = username
+	a.PasswordHash = passwordHash
+	as.s.d.admins[telegramID] = a
+	return nil
+}
+
+func (as adminStore) Delete(telegramID int64) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	delete(as.s.d.admins, telegramID)
+	return nil
+}
@@ -0,0 +1,35 @@
+package memstore
+
+import (
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type attemptStore struct{ s *Store }
+
+func (as attemptStore) Get(key string) (models.LoginAttempt, error) {
+	as.s.lock()
+	defer as.s.unlock()
+
+	a, ok := as.s.d.attempts[key]
+	if !ok {
+		return models.LoginAttempt{}, store.ErrNotFound
+	}
+	return a, nil
+}
+
+func (as attemptStore) Save(a models.LoginAttempt) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	as.s.d.attempts[a.Key] = a
+	return nil
+}
+
+func (as attemptStore) Delete(key string) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	delete(as.s.d.attempts, key)
+	return nil
+}
@@ -0,0 +1,61 @@
+package memstore
+
+import (
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type auditStore struct{ s *Store }
+
+func (as auditStore) Append(e *models.AuditEntry) error {
+	as.s.lock()
+	defer as.s.unlock()
+
+	e.ID = as.s.d.nextID("audit_log")
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	as.s.d.audit = append(as.s.d.audit, *e)
+	return nil
+}
+
+func (as auditStore) List(f store.AuditFilter) ([]models.AuditEntry, error) {
+	as.s.lock()
+	defer as.s.unlock()
+
+	var entries []models.AuditEntry
+	for i := len(as.s.d.audit) - 1; i >= 0; i-- {
+		e := as.s.d.audit[i]
+		if !auditMatch(e, f) {
+			continue
+		}
+		entries = append(entries, e)
+		if f.Limit > 0 && len(entries) == f.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func auditMatch(e models.AuditEntry, f store.AuditFilter) bool {
+	switch {
+	case f.Actor != "" && !strings.Contains(strings.ToLower(e.Actor), strings.ToLower(f.Actor)):
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.RaffleID != 0 && (e.RaffleID == nil || *e.RaffleID != f.RaffleID):
+		return false
+	case f.TicketID != 0 && (e.TicketID == nil || *e.TicketID != f.TicketID):
+		return false
+	case f.PaymentID != 0 && (e.PaymentID == nil || *e.PaymentID != f.PaymentID):
+		return false
+	case !f.From.IsZero() && e.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.CreatedAt.Before(f.To):
+		return false
+	}
+	return true
+}
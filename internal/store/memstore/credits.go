@@ -0,0 +1,55 @@
+package memstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type creditStore struct{ s *Store }
+
+func (cs creditStore) Add(c *models.Credit) error {
+	cs.s.lock()
+	defer cs.s.unlock()
+
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	if c.Currency == "" {
+		c.Currency = models.DefaultCurrency
+	}
+	c.ID = cs.s.d.nextID("credits")
+	cs.s.d.credits[c.ID] = *c
+	return nil
+}
+
+func (cs creditStore) Balance(userID int64, currency string) (models.Money, error) {
+	cs.s.lock()
+	defer cs.s.unlock()
+
+	var total models.Money
+	for _, c := range cs.s.d.credits {
+		if c.UserID == userID && c.Currency == currency {
+			total += c.Amount
+		}
+	}
+	return total, nil
+}
+
+func (cs creditStore) Balances(userID int64) (map[string]models.Money, error) {
+	cs.s.lock()
+	defer cs.s.unlock()
+
+	balances := map[string]models.Money{}
+	for _, c := range cs.s.d.credits {
+		if c.UserID == userID {
+			balances[c.Currency] += c.Amount
+		}
+	}
+	for currency, total := range balances {
+		if total == 0 {
+			delete(balances, currency)
+		}
+	}
+	return balances, nil
+}
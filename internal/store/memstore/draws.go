@@ -0,0 +1,68 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type drawStore struct{ s *Store }
+
+// fillDraw completa los campos virtuales igual que el JOIN de sqlstore
+func (d *data) fillDraw(dr models.Draw) models.Draw {
+	dr.RaffleName = d.raffles[dr.RaffleID].Name
+	if dr.UserID != nil {
+		u := d.users[*dr.UserID]
+		dr.WinnerName = u.Name
+		dr.WinnerPhone = u.Phone
+	}
+	return dr
+}
+
+func (ds drawStore) Get(raffleID int64) (models.Draw, error) {
+	ds.s.lock()
+	defer ds.s.unlock()
+
+	dr, ok := ds.s.d.draws[raffleID]
+	if !ok {
+		return models.Draw{}, store.ErrNotFound
+	}
+	return ds.s.d.fillDraw(dr), nil
+}
+
+func (ds drawStore) Recent(limit int) ([]models.Draw, error) {
+	ds.s.lock()
+	defer ds.s.unlock()
+
+	var draws []models.Draw
+	for _, dr := range ds.s.d.draws {
+		draws = append(draws, ds.s.d.fillDraw(dr))
+	}
+	sort.Slice(draws, func(i, j int) bool {
+		if !draws[i].DrawnAt.Equal(draws[j].DrawnAt) {
+			return draws[i].DrawnAt.After(draws[j].DrawnAt)
+		}
+		return draws[i].ID > draws[j].ID
+	})
+	if len(draws) > limit {
+		draws = draws[:limit]
+	}
+	return draws, nil
+}
+
+func (ds drawStore) Create(dr *models.Draw) error {
+	ds.s.lock()
+	defer ds.s.unlock()
+
+	if _, exists := ds.s.d.draws[dr.RaffleID]; exists {
+		return store.ErrConflict
+	}
+	if dr.DrawnAt.IsZero() {
+		dr.DrawnAt = time.Now()
+	}
+	dr.ID = ds.s.d.nextID("raffle_draws")
+	ds.s.d.draws[dr.RaffleID] = *dr
+	return nil
+}
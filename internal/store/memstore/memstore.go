@@ -0,0 +1,167 @@
+// Package memstore implementa store.Store en memoria, para tests y desarrollo
+// sin base de datos. Las transacciones se simulan con una copia del estado.
+package memstore
+
+import (
+	"sync"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type data struct {
+	raffles       map[int64]models.Raffle
+	tickets       map[int64]models.Ticket
+	users         map[int64]models.User
+	payments      map[int64]models.Payment
+	credits       map[int64]models.Credit
+	rates         []models.ExchangeRate // En orden de inserción
+	methods       map[string]models.PaymentMethod
+	raffleMethods map[int64][]string         // Códigos habilitados por raffle_id
+	draws         map[int64]models.Draw      // por raffle_id
+	recipients    map[int64]models.Recipient // por chat_id
+	outbox        map[int64]models.OutboxMessage
+	admins        map[int64]models.Admin // por telegram_id
+	sessions      map[string]models.AdminSession
+	attempts      map[string]models.LoginAttempt
+	audit         []models.AuditEntry // En orden de inserción
+	lastID        map[string]int64
+}
+
+func newData() *data {
+	return &data{
+		raffles:       map[int64]models.Raffle{},
+		tickets:       map[int64]models.Ticket{},
+		users:         map[int64]models.User{},
+		payments:      map[int64]models.Payment{},
+		credits:       map[int64]models.Credit{},
+		methods:       map[string]models.PaymentMethod{},
+		raffleMethods: map[int64][]string{},
+		draws:         map[int64]models.Draw{},
+		recipients:    map[int64]models.Recipient{},
+		outbox:        map[int64]models.OutboxMessage{},
+		admins:        map[int64]models.Admin{},
+		sessions:      map[string]models.AdminSession{},
+		attempts:      map[string]models.LoginAttempt{},
+		lastID:        map[string]int64{},
+	}
+}
+
+// clone copia el estado para poder deshacer una transacción.
+// Los structs se copian por valor; nunca se modifican a través de sus punteros.
+func (d *data) clone() *data {
+	c := newData()
+	for k, v := range d.raffles {
+		c.raffles[k] = v
+	}
+	for k, v := range d.tickets {
+		c.tickets[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.payments {
+		c.payments[k] = v
+	}
+	for k, v := range d.credits {
+		c.credits[k] = v
+	}
+	for k, v := range d.methods {
+		c.methods[k] = v
+	}
+	for k, v := range d.raffleMethods {
+		c.raffleMethods[k] = v
+	}
+	for k, v := range d.draws {
+		c.draws[k] = v
+	}
+	for k, v := range d.recipients {
+		c.recipients[k] = v
+	}
+	for k, v := range d.outbox {
+		c.outbox[k] = v
+	}
+	for k, v := range d.admins {
+		c.admins[k] = v
+	}
+	for k, v := range d.sessions {
+		c.sessions[k] = v
+	}
+	for k, v := range d.attempts {
+		c.attempts[k] = v
+	}
+	c.rates = append(c.rates, d.rates...)
+	c.audit = append(c.audit, d.audit...)
+	for k, v := range d.lastID {
+		c.lastID[k] = v
+	}
+	return c
+}
+
+func (d *data) nextID(table string) int64 {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+var _ store.Store = (*Store)(nil)
+
+type Store struct {
+	mu   *sync.Mutex
+	d    *data
+	inTx bool // el lock ya lo tiene Tx
+}
+
+func New() *Store {
+	d := newData()
+	for _, m := range defaultMethods {
+		d.methods[m.Code] = m
+	}
+	return &Store{mu: &sync.Mutex{}, d: d}
+}
+
+func (s *Store) lock() {
+	if !s.inTx {
+		s.mu.Lock()
+	}
+}
+
+func (s *Store) unlock() {
+	if !s.inTx {
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) Raffles() store.RaffleStore             { return raffleStore{s} }
+func (s *Store) Tickets() store.TicketStore             { return ticketStore{s} }
+func (s *Store) Users() store.UserStore                 { return userStore{s} }
+func (s *Store) Payments() store.PaymentStore           { return paymentStore{s} }
+func (s *Store) Credits() store.CreditStore             { return creditStore{s} }
+func (s *Store) Rates() store.RateStore                 { return rateStore{s} }
+func (s *Store) Methods() store.PaymentMethodStore      { return methodStore{s} }
+func (s *Store) Draws() store.DrawStore                 { return drawStore{s} }
+func (s *Store) Recipients() store.RecipientStore       { return recipientStore{s} }
+func (s *Store) Outbox() store.OutboxStore              { return outboxStore{s} }
+func (s *Store) Admins() store.AdminStore               { return adminStore{s} }
+func (s *Store) Sessions() store.SessionStore           { return sessionStore{s} }
+func (s *Store) LoginAttempts() store.LoginAttemptStore { return attemptStore{s} }
+func (s *Store) Audit() store.AuditStore                { return auditStore{s} }
+
+func (s *Store) Tx(fn func(store.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.d.clone()
+	if err := fn(&Store{mu: s.mu, d: s.d, inTx: true}); err != nil {
+		*s.d = *snapshot
+		return err
+	}
+	return nil
+}
+
+func ptr(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,102 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+// defaultMethods es el catálogo inicial, igual que el de la migración 0016
+var defaultMethods = []models.PaymentMethod{
+	{Code: "transfer", Name: "Transferencia bancaria", Public: true, Position: 1},
+	{Code: "pago_movil", Name: "Pago móvil", Position: 2},
+	{Code: "zelle", Name: "Zelle", Position: 3},
+	{Code: "crypto", Name: "Cripto (USDT)", Position: 4},
+	{Code: "cash", Name: "Efectivo", Position: 5},
+}
+
+type methodStore struct{ s *Store }
+
+func (ms methodStore) List() ([]models.PaymentMethod, error) {
+	ms.s.lock()
+	defer ms.s.unlock()
+
+	var methods []models.PaymentMethod
+	for _, m := range ms.s.d.methods {
+		methods = append(methods, m)
+	}
+	sortMethods(methods)
+	return methods, nil
+}
+
+func (ms methodStore) Get(code string) (models.PaymentMethod, error) {
+	ms.s.lock()
+	defer ms.s.unlock()
+
+	m, ok := ms.s.d.methods[code]
+	if !ok {
+		return models.PaymentMethod{}, store.ErrNotFound
+	}
+	return m, nil
+}
+
+func (ms methodStore) Save(m models.PaymentMethod) error {
+	ms.s.lock()
+	defer ms.s.unlock()
+
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = time.Now()
+	}
+	ms.s.d.methods[m.Code] = m
+	return nil
+}
+
+func (ms methodStore) ForRaffle(raffleID int64) ([]string, error) {
+	ms.s.lock()
+	defer ms.s.unlock()
+
+	// Igual que el JOIN con payment_methods: en el orden del catálogo
+	var methods []models.PaymentMethod
+	for _, code := range ms.s.d.raffleMethods[raffleID] {
+		if m, ok := ms.s.d.methods[code]; ok {
+			methods = append(methods, m)
+		}
+	}
+	sortMethods(methods)
+	var codes []string
+	for _, m := range methods {
+		codes = append(codes, m.Code)
+	}
+	return codes, nil
+}
+
+func (ms methodStore) SetForRaffle(raffleID int64, codes []string) error {
+	ms.s.lock()
+	defer ms.s.unlock()
+
+	// Una copia nueva: clone comparte los slices con el estado anterior
+	var enabled []string
+	for _, code := range codes {
+		if !contains(enabled, code) {
+			enabled = append(enabled, code)
+		}
+	}
+	if len(enabled) == 0 {
+		delete(ms.s.d.raffleMethods, raffleID)
+		return nil
+	}
+	ms.s.d.raffleMethods[raffleID] = enabled
+	return nil
+}
+
+// sortMethods ordena como ORDER BY position, code
+func sortMethods(methods []models.PaymentMethod) {
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Position != methods[j].Position {
+			return methods[i].Position < methods[j].Position
+		}
+		return methods[i].Code < methods[j].Code
+	})
+}
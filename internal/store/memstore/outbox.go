@@ -0,0 +1,118 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type outboxStore struct{ s *Store }
+
+func (o outboxStore) Enqueue(m *models.OutboxMessage) error {
+	o.s.lock()
+	defer o.s.unlock()
+
+	now := time.Now()
+	m.ID = o.s.d.nextID("outbox")
+	m.Status = "pending"
+	m.CreatedAt = now
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = now
+	}
+	o.s.d.outbox[m.ID] = *m
+	return nil
+}
+
+func (o outboxStore) list(match func(models.OutboxMessage) bool, newestFirst bool, limit int) []models.OutboxMessage {
+	var msgs []models.OutboxMessage
+	for _, m := range o.s.d.outbox {
+		if match(m) {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if newestFirst {
+			return msgs[i].ID > msgs[j].ID
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs
+}
+
+func (o outboxStore) ListDue(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	o.s.lock()
+	defer o.s.unlock()
+
+	return o.list(func(m models.OutboxMessage) bool {
+		return m.Status == "pending" && !m.NextAttemptAt.After(now)
+	}, false, limit), nil
+}
+
+// update aplica fn al mensaje si cumple cond; si no, ErrConflict
+func (o outboxStore) update(id int64, cond func(models.OutboxMessage) bool, fn func(*models.OutboxMessage)) error {
+	o.s.lock()
+	defer o.s.unlock()
+
+	m, ok := o.s.d.outbox[id]
+	if !ok || (cond != nil && !cond(m)) {
+		return store.ErrConflict
+	}
+	fn(&m)
+	o.s.d.outbox[id] = m
+	return nil
+}
+
+func (o outboxStore) Claim(id int64, now, until time.Time) error {
+	return o.update(id, func(m models.OutboxMessage) bool {
+		return m.Status == "pending" && !m.NextAttemptAt.After(now)
+	}, func(m *models.OutboxMessage) {
+		m.NextAttemptAt = until
+	})
+}
+
+func (o outboxStore) MarkSent(id int64, at time.Time) error {
+	return o.update(id, nil, func(m *models.OutboxMessage) {
+		m.Status = "sent"
+		m.Attempts++
+		m.SentAt = &at
+		m.LastError = ""
+	})
+}
+
+func (o outboxStore) Reschedule(id int64, attempts int, lastErr string, next time.Time) error {
+	return o.update(id, nil, func(m *models.OutboxMessage) {
+		m.Attempts = attempts
+		m.LastError = lastErr
+		m.NextAttemptAt = next
+	})
+}
+
+func (o outboxStore) Fail(id int64, attempts int, lastErr string) error {
+	return o.update(id, nil, func(m *models.OutboxMessage) {
+		m.Status = "failed"
+		m.Attempts = attempts
+		m.LastError = lastErr
+	})
+}
+
+func (o outboxStore) ListFailed(limit int) ([]models.OutboxMessage, error) {
+	o.s.lock()
+	defer o.s.unlock()
+
+	return o.list(func(m models.OutboxMessage) bool { return m.Status == "failed" }, true, limit), nil
+}
+
+func (o outboxStore) Retry(id int64, now time.Time) error {
+	return o.update(id, func(m models.OutboxMessage) bool {
+		return m.Status == "failed"
+	}, func(m *models.OutboxMessage) {
+		m.Status = "pending"
+		m.Attempts = 0
+		m.NextAttemptAt = now
+	})
+}
@@ -0,0 +1,184 @@
+package memstore
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type paymentStore struct{ s *Store }
+
+// current indica si la entrada es del cliente actual de su ticket
+func (d *data) current(p models.Payment) bool {
+	t := d.tickets[p.TicketID]
+	return p.UserID != nil && t.UserID != nil && *p.UserID == *t.UserID
+}
+
+// totalPaid suma las entradas del cliente actual del ticket con alguno de los estados dados
+func (d *data) totalPaid(ticketID int64, statuses ...string) models.Money {
+	var total models.Money
+	for _, p := range d.payments {
+		if p.TicketID == ticketID && d.current(p) && slices.Contains(statuses, p.Status) {
+			total += p.Amount
+		}
+	}
+	return total
+}
+
+func (d *data) fillPayment(p models.Payment) models.Payment {
+	t := d.tickets[p.TicketID]
+	p.TicketNumber = t.Number
+	p.RaffleID = t.RaffleID
+	p.RaffleName = d.raffles[t.RaffleID].Name
+	userID := p.UserID
+	if userID == nil {
+		userID = t.UserID
+	}
+	if userID != nil {
+		u := d.users[*userID]
+		p.UserName = u.Name
+		p.UserPhone = u.Phone
+	}
+	return p
+}
+
+func (ps paymentStore) Get(id int64) (models.Payment, error) {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	p, ok := ps.s.d.payments[id]
+	if !ok {
+		return models.Payment{}, store.ErrNotFound
+	}
+	return ps.s.d.fillPayment(p), nil
+}
+
+func (ps paymentStore) Create(p *models.Payment) error {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	if p.Status == "" {
+		p.Status = "pending"
+		if p.IsVerified {
+			p.Status = "verified"
+		}
+	}
+	p.IsVerified = p.Status == "verified"
+	if p.Kind == "" {
+		p.Kind = models.PaymentKindPayment
+	}
+	t, ok := ps.s.d.tickets[p.TicketID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if p.UserID == nil {
+		p.UserID = t.UserID
+	}
+	if p.Currency == "" {
+		p.Currency, p.OriginalAmount, p.Rate = ps.s.d.raffles[t.RaffleID].Currency, p.Amount, 1
+	}
+	p.ID = ps.s.d.nextID("payments")
+	ps.s.d.payments[p.ID] = *p
+	return nil
+}
+
+func (ps paymentStore) ListByTicket(ticketID int64) ([]models.Payment, error) {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	var payments []models.Payment
+	for _, p := range ps.s.d.payments {
+		if p.TicketID == ticketID && ps.s.d.current(p) {
+			payments = append(payments, ps.s.d.fillPayment(p))
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].CreatedAt.After(payments[j].CreatedAt)
+		}
+		return payments[i].ID > payments[j].ID
+	})
+	return payments, nil
+}
+
+func (ps paymentStore) TotalsByCurrency(raffleID int64) ([]models.CurrencyTotal, error) {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	byCurrency := map[string]*models.CurrencyTotal{}
+	var totals []models.CurrencyTotal
+	for _, p := range ps.s.d.payments {
+		if ps.s.d.tickets[p.TicketID].RaffleID != raffleID || !ps.s.d.current(p) || p.Status != "verified" {
+			continue
+		}
+		t, ok := byCurrency[p.Currency]
+		if !ok {
+			t = &models.CurrencyTotal{Currency: p.Currency}
+			byCurrency[p.Currency] = t
+		}
+		t.Original += p.OriginalAmount
+		t.Amount += p.Amount
+	}
+	for _, t := range byCurrency {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	return totals, nil
+}
+
+func (ps paymentStore) ListPending() ([]models.Payment, error) {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	var payments []models.Payment
+	for _, p := range ps.s.d.payments {
+		if p.Status == "pending" {
+			payments = append(payments, ps.s.d.fillPayment(p))
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+		}
+		return payments[i].ID < payments[j].ID
+	})
+	return payments, nil
+}
+
+func (ps paymentStore) TotalByTicket(ticketID int64) (models.Money, error) {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	return ps.s.d.totalPaid(ticketID, "verified"), nil
+}
+
+func (ps paymentStore) review(id int64, status, by, reason string, at time.Time) error {
+	ps.s.lock()
+	defer ps.s.unlock()
+
+	p, ok := ps.s.d.payments[id]
+	if !ok || p.Status != "pending" {
+		return store.ErrConflict
+	}
+	p.Status = status
+	p.IsVerified = status == "verified"
+	p.ReviewedBy = by
+	p.ReviewedAt = &at
+	p.RejectReason = reason
+	ps.s.d.payments[id] = p
+	return nil
+}
+
+func (ps paymentStore) Verify(id int64, by string, at time.Time) error {
+	return ps.review(id, "verified", by, "", at)
+}
+
+func (ps paymentStore) Reject(id int64, by, reason string, at time.Time) error {
+	return ps.review(id, "rejected", by, reason, at)
+}
@@ -0,0 +1,96 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type raffleStore struct{ s *Store }
+
+func (rs raffleStore) Get(id int64) (models.Raffle, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	r, ok := rs.s.d.raffles[id]
+	if !ok {
+		return models.Raffle{}, store.ErrNotFound
+	}
+	return r, nil
+}
+
+func (rs raffleStore) List(statuses ...string) ([]models.Raffle, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	var raffles []models.Raffle
+	for _, r := range rs.s.d.raffles {
+		if len(statuses) == 0 || contains(statuses, r.Status) {
+			raffles = append(raffles, r)
+		}
+	}
+	sort.Slice(raffles, func(i, j int) bool {
+		if !raffles[i].CreatedAt.Equal(raffles[j].CreatedAt) {
+			return raffles[i].CreatedAt.After(raffles[j].CreatedAt)
+		}
+		return raffles[i].ID > raffles[j].ID
+	})
+	return raffles, nil
+}
+
+func (rs raffleStore) Create(r *models.Raffle, numbers []string) error {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	d := rs.s.d
+	// Igual que el índice único (raffle_id, number) de la base
+	seen := map[string]bool{}
+	for _, num := range numbers {
+		if seen[num] {
+			return store.ErrConflict
+		}
+		seen[num] = true
+	}
+
+	if r.Status == "" {
+		r.Status = "active"
+	}
+	if r.Currency == "" {
+		r.Currency = models.DefaultCurrency
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	r.ID = d.nextID("raffles")
+	d.raffles[r.ID] = *r
+
+	for _, num := range numbers {
+		id := d.nextID("tickets")
+		d.tickets[id] = models.Ticket{ID: id, RaffleID: r.ID, Number: num, Status: "available"}
+	}
+	return nil
+}
+
+func (rs raffleStore) UpdateStatus(id int64, from, to string) error {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	r, ok := rs.s.d.raffles[id]
+	if !ok || r.Status != from {
+		return store.ErrConflict
+	}
+	r.Status = to
+	rs.s.d.raffles[id] = r
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type rateStore struct{ s *Store }
+
+func (rs rateStore) Add(r *models.ExchangeRate) error {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	r.ID = rs.s.d.nextID("exchange_rates")
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	rs.s.d.rates = append(rs.s.d.rates, *r)
+	return nil
+}
+
+func (rs rateStore) Find(currency, base string, at time.Time) (models.ExchangeRate, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	var found *models.ExchangeRate
+	for i, r := range rs.s.d.rates {
+		if r.Currency != currency || r.Base != base || r.ValidFrom.After(at) {
+			continue
+		}
+		// Igual que ORDER BY valid_from DESC, id DESC
+		if found == nil || !r.ValidFrom.Before(found.ValidFrom) {
+			found = &rs.s.d.rates[i]
+		}
+	}
+	if found == nil {
+		return models.ExchangeRate{}, store.ErrNotFound
+	}
+	return *found, nil
+}
+
+func (rs rateStore) List(limit int) ([]models.ExchangeRate, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	rates := append([]models.ExchangeRate(nil), rs.s.d.rates...)
+	sort.SliceStable(rates, func(i, j int) bool {
+		if !rates[i].ValidFrom.Equal(rates[j].ValidFrom) {
+			return rates[i].ValidFrom.After(rates[j].ValidFrom)
+		}
+		return rates[i].ID > rates[j].ID
+	})
+	if len(rates) > limit {
+		rates = rates[:limit]
+	}
+	return rates, nil
+}
@@ -0,0 +1,60 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type recipientStore struct{ s *Store }
+
+func (rs recipientStore) List() ([]models.Recipient, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	var recipients []models.Recipient
+	for _, r := range rs.s.d.recipients {
+		recipients = append(recipients, r)
+	}
+	sort.Slice(recipients, func(i, j int) bool {
+		if !recipients[i].CreatedAt.Equal(recipients[j].CreatedAt) {
+			return recipients[i].CreatedAt.Before(recipients[j].CreatedAt)
+		}
+		return recipients[i].ChatID < recipients[j].ChatID
+	})
+	return recipients, nil
+}
+
+func (rs recipientStore) Get(chatID int64) (models.Recipient, error) {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	r, ok := rs.s.d.recipients[chatID]
+	if !ok {
+		return models.Recipient{}, store.ErrNotFound
+	}
+	return r, nil
+}
+
+func (rs recipientStore) Save(r models.Recipient) error {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	if old, ok := rs.s.d.recipients[r.ChatID]; ok {
+		r.CreatedAt = old.CreatedAt
+	} else if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	rs.s.d.recipients[r.ChatID] = r
+	return nil
+}
+
+func (rs recipientStore) Delete(chatID int64) error {
+	rs.s.lock()
+	defer rs.s.unlock()
+
+	delete(rs.s.d.recipients, chatID)
+	return nil
+}
@@ -0,0 +1,64 @@
+package memstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type sessionStore struct{ s *Store }
+
+func (ss sessionStore) Create(s models.AdminSession) error {
+	ss.s.lock()
+	defer ss.s.unlock()
+
+	if _, ok := ss.s.d.sessions[s.ID]; ok {
+		return store.ErrConflict
+	}
+	ss.s.d.sessions[s.ID] = s
+	return nil
+}
+
+func (ss sessionStore) Get(id string) (models.AdminSession, error) {
+	ss.s.lock()
+	defer ss.s.unlock()
+
+	s, ok := ss.s.d.sessions[id]
+	if !ok {
+		return models.AdminSession{}, store.ErrNotFound
+	}
+	return s, nil
+}
+
+func (ss sessionStore) Delete(id string) error {
+	ss.s.lock()
+	defer ss.s.unlock()
+
+	delete(ss.s.d.sessions, id)
+	return nil
+}
+
+func (ss sessionStore) DeleteByAdmin(telegramID int64) error {
+	ss.s.lock()
+	defer ss.s.unlock()
+
+	for id, s := range ss.s.d.sessions {
+		if s.TelegramID == telegramID {
+			delete(ss.s.d.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (ss sessionStore) DeleteExpired(now time.Time) error {
+	ss.s.lock()
+	defer ss.s.unlock()
+
+	for id, s := range ss.s.d.sessions {
+		if !s.ExpiresAt.After(now) {
+			delete(ss.s.d.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package memstore
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type ticketStore struct{ s *Store }
+
+// fill completa los campos virtuales igual que el JOIN de sqlstore
+func (d *data) fill(t models.Ticket) models.Ticket {
+	if t.UserID != nil {
+		u := d.users[*t.UserID]
+		t.UserName = u.Name
+		t.UserPhone = u.Phone
+		t.UserTelegramID = u.TelegramID
+	}
+	t.TotalPaid = d.totalPaid(t.ID, "pending", "verified")
+	t.TotalVerified = d.totalPaid(t.ID, "verified")
+	return t
+}
+
+func (d *data) sorted(match func(models.Ticket) bool) []models.Ticket {
+	var tickets []models.Ticket
+	for _, t := range d.tickets {
+		if match(t) {
+			tickets = append(tickets, d.fill(t))
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		if tickets[i].RaffleID != tickets[j].RaffleID {
+			return tickets[i].RaffleID < tickets[j].RaffleID
+		}
+		return tickets[i].Number < tickets[j].Number
+	})
+	return tickets
+}
+
+func (ts ticketStore) Get(id int64) (models.Ticket, error) {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok {
+		return models.Ticket{}, store.ErrNotFound
+	}
+	return ts.s.d.fill(t), nil
+}
+
+func (ts ticketStore) GetByNumber(raffleID int64, number string) (models.Ticket, error) {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	for _, t := range ts.s.d.tickets {
+		if t.RaffleID == raffleID && t.Number == number {
+			return ts.s.d.fill(t), nil
+		}
+	}
+	return models.Ticket{}, store.ErrNotFound
+}
+
+func (ts ticketStore) List(raffleID int64, query string) ([]models.Ticket, error) {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	return ts.s.d.sorted(func(t models.Ticket) bool {
+		return t.RaffleID == raffleID && strings.Contains(t.Number, query)
+	}), nil
+}
+
+func (ts ticketStore) Reserve(id, userID int64, at time.Time) error {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok || t.Status != "available" {
+		return store.ErrConflict
+	}
+	t.UserID = ptr(userID)
+	t.Status = "reserved"
+	t.ReservedAt = &at
+	t.RemindedAt = nil
+	ts.s.d.tickets[id] = t
+	return nil
+}
+
+func (ts ticketStore) MarkPaid(id int64) error {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok || t.Status != "reserved" {
+		return store.ErrConflict
+	}
+	t.Status = "paid"
+	ts.s.d.tickets[id] = t
+	return nil
+}
+
+func (ts ticketStore) MarkUnpaid(id int64) error {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok || t.Status != "paid" {
+		return store.ErrConflict
+	}
+	t.Status = "reserved"
+	ts.s.d.tickets[id] = t
+	return nil
+}
+
+func (ts ticketStore) Release(id int64, from string) error {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok || t.Status != from {
+		return store.ErrConflict
+	}
+	t.UserID = nil
+	t.Status = "available"
+	t.ReservedAt = nil
+	t.RemindedAt = nil
+	ts.s.d.tickets[id] = t
+	return nil
+}
+
+func (ts ticketStore) ListExpired(now time.Time) ([]models.Ticket, error) {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	d := ts.s.d
+	return d.sorted(func(t models.Ticket) bool {
+		r := d.raffles[t.RaffleID]
+		if t.Status != "reserved" || t.ReservedAt == nil || r.Status != "active" || r.ReserveHours <= 0 {
+			return false
+		}
+		deadline := t.ReservedAt.Add(time.Duration(r.ReserveHours) * time.Hour)
+		return !deadline.After(now)
+	}), nil
+}
+
+func (ts ticketStore) ListExpiring(now time.Time, within time.Duration) ([]models.Ticket, error) {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	d := ts.s.d
+	return d.sorted(func(t models.Ticket) bool {
+		r := d.raffles[t.RaffleID]
+		if t.Status != "reserved" || t.ReservedAt == nil || t.RemindedAt != nil || r.Status != "active" || r.ReserveHours <= 0 {
+			return false
+		}
+		deadline := t.ReservedAt.Add(time.Duration(r.ReserveHours) * time.Hour)
+		return deadline.After(now) && !deadline.After(now.Add(within))
+	}), nil
+}
+
+func (ts ticketStore) MarkReminded(id int64, at time.Time) error {
+	ts.s.lock()
+	defer ts.s.unlock()
+
+	t, ok := ts.s.d.tickets[id]
+	if !ok {
+		return store.ErrConflict
+	}
+	t.RemindedAt = &at
+	ts.s.d.tickets[id] = t
+	return nil
+}
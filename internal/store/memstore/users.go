@@ -0,0 +1,187 @@
+package memstore
+
+import (
+	"sort"
+	"strings"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type userStore struct{ s *Store }
+
+func (us userStore) Get(id int64) (models.User, error) {
+	us.s.lock()
+	defer us.s.unlock()
+
+	u, ok := us.s.d.users[id]
+	if !ok {
+		return models.User{}, store.ErrNotFound
+	}
+	return u, nil
+}
+
+// findUser devuelve el cliente más antiguo que cumpla match
+func (d *data) findUser(match func(models.User) bool) (models.User, error) {
+	var found *models.User
+	for _, u := range d.users {
+		if match(u) && (found == nil || u.ID < found.ID) {
+			u := u
+			found = &u
+		}
+	}
+	if found == nil {
+		return models.User{}, store.ErrNotFound
+	}
+	return *found, nil
+}
+
+func (us userStore) FindByPhone(phone string) (models.User, error) {
+	us.s.lock()
+	defer us.s.unlock()
+
+	norm := models.NormalizePhone(phone)
+	return us.s.d.findUser(func(u models.User) bool {
+		return norm != "" && models.NormalizePhone(u.Phone) == norm
+	})
+}
+
+func (us userStore) FindByTelegramID(telegramID int64) (models.User, error) {
+	us.s.lock()
+	defer us.s.unlock()
+
+	return us.s.d.findUser(func(u models.User) bool {
+		return u.TelegramID != nil && *u.TelegramID == telegramID
+	})
+}
+
+func (us userStore) Create(u *models.User) error {
+	us.s.lock()
+	defer us.s.unlock()
+
+	if u.TelegramID != nil {
+		if _, err := us.s.d.findUser(func(o models.User) bool {
+			return o.TelegramID != nil && *o.TelegramID == *u.TelegramID
+		}); err == nil {
+			return store.ErrConflict
+		}
+	}
+	u.ID = us.s.d.nextID("users")
+	us.s.d.users[u.ID] = *u
+	return nil
+}
+
+func (us userStore) Update(u models.User) error {
+	us.s.lock()
+	defer us.s.unlock()
+
+	if _, ok := us.s.d.users[u.ID]; !ok {
+		return store.ErrConflict
+	}
+	us.s.d.users[u.ID] = u
+	return nil
+}
+
+func (us userStore) Search(q string, limit int) ([]models.User, error) {
+	us.s.lock()
+	defer us.s.unlock()
+
+	norm := models.NormalizePhone(q)
+	var users []models.User
+	for _, u := range us.s.d.users {
+		if strings.Contains(u.Name, q) || strings.Contains(u.Phone, q) ||
+			(norm != "" && strings.Contains(models.NormalizePhone(u.Phone), norm)) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (us userStore) Duplicates() ([][]models.User, error) {
+	us.s.lock()
+	defer us.s.unlock()
+
+	byPhone := map[string][]models.User{}
+	for _, u := range us.s.d.users {
+		if norm := models.NormalizePhone(u.Phone); norm != "" {
+			byPhone[norm] = append(byPhone[norm], u)
+		}
+	}
+
+	var phones []string
+	for phone, users := range byPhone {
+		if len(users) > 1 {
+			phones = append(phones, phone)
+		}
+	}
+	sort.Strings(phones)
+
+	var groups [][]models.User
+	for _, phone := range phones {
+		users := byPhone[phone]
+		sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+		groups = append(groups, users)
+	}
+	return groups, nil
+}
+
+func (us userStore) Merge(keepID int64, mergeIDs []int64) error {
+	us.s.lock()
+	defer us.s.unlock()
+
+	d := us.s.d
+	keep, ok := d.users[keepID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	for _, id := range mergeIDs {
+		if id == keepID {
+			continue
+		}
+		u, ok := d.users[id]
+		if !ok {
+			return store.ErrNotFound
+		}
+		// Conservar los datos que le falten al cliente principal
+		if keep.TelegramID == nil {
+			keep.TelegramID = u.TelegramID
+		}
+		if keep.Phone == "" {
+			keep.Phone = u.Phone
+		}
+
+		for tid, t := range d.tickets {
+			if t.UserID != nil && *t.UserID == id {
+				t.UserID = ptr(keepID)
+				d.tickets[tid] = t
+			}
+		}
+		for pid, p := range d.payments {
+			if p.UserID != nil && *p.UserID == id {
+				p.UserID = ptr(keepID)
+				d.payments[pid] = p
+			}
+		}
+		for cid, c := range d.credits {
+			if c.UserID == id {
+				c.UserID = keepID
+				d.credits[cid] = c
+			}
+		}
+		for rid, dr := range d.draws {
+			if dr.UserID != nil && *dr.UserID == id {
+				dr.UserID = ptr(keepID)
+				d.draws[rid] = dr
+			}
+		}
+		delete(d.users, id)
+	}
+
+	d.users[keepID] = keep
+	return nil
+}
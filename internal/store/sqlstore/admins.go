@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type adminStore struct{ q querier }
+
+const adminSelect = `
+	SELECT telegram_id, COALESCE(name, ''), role, COALESCE(invited_by, ''), created_at,
+	       COALESCE(username, ''), COALESCE(password_hash, '')
+	FROM admins`
+
+func scanAdmin(row scanner) (models.Admin, error) {
+	var a models.Admin
+	err := row.Scan(&a.TelegramID, &a.Name, &a.Role, &a.InvitedBy, &a.CreatedAt, &a.Username, &a.PasswordHash)
+	return a, err
+}
+
+func (s adminStore) List() ([]models.Admin, error) {
+	rows, err := s.q.Query(adminSelect + " ORDER BY created_at, telegram_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []models.Admin
+	for rows.Next() {
+		a, err := scanAdmin(rows)
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, a)
+	}
+	return admins, rows.Err()
+}
+
+func (s adminStore) Get(telegramID int64) (models.Admin, error) {
+	a, err := scanAdmin(s.q.QueryRow(adminSelect+" WHERE telegram_id = ?", telegramID))
+	return a, notFound(err)
+}
+
+func (s adminStore) GetByUsername(username string) (models.Admin, error) {
+	a, err := scanAdmin(s.q.QueryRow(adminSelect+" WHERE username = ?", username))
+	return a, notFound(err)
+}
+
+func (s adminStore) Save(a models.Admin) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	_, err := s.q.Exec(`
+		INSERT INTO admins (telegram_id, name, role, invited_by, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(telegram_id) DO UPDATE SET
+			name = excluded.name,
+			role = excluded.role`,
+		a.TelegramID, nullString(a.Name), a.Role, nullString(a.InvitedBy), formatTime(a.CreatedAt))
+	return err
+}
+
+func (s adminStore) SetPassword(telegramID int64, username, passwordHash string) error {
+	if username != "" {
+		var other int64
+		err := s.q.QueryRow("SELECT telegram_id FROM admins WHERE username = ? AND telegram_id != ?", username, telegramID).Scan(&other)
+		if err == nil {
+			return store.ErrConflict
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+	res, err := s.q.Exec("UPDATE admins SET username = ?, password_hash = ? WHERE telegram_id = ?",
+		nullString(username), nullString(passwordHash), telegramID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s adminStore) Delete(telegramID int64) error {
+	_, err := s.q.Exec("DELETE FROM admins WHERE telegram_id = ?", telegramID)
+	return err
+}
@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"lotto-tg-app/internal/models"
+)
+
+type attemptStore struct{ q querier }
+
+func (s attemptStore) Get(key string) (models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	var lockedUntil sql.NullTime
+	err := s.q.QueryRow("SELECT key, failures, first_failed_at, locked_until FROM login_attempts WHERE key = ?", key).
+		Scan(&a.Key, &a.Failures, &a.FirstFailedAt, &lockedUntil)
+	if lockedUntil.Valid {
+		a.LockedUntil = &lockedUntil.Time
+	}
+	return a, notFound(err)
+}
+
+func (s attemptStore) Save(a models.LoginAttempt) error {
+	var lockedUntil interface{}
+	if a.LockedUntil != nil {
+		lockedUntil = formatTime(*a.LockedUntil)
+	}
+	_, err := s.q.Exec(`
+		INSERT INTO login_attempts (key, failures, first_failed_at, locked_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			failures = excluded.failures,
+			first_failed_at = excluded.first_failed_at,
+			locked_until = excluded.locked_until`,
+		a.Key, a.Failures, formatTime(a.FirstFailedAt), lockedUntil)
+	return err
+}
+
+func (s attemptStore) Delete(key string) error {
+	_, err := s.q.Exec("DELETE FROM login_attempts WHERE key = ?", key)
+	return err
+}
@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+)
+
+type auditStore struct{ q querier }
+
+func (s auditStore) Append(e *models.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	res, err := s.q.Exec(`
+		INSERT INTO audit_log (actor, action, raffle_id, ticket_id, payment_id, target, before_json, after_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Actor, e.Action, nullInt(e.RaffleID), nullInt(e.TicketID), nullInt(e.PaymentID),
+		nullString(e.Target), nullString(e.Before), nullString(e.After), formatTime(e.CreatedAt))
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s auditStore) List(f store.AuditFilter) ([]models.AuditEntry, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.Actor != "" {
+		add("instr(lower(actor), lower(?)) > 0", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.RaffleID != 0 {
+		add("raffle_id = ?", f.RaffleID)
+	}
+	if f.TicketID != 0 {
+		add("ticket_id = ?", f.TicketID)
+	}
+	if f.PaymentID != 0 {
+		add("payment_id = ?", f.PaymentID)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", formatTime(f.From))
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", formatTime(f.To))
+	}
+
+	query := `
+		SELECT id, actor, action, raffle_id, ticket_id, payment_id,
+		       COALESCE(target, ''), COALESCE(before_json, ''), COALESCE(after_json, ''), created_at
+		FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var raffleID, ticketID, paymentID sql.NullInt64
+		err := rows.Scan(&e.ID, &e.Actor, &e.Action, &raffleID, &ticketID, &paymentID,
+			&e.Target, &e.Before, &e.After, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.RaffleID = intPtr(raffleID)
+		e.TicketID = intPtr(ticketID)
+		e.PaymentID = intPtr(paymentID)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type creditStore struct{ q querier }
+
+func (s creditStore) Add(c *models.Credit) error {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	if c.Currency == "" {
+		c.Currency = models.DefaultCurrency
+	}
+	res, err := s.q.Exec(`
+		INSERT INTO credits (user_id, amount, payment_id, currency, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.UserID, c.Amount, nullInt(c.PaymentID), c.Currency, nullString(c.Reason), nullString(c.CreatedBy), formatTime(c.CreatedAt))
+	if err != nil {
+		return err
+	}
+	c.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s creditStore) Balance(userID int64, currency string) (models.Money, error) {
+	var total models.Money
+	err := s.q.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM credits WHERE user_id = ? AND currency = ?", userID, currency).Scan(&total)
+	return total, err
+}
+
+func (s creditStore) Balances(userID int64) (map[string]models.Money, error) {
+	rows, err := s.q.Query("SELECT currency, SUM(amount) FROM credits WHERE user_id = ? GROUP BY currency", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := map[string]models.Money{}
+	for rows.Next() {
+		var currency string
+		var total models.Money
+		if err := rows.Scan(&currency, &total); err != nil {
+			return nil, err
+		}
+		if total != 0 {
+			balances[currency] = total
+		}
+	}
+	return balances, rows.Err()
+}
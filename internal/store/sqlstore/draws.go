@@ -0,0 +1,69 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type drawStore struct{ q querier }
+
+const drawSelect = `
+	SELECT d.id, d.raffle_id, d.winning_number, d.ticket_id, d.user_id,
+	       COALESCE(d.ticket_status, ''), d.method, COALESCE(d.seed, ''),
+	       COALESCE(d.source, ''), COALESCE(d.drawn_by, ''), d.drawn_at,
+	       r.name, COALESCE(u.name, ''), COALESCE(u.phone, '')
+	FROM raffle_draws d
+	JOIN raffles r ON d.raffle_id = r.id
+	LEFT JOIN users u ON d.user_id = u.id`
+
+func scanDraw(row scanner) (models.Draw, error) {
+	var d models.Draw
+	var ticketID, userID sql.NullInt64
+	err := row.Scan(&d.ID, &d.RaffleID, &d.WinningNumber, &ticketID, &userID,
+		&d.TicketStatus, &d.Method, &d.Seed, &d.Source, &d.DrawnBy, &d.DrawnAt,
+		&d.RaffleName, &d.WinnerName, &d.WinnerPhone)
+	d.TicketID = intPtr(ticketID)
+	d.UserID = intPtr(userID)
+	return d, err
+}
+
+func (s drawStore) Get(raffleID int64) (models.Draw, error) {
+	d, err := scanDraw(s.q.QueryRow(drawSelect+" WHERE d.raffle_id = ?", raffleID))
+	return d, notFound(err)
+}
+
+func (s drawStore) Recent(limit int) ([]models.Draw, error) {
+	rows, err := s.q.Query(drawSelect+" ORDER BY d.drawn_at DESC, d.id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var draws []models.Draw
+	for rows.Next() {
+		d, err := scanDraw(rows)
+		if err != nil {
+			return nil, err
+		}
+		draws = append(draws, d)
+	}
+	return draws, rows.Err()
+}
+
+func (s drawStore) Create(d *models.Draw) error {
+	if d.DrawnAt.IsZero() {
+		d.DrawnAt = time.Now()
+	}
+	res, err := s.q.Exec(`
+		INSERT INTO raffle_draws (raffle_id, winning_number, ticket_id, user_id, ticket_status, method, seed, source, drawn_by, drawn_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(raffle_id) DO NOTHING`,
+		d.RaffleID, d.WinningNumber, nullInt(d.TicketID), nullInt(d.UserID), d.TicketStatus, d.Method, d.Seed, d.Source, d.DrawnBy, formatTime(d.DrawnAt))
+	if err := expectOne(res, err); err != nil {
+		return err
+	}
+	d.ID, err = res.LastInsertId()
+	return err
+}
@@ -0,0 +1,98 @@
+package sqlstore
+
+import (
+	"strings"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type methodStore struct{ q querier }
+
+const methodColumns = "code, name, instructions, ref_pattern, ref_hint, public, position, COALESCE(updated_by, ''), updated_at"
+
+func scanMethod(row scanner) (models.PaymentMethod, error) {
+	var m models.PaymentMethod
+	err := row.Scan(&m.Code, &m.Name, &m.Instructions, &m.RefPattern, &m.RefHint, &m.Public, &m.Position, &m.UpdatedBy, &m.UpdatedAt)
+	return m, err
+}
+
+func (s methodStore) List() ([]models.PaymentMethod, error) {
+	rows, err := s.q.Query("SELECT " + methodColumns + " FROM payment_methods ORDER BY position, code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var methods []models.PaymentMethod
+	for rows.Next() {
+		m, err := scanMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, m)
+	}
+	return methods, rows.Err()
+}
+
+func (s methodStore) Get(code string) (models.PaymentMethod, error) {
+	m, err := scanMethod(s.q.QueryRow("SELECT "+methodColumns+" FROM payment_methods WHERE code = ?", code))
+	return m, notFound(err)
+}
+
+func (s methodStore) Save(m models.PaymentMethod) error {
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = time.Now()
+	}
+	_, err := s.q.Exec(`
+		INSERT INTO payment_methods (code, name, instructions, ref_pattern, ref_hint, public, position, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(code) DO UPDATE SET
+			name = excluded.name,
+			instructions = excluded.instructions,
+			ref_pattern = excluded.ref_pattern,
+			ref_hint = excluded.ref_hint,
+			public = excluded.public,
+			position = excluded.position,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at`,
+		m.Code, m.Name, m.Instructions, m.RefPattern, m.RefHint, m.Public, m.Position, nullString(m.UpdatedBy), formatTime(m.UpdatedAt))
+	return err
+}
+
+func (s methodStore) ForRaffle(raffleID int64) ([]string, error) {
+	rows, err := s.q.Query(`
+		SELECT rpm.method FROM raffle_payment_methods rpm
+		JOIN payment_methods pm ON pm.code = rpm.method
+		WHERE rpm.raffle_id = ?
+		ORDER BY pm.position, pm.code`, raffleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (s methodStore) SetForRaffle(raffleID int64, codes []string) error {
+	if _, err := s.q.Exec("DELETE FROM raffle_payment_methods WHERE raffle_id = ?", raffleID); err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, 2*len(codes))
+	for _, code := range codes {
+		args = append(args, raffleID, code)
+	}
+	_, err := s.q.Exec("INSERT OR IGNORE INTO raffle_payment_methods (raffle_id, method) VALUES (?, ?)"+strings.Repeat(", (?, ?)", len(codes)-1), args...)
+	return err
+}
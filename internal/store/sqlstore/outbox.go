@@ -0,0 +1,101 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type outboxStore struct{ q querier }
+
+const outboxSelect = `
+	SELECT id, chat_id, text, COALESCE(reply_markup, ''), COALESCE(receipt_key, ''), COALESCE(receipt_type, ''),
+	       status, attempts, COALESCE(last_error, ''), next_attempt_at, created_at, sent_at
+	FROM outbox`
+
+func scanOutbox(row scanner) (models.OutboxMessage, error) {
+	var m models.OutboxMessage
+	var sentAt sql.NullTime
+	err := row.Scan(&m.ID, &m.ChatID, &m.Text, &m.ReplyMarkup, &m.ReceiptKey, &m.ReceiptType,
+		&m.Status, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.CreatedAt, &sentAt)
+	if sentAt.Valid {
+		m.SentAt = &sentAt.Time
+	}
+	return m, err
+}
+
+func (s outboxStore) list(query string, args ...interface{}) ([]models.OutboxMessage, error) {
+	rows, err := s.q.Query(outboxSelect+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []models.OutboxMessage
+	for rows.Next() {
+		m, err := scanOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func (s outboxStore) Enqueue(m *models.OutboxMessage) error {
+	now := time.Now()
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = now
+	}
+	res, err := s.q.Exec(`
+		INSERT INTO outbox (chat_id, text, reply_markup, receipt_key, receipt_type, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.ChatID, m.Text, nullString(m.ReplyMarkup), nullString(m.ReceiptKey), nullString(m.ReceiptType),
+		formatTime(m.NextAttemptAt), formatTime(now))
+	if err != nil {
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	m.Status = "pending"
+	m.CreatedAt = now
+	return err
+}
+
+func (s outboxStore) ListDue(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	return s.list(" WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY id LIMIT ?", formatTime(now), limit)
+}
+
+func (s outboxStore) Claim(id int64, now, until time.Time) error {
+	return expectOne(s.q.Exec(
+		"UPDATE outbox SET next_attempt_at = ? WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?",
+		formatTime(until), id, formatTime(now)))
+}
+
+func (s outboxStore) MarkSent(id int64, at time.Time) error {
+	return expectOne(s.q.Exec(
+		"UPDATE outbox SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = ? WHERE id = ?",
+		formatTime(at), id))
+}
+
+func (s outboxStore) Reschedule(id int64, attempts int, lastErr string, next time.Time) error {
+	return expectOne(s.q.Exec(
+		"UPDATE outbox SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		attempts, lastErr, formatTime(next), id))
+}
+
+func (s outboxStore) Fail(id int64, attempts int, lastErr string) error {
+	return expectOne(s.q.Exec(
+		"UPDATE outbox SET status = 'failed', attempts = ?, last_error = ? WHERE id = ?",
+		attempts, lastErr, id))
+}
+
+func (s outboxStore) ListFailed(limit int) ([]models.OutboxMessage, error) {
+	return s.list(" WHERE status = 'failed' ORDER BY id DESC LIMIT ?", limit)
+}
+
+func (s outboxStore) Retry(id int64, now time.Time) error {
+	return expectOne(s.q.Exec(
+		"UPDATE outbox SET status = 'pending', attempts = 0, next_attempt_at = ? WHERE id = ? AND status = 'failed'",
+		formatTime(now), id))
+}
@@ -0,0 +1,159 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type paymentStore struct{ q querier }
+
+const paymentSelect = `
+	SELECT p.id, p.ticket_id, p.amount, COALESCE(p.method, ''), COALESCE(p.reference, ''), p.is_verified, p.created_at,
+	       COALESCE(p.status, 'pending'), COALESCE(p.reviewed_by, ''), p.reviewed_at, COALESCE(p.reject_reason, ''),
+	       COALESCE(p.receipt_key, ''), COALESCE(p.receipt_type, ''),
+	       p.kind, p.user_id, p.related_id, COALESCE(p.reason, ''),
+	       COALESCE(p.currency, r.currency, ''), COALESCE(p.original_amount, p.amount), COALESCE(p.rate, 1),
+	       COALESCE(t.number, ''), COALESCE(t.raffle_id, 0), COALESCE(r.name, ''), COALESCE(u.name, ''), COALESCE(u.phone, '')
+	FROM payments p
+	LEFT JOIN tickets t ON p.ticket_id = t.id
+	LEFT JOIN raffles r ON t.raffle_id = r.id
+	LEFT JOIN users u ON COALESCE(p.user_id, t.user_id) = u.id`
+
+func scanPayment(row scanner) (models.Payment, error) {
+	var p models.Payment
+	var verified sql.NullBool
+	var reviewedAt sql.NullTime
+	var userID, relatedID sql.NullInt64
+	err := row.Scan(&p.ID, &p.TicketID, &p.Amount, &p.Method, &p.Reference, &verified, &p.CreatedAt,
+		&p.Status, &p.ReviewedBy, &reviewedAt, &p.RejectReason,
+		&p.ReceiptKey, &p.ReceiptType,
+		&p.Kind, &userID, &relatedID, &p.Reason,
+		&p.Currency, &p.OriginalAmount, &p.Rate,
+		&p.TicketNumber, &p.RaffleID, &p.RaffleName, &p.UserName, &p.UserPhone)
+	p.IsVerified = verified.Bool
+	p.UserID = intPtr(userID)
+	p.RelatedID = intPtr(relatedID)
+	if reviewedAt.Valid {
+		p.ReviewedAt = &reviewedAt.Time
+	}
+	return p, err
+}
+
+func (s paymentStore) list(query string, args ...interface{}) ([]models.Payment, error) {
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func (s paymentStore) Get(id int64) (models.Payment, error) {
+	p, err := scanPayment(s.q.QueryRow(paymentSelect+" WHERE p.id = ?", id))
+	return p, notFound(err)
+}
+
+func (s paymentStore) Create(p *models.Payment) error {
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	if p.Status == "" {
+		p.Status = "pending"
+		if p.IsVerified {
+			p.Status = "verified"
+		}
+	}
+	p.IsVerified = p.Status == "verified"
+	if p.Kind == "" {
+		p.Kind = models.PaymentKindPayment
+	}
+	var userID sql.NullInt64
+	var currency string
+	err := s.q.QueryRow("SELECT t.user_id, r.currency FROM tickets t JOIN raffles r ON t.raffle_id = r.id WHERE t.id = ?", p.TicketID).Scan(&userID, &currency)
+	if err != nil {
+		return notFound(err)
+	}
+	if p.UserID == nil {
+		p.UserID = intPtr(userID)
+	}
+	if p.Currency == "" {
+		p.Currency, p.OriginalAmount, p.Rate = currency, p.Amount, 1
+	}
+
+	var reviewedAt interface{}
+	if p.ReviewedAt != nil {
+		reviewedAt = formatTime(*p.ReviewedAt)
+	}
+	res, err := s.q.Exec(`INSERT INTO payments (ticket_id, amount, method, reference, is_verified, created_at, status, reviewed_by, reviewed_at, receipt_key, receipt_type,
+			kind, user_id, related_id, reason, currency, original_amount, rate)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.TicketID, p.Amount, p.Method, p.Reference, p.IsVerified, formatTime(p.CreatedAt), p.Status, p.ReviewedBy, reviewedAt,
+		nullString(p.ReceiptKey), nullString(p.ReceiptType),
+		p.Kind, nullInt(p.UserID), nullInt(p.RelatedID), nullString(p.Reason), p.Currency, p.OriginalAmount, p.Rate)
+	if err != nil {
+		return err
+	}
+	p.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s paymentStore) ListByTicket(ticketID int64) ([]models.Payment, error) {
+	return s.list(paymentSelect+" WHERE p.ticket_id = ? AND p.user_id = t.user_id ORDER BY p.created_at DESC, p.id DESC", ticketID)
+}
+
+func (s paymentStore) ListPending() ([]models.Payment, error) {
+	return s.list(paymentSelect + " WHERE p.status = 'pending' ORDER BY p.created_at, p.id")
+}
+
+func (s paymentStore) TotalByTicket(ticketID int64) (models.Money, error) {
+	var total models.Money
+	err := s.q.QueryRow(`
+		SELECT COALESCE(SUM(p.amount), 0) FROM payments p JOIN tickets t ON p.ticket_id = t.id
+		WHERE p.ticket_id = ? AND p.user_id = t.user_id AND p.status = 'verified'`, ticketID).Scan(&total)
+	return total, err
+}
+
+func (s paymentStore) TotalsByCurrency(raffleID int64) ([]models.CurrencyTotal, error) {
+	rows, err := s.q.Query(`
+		SELECT COALESCE(p.currency, r.currency), SUM(COALESCE(p.original_amount, p.amount)), SUM(p.amount)
+		FROM payments p
+		JOIN tickets t ON p.ticket_id = t.id
+		JOIN raffles r ON t.raffle_id = r.id
+		WHERE t.raffle_id = ? AND p.user_id = t.user_id AND p.status = 'verified'
+		GROUP BY 1 ORDER BY 1`, raffleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []models.CurrencyTotal
+	for rows.Next() {
+		var t models.CurrencyTotal
+		if err := rows.Scan(&t.Currency, &t.Original, &t.Amount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func (s paymentStore) Verify(id int64, by string, at time.Time) error {
+	return expectOne(s.q.Exec("UPDATE payments SET status = 'verified', is_verified = 1, reviewed_by = ?, reviewed_at = ? WHERE id = ? AND status = 'pending'",
+		by, formatTime(at), id))
+}
+
+func (s paymentStore) Reject(id int64, by, reason string, at time.Time) error {
+	return expectOne(s.q.Exec("UPDATE payments SET status = 'rejected', is_verified = 0, reviewed_by = ?, reviewed_at = ?, reject_reason = ? WHERE id = ? AND status = 'pending'",
+		by, formatTime(at), reason, id))
+}
@@ -0,0 +1,86 @@
+package sqlstore
+
+import (
+	"strings"
+
+	"lotto-tg-app/internal/models"
+)
+
+type raffleStore struct{ q querier }
+
+const raffleColumns = "id, name, total_numbers, ticket_price, currency, reserve_hours, status, created_at"
+
+func scanRaffle(row scanner) (models.Raffle, error) {
+	var r models.Raffle
+	err := row.Scan(&r.ID, &r.Name, &r.TotalNumbers, &r.TicketPrice, &r.Currency, &r.ReserveHours, &r.Status, &r.CreatedAt)
+	return r, err
+}
+
+func (s raffleStore) Get(id int64) (models.Raffle, error) {
+	r, err := scanRaffle(s.q.QueryRow("SELECT "+raffleColumns+" FROM raffles WHERE id = ?", id))
+	return r, notFound(err)
+}
+
+func (s raffleStore) List(statuses ...string) ([]models.Raffle, error) {
+	query := "SELECT " + raffleColumns + " FROM raffles"
+	var args []interface{}
+	if len(statuses) > 0 {
+		query += " WHERE status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, st := range statuses {
+			args = append(args, st)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var raffles []models.Raffle
+	for rows.Next() {
+		r, err := scanRaffle(rows)
+		if err != nil {
+			return nil, err
+		}
+		raffles = append(raffles, r)
+	}
+	return raffles, rows.Err()
+}
+
+func (s raffleStore) Create(r *models.Raffle, numbers []string) error {
+	if r.Status == "" {
+		r.Status = "active"
+	}
+	if r.Currency == "" {
+		r.Currency = models.DefaultCurrency
+	}
+	res, err := s.q.Exec("INSERT INTO raffles (name, total_numbers, ticket_price, currency, reserve_hours, status) VALUES (?, ?, ?, ?, ?, ?)",
+		r.Name, r.TotalNumbers, r.TicketPrice, r.Currency, r.ReserveHours, r.Status)
+	if err != nil {
+		return err
+	}
+	if r.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	// Insertar en lotes para no hacer mil viajes a Turso
+	const batch = 200
+	for start := 0; start < len(numbers); start += batch {
+		end := min(start+batch, len(numbers))
+		args := make([]interface{}, 0, 2*(end-start))
+		for _, num := range numbers[start:end] {
+			args = append(args, r.ID, num)
+		}
+		query := "INSERT INTO tickets (raffle_id, number) VALUES (?, ?)" + strings.Repeat(", (?, ?)", end-start-1)
+		if _, err := s.q.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s raffleStore) UpdateStatus(id int64, from, to string) error {
+	return expectOne(s.q.Exec("UPDATE raffles SET status = ? WHERE id = ? AND status = ?", to, id, from))
+}
@@ -0,0 +1,55 @@
+package sqlstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type rateStore struct{ q querier }
+
+const rateColumns = "id, currency, base, rate, valid_from, COALESCE(created_by, ''), created_at"
+
+func scanRate(row scanner) (models.ExchangeRate, error) {
+	var r models.ExchangeRate
+	err := row.Scan(&r.ID, &r.Currency, &r.Base, &r.Rate, &r.ValidFrom, &r.CreatedBy, &r.CreatedAt)
+	return r, err
+}
+
+func (s rateStore) Add(r *models.ExchangeRate) error {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	res, err := s.q.Exec("INSERT INTO exchange_rates (currency, base, rate, valid_from, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		r.Currency, r.Base, r.Rate, formatTime(r.ValidFrom), nullString(r.CreatedBy), formatTime(r.CreatedAt))
+	if err != nil {
+		return err
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s rateStore) Find(currency, base string, at time.Time) (models.ExchangeRate, error) {
+	r, err := scanRate(s.q.QueryRow(`SELECT `+rateColumns+` FROM exchange_rates
+		WHERE currency = ? AND base = ? AND valid_from <= ?
+		ORDER BY valid_from DESC, id DESC LIMIT 1`, currency, base, formatTime(at)))
+	return r, notFound(err)
+}
+
+func (s rateStore) List(limit int) ([]models.ExchangeRate, error) {
+	rows, err := s.q.Query("SELECT "+rateColumns+" FROM exchange_rates ORDER BY valid_from DESC, id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		r, err := scanRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
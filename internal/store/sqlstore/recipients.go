@@ -0,0 +1,64 @@
+package sqlstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type recipientStore struct{ q querier }
+
+const recipientSelect = `
+	SELECT chat_id, telegram_id, COALESCE(name, ''), bookings, payments, expiries, created_at
+	FROM notification_recipients`
+
+func scanRecipient(row scanner) (models.Recipient, error) {
+	var r models.Recipient
+	err := row.Scan(&r.ChatID, &r.TelegramID, &r.Name, &r.Bookings, &r.Payments, &r.Expiries, &r.CreatedAt)
+	return r, err
+}
+
+func (s recipientStore) List() ([]models.Recipient, error) {
+	rows, err := s.q.Query(recipientSelect + " ORDER BY created_at, chat_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []models.Recipient
+	for rows.Next() {
+		r, err := scanRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+func (s recipientStore) Get(chatID int64) (models.Recipient, error) {
+	r, err := scanRecipient(s.q.QueryRow(recipientSelect+" WHERE chat_id = ?", chatID))
+	return r, notFound(err)
+}
+
+func (s recipientStore) Save(r models.Recipient) error {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	_, err := s.q.Exec(`
+		INSERT INTO notification_recipients (chat_id, telegram_id, name, bookings, payments, expiries, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_id) DO UPDATE SET
+			telegram_id = excluded.telegram_id,
+			name = excluded.name,
+			bookings = excluded.bookings,
+			payments = excluded.payments,
+			expiries = excluded.expiries`,
+		r.ChatID, r.TelegramID, r.Name, r.Bookings, r.Payments, r.Expiries, formatTime(r.CreatedAt))
+	return err
+}
+
+func (s recipientStore) Delete(chatID int64) error {
+	_, err := s.q.Exec("DELETE FROM notification_recipients WHERE chat_id = ?", chatID)
+	return err
+}
@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type sessionStore struct{ q querier }
+
+func (s sessionStore) Create(sess models.AdminSession) error {
+	_, err := s.q.Exec(`
+		INSERT INTO admin_sessions (id, telegram_id, name, csrf_token, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		sess.ID, sess.TelegramID, nullString(sess.Name), sess.CSRFToken, formatTime(sess.CreatedAt), formatTime(sess.ExpiresAt))
+	return err
+}
+
+func (s sessionStore) Get(id string) (models.AdminSession, error) {
+	var sess models.AdminSession
+	err := s.q.QueryRow(`
+		SELECT id, telegram_id, COALESCE(name, ''), csrf_token, created_at, expires_at
+		FROM admin_sessions WHERE id = ?`, id).
+		Scan(&sess.ID, &sess.TelegramID, &sess.Name, &sess.CSRFToken, &sess.CreatedAt, &sess.ExpiresAt)
+	return sess, notFound(err)
+}
+
+func (s sessionStore) Delete(id string) error {
+	_, err := s.q.Exec("DELETE FROM admin_sessions WHERE id = ?", id)
+	return err
+}
+
+func (s sessionStore) DeleteByAdmin(telegramID int64) error {
+	_, err := s.q.Exec("DELETE FROM admin_sessions WHERE telegram_id = ?", telegramID)
+	return err
+}
+
+func (s sessionStore) DeleteExpired(now time.Time) error {
+	_, err := s.q.Exec("DELETE FROM admin_sessions WHERE expires_at <= ?", formatTime(now))
+	return err
+}
@@ -0,0 +1,114 @@
+// Package sqlstore implementa store.Store sobre libsql/SQLite
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/store"
+)
+
+// timeFormat es el formato de CURRENT_TIMESTAMP en SQLite (UTC). Las fechas se
+// guardan como texto con este formato para poder compararlas en SQL.
+const timeFormat = "2006-01-02 15:04:05"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// querier es lo que comparten *sql.DB y *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+var _ store.Store = (*Store)(nil)
+
+type Store struct {
+	db *sql.DB // nil dentro de una transacción
+	q  querier
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{db: db, q: db}
+}
+
+func (s *Store) Raffles() store.RaffleStore             { return raffleStore{s.q} }
+func (s *Store) Tickets() store.TicketStore             { return ticketStore{s.q} }
+func (s *Store) Users() store.UserStore                 { return userStore{s.q} }
+func (s *Store) Payments() store.PaymentStore           { return paymentStore{s.q} }
+func (s *Store) Credits() store.CreditStore             { return creditStore{s.q} }
+func (s *Store) Rates() store.RateStore                 { return rateStore{s.q} }
+func (s *Store) Methods() store.PaymentMethodStore      { return methodStore{s.q} }
+func (s *Store) Draws() store.DrawStore                 { return drawStore{s.q} }
+func (s *Store) Recipients() store.RecipientStore       { return recipientStore{s.q} }
+func (s *Store) Outbox() store.OutboxStore              { return outboxStore{s.q} }
+func (s *Store) Admins() store.AdminStore               { return adminStore{s.q} }
+func (s *Store) Sessions() store.SessionStore           { return sessionStore{s.q} }
+func (s *Store) LoginAttempts() store.LoginAttemptStore { return attemptStore{s.q} }
+func (s *Store) Audit() store.AuditStore                { return auditStore{s.q} }
+
+func (s *Store) Tx(fn func(store.Store) error) error {
+	// Transacción anidada: reutilizar la actual
+	if s.db == nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Store{q: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// notFound traduce sql.ErrNoRows al error del paquete store
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// expectOne devuelve store.ErrConflict si el UPDATE condicional no afectó filas
+func expectOne(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrConflict
+	}
+	return nil
+}
+
+func nullInt(p *int64) sql.NullInt64 {
+	if p == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *p, Valid: true}
+}
+
+func intPtr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	v := n.Int64
+	return &v
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
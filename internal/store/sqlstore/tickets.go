@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+type ticketStore struct{ q querier }
+
+const ticketSelect = `
+	SELECT t.id, t.raffle_id, t.number, t.user_id, t.status, t.reserved_at, t.reminded_at,
+	       COALESCE(u.name, ''), COALESCE(u.phone, ''), u.telegram_id,
+	       COALESCE((SELECT SUM(amount) FROM payments WHERE ticket_id = t.id AND user_id = t.user_id AND status <> 'rejected'), 0),
+	       COALESCE((SELECT SUM(amount) FROM payments WHERE ticket_id = t.id AND user_id = t.user_id AND status = 'verified'), 0)
+	FROM tickets t
+	LEFT JOIN users u ON t.user_id = u.id`
+
+func scanTicket(row scanner) (models.Ticket, error) {
+	var t models.Ticket
+	var userID, telegramID sql.NullInt64
+	var reservedAt, remindedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.RaffleID, &t.Number, &userID, &t.Status, &reservedAt, &remindedAt,
+		&t.UserName, &t.UserPhone, &telegramID, &t.TotalPaid, &t.TotalVerified)
+	t.UserID = intPtr(userID)
+	t.UserTelegramID = intPtr(telegramID)
+	if reservedAt.Valid {
+		t.ReservedAt = &reservedAt.Time
+	}
+	if remindedAt.Valid {
+		t.RemindedAt = &remindedAt.Time
+	}
+	return t, err
+}
+
+func (s ticketStore) list(query string, args ...interface{}) ([]models.Ticket, error) {
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []models.Ticket
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+func (s ticketStore) Get(id int64) (models.Ticket, error) {
+	t, err := scanTicket(s.q.QueryRow(ticketSelect+" WHERE t.id = ?", id))
+	return t, notFound(err)
+}
+
+func (s ticketStore) GetByNumber(raffleID int64, number string) (models.Ticket, error) {
+	t, err := scanTicket(s.q.QueryRow(ticketSelect+" WHERE t.raffle_id = ? AND t.number = ?", raffleID, number))
+	return t, notFound(err)
+}
+
+func (s ticketStore) List(raffleID int64, query string) ([]models.Ticket, error) {
+	sqlQuery := ticketSelect + " WHERE t.raffle_id = ?"
+	args := []interface{}{raffleID}
+
+	if query != "" {
+		sqlQuery += " AND t.number LIKE ?"
+		args = append(args, "%"+query+"%")
+	}
+
+	return s.list(sqlQuery+" ORDER BY t.number ASC", args...)
+}
+
+func (s ticketStore) Reserve(id, userID int64, at time.Time) error {
+	return expectOne(s.q.Exec("UPDATE tickets SET user_id = ?, status = 'reserved', reserved_at = ?, reminded_at = NULL WHERE id = ? AND status = 'available'",
+		userID, formatTime(at), id))
+}
+
+func (s ticketStore) MarkPaid(id int64) error {
+	return expectOne(s.q.Exec("UPDATE tickets SET status = 'paid' WHERE id = ? AND status = 'reserved'", id))
+}
+
+func (s ticketStore) MarkUnpaid(id int64) error {
+	return expectOne(s.q.Exec("UPDATE tickets SET status = 'reserved' WHERE id = ? AND status = 'paid'", id))
+}
+
+func (s ticketStore) Release(id int64, from string) error {
+	return expectOne(s.q.Exec("UPDATE tickets SET user_id = NULL, status = 'available', reserved_at = NULL, reminded_at = NULL WHERE id = ? AND status = ?", id, from))
+}
+
+func (s ticketStore) ListExpired(now time.Time) ([]models.Ticket, error) {
+	return s.list(ticketSelect+`
+		JOIN raffles r ON t.raffle_id = r.id
+		WHERE t.status = 'reserved'
+		  AND r.status = 'active'
+		  AND r.reserve_hours > 0
+		  AND t.reserved_at IS NOT NULL
+		  AND datetime(t.reserved_at, '+' || r.reserve_hours || ' hours') <= ?
+		ORDER BY t.raffle_id, t.number`, formatTime(now))
+}
+
+func (s ticketStore) ListExpiring(now time.Time, within time.Duration) ([]models.Ticket, error) {
+	return s.list(ticketSelect+`
+		JOIN raffles r ON t.raffle_id = r.id
+		WHERE t.status = 'reserved'
+		  AND r.status = 'active'
+		  AND r.reserve_hours > 0
+		  AND t.reserved_at IS NOT NULL
+		  AND t.reminded_at IS NULL
+		  AND datetime(t.reserved_at, '+' || r.reserve_hours || ' hours') > ?
+		  AND datetime(t.reserved_at, '+' || r.reserve_hours || ' hours') <= ?
+		ORDER BY t.raffle_id, t.number`, formatTime(now), formatTime(now.Add(within)))
+}
+
+func (s ticketStore) MarkReminded(id int64, at time.Time) error {
+	return expectOne(s.q.Exec("UPDATE tickets SET reminded_at = ? WHERE id = ?", formatTime(at), id))
+}
@@ -0,0 +1,153 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+
+	"lotto-tg-app/internal/models"
+)
+
+type userStore struct{ q querier }
+
+const userColumns = "id, telegram_id, name, phone"
+
+func scanUser(row scanner) (models.User, error) {
+	var u models.User
+	var telegramID sql.NullInt64
+	var phone sql.NullString
+	err := row.Scan(&u.ID, &telegramID, &u.Name, &phone)
+	u.TelegramID = intPtr(telegramID)
+	u.Phone = phone.String
+	return u, err
+}
+
+func (s userStore) list(query string, args ...interface{}) ([]models.User, error) {
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s userStore) Get(id int64) (models.User, error) {
+	u, err := scanUser(s.q.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+	return u, notFound(err)
+}
+
+func (s userStore) FindByPhone(phone string) (models.User, error) {
+	norm := models.NormalizePhone(phone)
+	if norm == "" {
+		return models.User{}, notFound(sql.ErrNoRows)
+	}
+	u, err := scanUser(s.q.QueryRow("SELECT "+userColumns+" FROM users WHERE phone_norm = ? ORDER BY id LIMIT 1", norm))
+	return u, notFound(err)
+}
+
+func (s userStore) FindByTelegramID(telegramID int64) (models.User, error) {
+	u, err := scanUser(s.q.QueryRow("SELECT "+userColumns+" FROM users WHERE telegram_id = ?", telegramID))
+	return u, notFound(err)
+}
+
+func nullPhone(phone string) sql.NullString {
+	norm := models.NormalizePhone(phone)
+	return sql.NullString{String: norm, Valid: norm != ""}
+}
+
+func (s userStore) Create(u *models.User) error {
+	res, err := s.q.Exec("INSERT INTO users (telegram_id, name, phone, phone_norm) VALUES (?, ?, ?, ?)",
+		nullInt(u.TelegramID), u.Name, u.Phone, nullPhone(u.Phone))
+	if err != nil {
+		return err
+	}
+	u.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s userStore) Update(u models.User) error {
+	return expectOne(s.q.Exec("UPDATE users SET telegram_id = ?, name = ?, phone = ?, phone_norm = ? WHERE id = ?",
+		nullInt(u.TelegramID), u.Name, u.Phone, nullPhone(u.Phone), u.ID))
+}
+
+func (s userStore) Search(q string, limit int) ([]models.User, error) {
+	norm := models.NormalizePhone(q)
+	return s.list("SELECT "+userColumns+" FROM users WHERE name LIKE ? OR phone LIKE ? OR (? != '' AND phone_norm LIKE ?) ORDER BY name LIMIT ?",
+		"%"+q+"%", "%"+q+"%", norm, "%"+norm+"%", limit)
+}
+
+func (s userStore) Duplicates() ([][]models.User, error) {
+	users, err := s.list(`
+		SELECT ` + userColumns + ` FROM users
+		WHERE phone_norm IN (SELECT phone_norm FROM users WHERE phone_norm IS NOT NULL GROUP BY phone_norm HAVING COUNT(*) > 1)
+		ORDER BY phone_norm, id`)
+	if err != nil {
+		return nil, err
+	}
+	return groupByPhone(users), nil
+}
+
+// groupByPhone agrupa una lista ya ordenada por teléfono normalizado
+func groupByPhone(users []models.User) [][]models.User {
+	var groups [][]models.User
+	for _, u := range users {
+		n := len(groups)
+		if n > 0 && models.NormalizePhone(groups[n-1][0].Phone) == models.NormalizePhone(u.Phone) {
+			groups[n-1] = append(groups[n-1], u)
+		} else {
+			groups = append(groups, []models.User{u})
+		}
+	}
+	return groups
+}
+
+func (s userStore) Merge(keepID int64, mergeIDs []int64) error {
+	keep, err := s.Get(keepID)
+	if err != nil {
+		return err
+	}
+
+	var ids []interface{}
+	for _, id := range mergeIDs {
+		if id == keepID {
+			continue
+		}
+		u, err := s.Get(id)
+		if err != nil {
+			return err
+		}
+		// Conservar los datos que le falten al cliente principal
+		if keep.TelegramID == nil {
+			keep.TelegramID = u.TelegramID
+		}
+		if keep.Phone == "" {
+			keep.Phone = u.Phone
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	args := append([]interface{}{keepID}, ids...)
+
+	for _, table := range []string{"tickets", "payments", "credits", "released_payments", "raffle_draws"} {
+		if _, err := s.q.Exec("UPDATE "+table+" SET user_id = ? WHERE user_id IN "+in, args...); err != nil {
+			return err
+		}
+	}
+
+	// telegram_id es UNIQUE: borrar los duplicados antes de pasarlo al principal
+	if _, err := s.q.Exec("DELETE FROM users WHERE id IN "+in, ids...); err != nil {
+		return err
+	}
+	return s.Update(keep)
+}
@@ -0,0 +1,230 @@
+// Package store define los repositorios de datos que usan los handlers.
+// Hay dos implementaciones: sqlstore (libsql/SQLite) y memstore (en memoria, para tests).
+package store
+
+import (
+	"errors"
+	"time"
+
+	"lotto-tg-app/internal/models"
+)
+
+var (
+	// ErrNotFound se devuelve cuando el registro buscado no existe
+	ErrNotFound = errors.New("store: not found")
+	// ErrConflict se devuelve cuando una actualización condicional no afectó
+	// ninguna fila (ej: el ticket ya no estaba disponible)
+	ErrConflict = errors.New("store: conflict")
+)
+
+// Store agrupa los repositorios y permite ejecutarlos en una transacción
+type Store interface {
+	Raffles() RaffleStore
+	Tickets() TicketStore
+	Users() UserStore
+	Payments() PaymentStore
+	Credits() CreditStore
+	Rates() RateStore
+	Methods() PaymentMethodStore
+	Draws() DrawStore
+	Recipients() RecipientStore
+	Outbox() OutboxStore
+	Admins() AdminStore
+	Sessions() SessionStore
+	LoginAttempts() LoginAttemptStore
+	Audit() AuditStore
+
+	// Tx ejecuta fn de forma atómica: si fn devuelve error no se guarda nada.
+	// Dentro de fn se debe usar el Store recibido, no el original.
+	Tx(fn func(Store) error) error
+}
+
+type RaffleStore interface {
+	Get(id int64) (models.Raffle, error)
+	// List devuelve las rifas con alguno de los estados dados, las más nuevas primero
+	List(statuses ...string) ([]models.Raffle, error)
+	// Create guarda la rifa (asigna r.ID) y genera un ticket por cada número.
+	// Los números no se pueden repetir dentro de una rifa.
+	Create(r *models.Raffle, numbers []string) error
+	// UpdateStatus cambia el estado solo si el actual es from; si no, ErrConflict
+	UpdateStatus(id int64, from, to string) error
+}
+
+type TicketStore interface {
+	// Get devuelve el ticket con los datos del cliente y TotalPaid
+	Get(id int64) (models.Ticket, error)
+	GetByNumber(raffleID int64, number string) (models.Ticket, error)
+	// List devuelve los tickets de una rifa ordenados por número; query filtra por número
+	List(raffleID int64, query string) ([]models.Ticket, error)
+	// Reserve asigna el ticket a un cliente solo si está 'available'; si no, ErrConflict
+	Reserve(id, userID int64, at time.Time) error
+	// MarkPaid pasa el ticket a 'paid' solo si está 'reserved'; si no, ErrConflict
+	MarkPaid(id int64) error
+	// MarkUnpaid vuelve el ticket a 'reserved' solo si está 'paid' (se devolvió
+	// parte de lo cobrado); si no, ErrConflict
+	MarkUnpaid(id int64) error
+	// Release devuelve el ticket a 'available' solo si su estado es from; si no, ErrConflict
+	Release(id int64, from string) error
+	// ListExpired devuelve los tickets reservados de rifas activas cuyo plazo
+	// (reserved_at + reserve_hours) ya venció a la hora now
+	ListExpired(now time.Time) ([]models.Ticket, error)
+	// ListExpiring devuelve los reservados que vencen entre now y now+within
+	// y a cuyo cliente todavía no se le recordó
+	ListExpiring(now time.Time, within time.Duration) ([]models.Ticket, error)
+	MarkReminded(id int64, at time.Time) error
+}
+
+type UserStore interface {
+	Get(id int64) (models.User, error)
+	// FindByPhone busca por teléfono normalizado (models.NormalizePhone); devuelve el más antiguo
+	FindByPhone(phone string) (models.User, error)
+	FindByTelegramID(telegramID int64) (models.User, error)
+	Create(u *models.User) error
+	// Update guarda nombre, teléfono y telegram_id
+	Update(u models.User) error
+	Search(q string, limit int) ([]models.User, error)
+	// Duplicates agrupa los clientes que comparten teléfono normalizado
+	Duplicates() ([][]models.User, error)
+	// Merge pasa los tickets (y el historial) de mergeIDs a keepID y borra esos clientes
+	Merge(keepID int64, mergeIDs []int64) error
+}
+
+// PaymentStore es el libro de pagos: las entradas no se editan ni se borran,
+// solo se revisan las pendientes
+type PaymentStore interface {
+	Get(id int64) (models.Payment, error)
+	// Create guarda la entrada; sin Status queda 'verified' o 'pending' según
+	// IsVerified, sin Kind es un pago y sin UserID es del cliente actual del
+	// ticket. Sin Currency es en la moneda de la rifa (OriginalAmount = Amount).
+	Create(p *models.Payment) error
+	// ListByTicket devuelve las entradas del cliente actual del ticket, las más nuevas primero
+	ListByTicket(ticketID int64) ([]models.Payment, error)
+	// ListPending devuelve los pagos por verificar con ticket, rifa y cliente, los más antiguos primero
+	ListPending() ([]models.Payment, error)
+	// TotalByTicket suma las entradas verificadas del cliente actual del ticket
+	TotalByTicket(ticketID int64) (models.Money, error)
+	// TotalsByCurrency suma por moneda las entradas verificadas de los clientes
+	// actuales de los tickets de la rifa, ordenadas por moneda
+	TotalsByCurrency(raffleID int64) ([]models.CurrencyTotal, error)
+	// Verify y Reject revisan un pago 'pending'; si ya fue revisado, ErrConflict
+	Verify(id int64, by string, at time.Time) error
+	Reject(id int64, by, reason string, at time.Time) error
+}
+
+// CreditStore guarda los movimientos del saldo a favor de los clientes
+type CreditStore interface {
+	// Add guarda el movimiento y asigna c.ID; sin CreatedAt usa la hora actual
+	Add(c *models.Credit) error
+	// Balance suma los movimientos del cliente en la moneda dada
+	Balance(userID int64, currency string) (models.Money, error)
+	// Balances devuelve el saldo del cliente en cada moneda en que no es cero
+	Balances(userID int64) (map[string]models.Money, error)
+}
+
+// RateStore guarda las tasas de cambio; no se editan, una tasa nueva
+// reemplaza a la anterior desde su ValidFrom
+type RateStore interface {
+	// Add guarda la tasa y asigna r.ID; sin CreatedAt usa la hora actual
+	Add(r *models.ExchangeRate) error
+	// Find devuelve la última tasa de currency en base vigente en at; si no hay, ErrNotFound
+	Find(currency, base string, at time.Time) (models.ExchangeRate, error)
+	// List devuelve las últimas limit tasas cargadas, las más nuevas primero
+	List(limit int) ([]models.ExchangeRate, error)
+}
+
+// PaymentMethodStore guarda el catálogo de métodos de pago y cuáles acepta cada rifa
+type PaymentMethodStore interface {
+	// List devuelve el catálogo ordenado por posición
+	List() ([]models.PaymentMethod, error)
+	Get(code string) (models.PaymentMethod, error)
+	// Save crea el método o actualiza el existente con el mismo código
+	Save(m models.PaymentMethod) error
+	// ForRaffle devuelve los códigos habilitados en la rifa; vacío es que la
+	// rifa acepta todos los públicos
+	ForRaffle(raffleID int64) ([]string, error)
+	// SetForRaffle reemplaza los métodos habilitados en la rifa
+	SetForRaffle(raffleID int64, codes []string) error
+}
+
+type DrawStore interface {
+	Get(raffleID int64) (models.Draw, error)
+	Recent(limit int) ([]models.Draw, error)
+	// Create guarda el sorteo; si la rifa ya tiene uno, ErrConflict
+	Create(d *models.Draw) error
+}
+
+type RecipientStore interface {
+	// List devuelve todos los chats registrados, los más antiguos primero
+	List() ([]models.Recipient, error)
+	Get(chatID int64) (models.Recipient, error)
+	// Save crea el destinatario o actualiza el existente con el mismo chat_id
+	Save(r models.Recipient) error
+	Delete(chatID int64) error
+}
+
+type OutboxStore interface {
+	// Enqueue guarda el mensaje 'pending' para enviar desde NextAttemptAt (o ya)
+	Enqueue(m *models.OutboxMessage) error
+	// ListDue devuelve hasta limit mensajes 'pending' a enviar a la hora now, los más antiguos primero
+	ListDue(now time.Time, limit int) ([]models.OutboxMessage, error)
+	// Claim aparta un mensaje vencido hasta until para que otra instancia no
+	// lo envíe también; si ya no está vencido o pendiente, ErrConflict
+	Claim(id int64, now, until time.Time) error
+	MarkSent(id int64, at time.Time) error
+	// Reschedule anota el intento fallido y lo deja 'pending' hasta next
+	Reschedule(id int64, attempts int, lastErr string, next time.Time) error
+	// Fail anota el intento fallido y lo deja 'failed' hasta que un admin lo reintente
+	Fail(id int64, attempts int, lastErr string) error
+	// ListFailed devuelve los mensajes fallidos, los más nuevos primero
+	ListFailed(limit int) ([]models.OutboxMessage, error)
+	// Retry vuelve a encolar un mensaje 'failed'; si no lo está, ErrConflict
+	Retry(id int64, now time.Time) error
+}
+
+type AdminStore interface {
+	// List devuelve los admins, los más antiguos primero
+	List() ([]models.Admin, error)
+	Get(telegramID int64) (models.Admin, error)
+	GetByUsername(username string) (models.Admin, error)
+	// Save crea el admin o cambia nombre y rol del existente (no toca la contraseña)
+	Save(a models.Admin) error
+	// SetPassword cambia usuario y hash; vacíos deshabilitan el login con contraseña
+	SetPassword(telegramID int64, username, passwordHash string) error
+	Delete(telegramID int64) error
+}
+
+type SessionStore interface {
+	Create(s models.AdminSession) error
+	// Get devuelve ErrNotFound si no existe; la expiración la revisa quien llama
+	Get(id string) (models.AdminSession, error)
+	Delete(id string) error
+	// DeleteByAdmin cierra todas las sesiones del admin (al revocarlo)
+	DeleteByAdmin(telegramID int64) error
+	DeleteExpired(now time.Time) error
+}
+
+type LoginAttemptStore interface {
+	Get(key string) (models.LoginAttempt, error)
+	Save(a models.LoginAttempt) error
+	Delete(key string) error
+}
+
+// AuditFilter elige entradas del audit log; los campos vacíos no filtran
+type AuditFilter struct {
+	Actor     string // Contiene el texto, ej: "tg:123"
+	Action    string
+	RaffleID  int64
+	TicketID  int64
+	PaymentID int64
+	From      time.Time // Desde (inclusive)
+	To        time.Time // Hasta (exclusive)
+	Limit     int       // 0 = sin límite
+}
+
+// AuditStore no tiene Update ni Delete: el audit log es append-only
+type AuditStore interface {
+	// Append guarda la entrada y asigna e.ID; sin CreatedAt usa la hora actual
+	Append(e *models.AuditEntry) error
+	// List devuelve las entradas que cumplen el filtro, las más nuevas primero
+	List(f AuditFilter) ([]models.AuditEntry, error)
+}
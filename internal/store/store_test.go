@@ -0,0 +1,342 @@
+package store_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lotto-tg-app/internal/db"
+	"lotto-tg-app/internal/models"
+	"lotto-tg-app/internal/store"
+	"lotto-tg-app/internal/store/memstore"
+	"lotto-tg-app/internal/store/sqlstore"
+)
+
+// Los mismos casos corren contra las dos implementaciones: memstore tiene
+// que comportarse como la base real para que los tests con él valgan algo.
+func forEachStore(t *testing.T, fn func(t *testing.T, st store.Store)) {
+	t.Helper()
+	stores := map[string]func(t *testing.T) store.Store{
+		"memstore": func(t *testing.T) store.Store { return memstore.New() },
+		"sqlite": func(t *testing.T) store.Store {
+			if err := db.Init("file:"+filepath.Join(t.TempDir(), "test.db"), ""); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.DB.Close() })
+			return sqlstore.New(db.DB)
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) { fn(t, open(t)) })
+	}
+}
+
+func newRaffle(t *testing.T, st store.Store, numbers ...string) models.Raffle {
+	t.Helper()
+	r := models.Raffle{Name: "Rifa", TotalNumbers: 100, TicketPrice: models.Cents(10, 0), ReserveHours: 24}
+	if err := st.Raffles().Create(&r, numbers); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func newUser(t *testing.T, st store.Store, name, phone string) models.User {
+	t.Helper()
+	u := models.User{Name: name, Phone: phone}
+	if err := st.Users().Create(&u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestRaffles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		r := newRaffle(t, st, "00", "01")
+		got, err := st.Raffles().Get(r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != "active" || got.Currency != models.DefaultCurrency || got.TicketPrice != r.TicketPrice {
+			t.Fatalf("rifa = %+v", got)
+		}
+		tickets, err := st.Tickets().List(r.ID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tickets) != 2 || tickets[0].Number != "00" || tickets[0].Status != "available" {
+			t.Fatalf("tickets = %+v", tickets)
+		}
+
+		// Un número repetido falla y la transacción no deja nada a medias
+		dup := models.Raffle{Name: "Repetida", TotalNumbers: 100, TicketPrice: models.Cents(1, 0)}
+		err = st.Tx(func(tx store.Store) error { return tx.Raffles().Create(&dup, []string{"05", "05"}) })
+		if err == nil {
+			t.Fatal("se creó una rifa con números repetidos")
+		}
+		if raffles, _ := st.Raffles().List("active"); len(raffles) != 1 {
+			t.Fatalf("%d rifas activas, quería 1", len(raffles))
+		}
+
+		if _, err := st.Raffles().Get(r.ID + 100); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Get de una rifa inexistente: %v, quería ErrNotFound", err)
+		}
+		if err := st.Raffles().UpdateStatus(r.ID, "closed", "drawn"); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("UpdateStatus desde otro estado: %v, quería ErrConflict", err)
+		}
+		if err := st.Raffles().UpdateStatus(r.ID, "active", "closed"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTicketTransitions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		r := newRaffle(t, st, "07")
+		ana := newUser(t, st, "Ana", "0414-1111111")
+		bo := newUser(t, st, "Bo", "0414-2222222")
+		ticket, err := st.Tickets().GetByNumber(r.ID, "07")
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+		if err := st.Tickets().Reserve(ticket.ID, ana.ID, at); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Tickets().Reserve(ticket.ID, bo.ID, at); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("segunda reserva: %v, quería ErrConflict", err)
+		}
+		got, err := st.Tickets().Get(ticket.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != "reserved" || got.UserID == nil || *got.UserID != ana.ID || got.UserName != "Ana" {
+			t.Fatalf("ticket = %+v, quería reservado por Ana", got)
+		}
+		if got.ReservedAt == nil || !got.ReservedAt.Equal(at) {
+			t.Fatalf("reserved_at = %v, quería %v", got.ReservedAt, at)
+		}
+
+		if err := st.Tickets().MarkUnpaid(ticket.ID); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("MarkUnpaid de un reservado: %v, quería ErrConflict", err)
+		}
+		if err := st.Tickets().MarkPaid(ticket.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Tickets().Release(ticket.ID, "reserved"); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("Release desde otro estado: %v, quería ErrConflict", err)
+		}
+		if err := st.Tickets().Release(ticket.ID, "paid"); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := st.Tickets().Get(ticket.ID); got.Status != "available" || got.UserID != nil || got.ReservedAt != nil {
+			t.Fatalf("ticket liberado = %+v", got)
+		}
+	})
+}
+
+func TestPaymentTotals(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		r := newRaffle(t, st, "07")
+		ana := newUser(t, st, "Ana", "0414-1111111")
+		ticket, _ := st.Tickets().GetByNumber(r.ID, "07")
+		if err := st.Tickets().Reserve(ticket.ID, ana.ID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range []models.Payment{
+			{TicketID: ticket.ID, Amount: models.Cents(3, 33), Method: "cash", Status: "verified"},
+			{TicketID: ticket.ID, Amount: models.Cents(3, 34), Method: "cash", Status: "verified"},
+			{TicketID: ticket.ID, Amount: models.Cents(5, 0), Method: "transfer", Status: "pending"},
+		} {
+			if err := st.Payments().Create(&p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Solo suma lo verificado; TotalPaid incluye lo pendiente
+		total, err := st.Payments().TotalByTicket(ticket.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != models.Cents(6, 67) {
+			t.Fatalf("total verificado %s, quería 6.67", total)
+		}
+		if got, _ := st.Tickets().Get(ticket.ID); got.TotalPaid != models.Cents(11, 67) || got.TotalVerified != total {
+			t.Fatalf("ticket con %s pagado y %s verificado, quería 11.67 y 6.67", got.TotalPaid, got.TotalVerified)
+		}
+		totals, err := st.Payments().TotalsByCurrency(r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(totals) != 1 || totals[0].Currency != r.Currency || totals[0].Amount != total {
+			t.Fatalf("totales por moneda = %+v", totals)
+		}
+
+		pending, err := st.Payments().ListPending()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 {
+			t.Fatalf("%d pendientes, quería 1", len(pending))
+		}
+		at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		if err := st.Payments().Verify(pending[0].ID, "tg:1 Ana", at); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Payments().Reject(pending[0].ID, "tg:1 Ana", "tarde", at); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("rechazo de un pago revisado: %v, quería ErrConflict", err)
+		}
+		if total, _ := st.Payments().TotalByTicket(ticket.ID); total != models.Cents(11, 67) {
+			t.Fatalf("total verificado %s, quería 11.67", total)
+		}
+	})
+}
+
+func TestCreditBalances(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		ana := newUser(t, st, "Ana", "0414-1111111")
+		for _, c := range []models.Credit{
+			{UserID: ana.ID, Amount: models.Cents(4, 0), Currency: "USD"},
+			{UserID: ana.ID, Amount: -models.Cents(1, 50), Currency: "USD"},
+			{UserID: ana.ID, Amount: models.Cents(100, 0), Currency: "VES"},
+			{UserID: ana.ID, Amount: -models.Cents(100, 0), Currency: "VES"},
+		} {
+			if err := st.Credits().Add(&c); err != nil {
+				t.Fatal(err)
+			}
+			if c.ID == 0 {
+				t.Fatal("Add no asignó el ID")
+			}
+		}
+
+		balance, err := st.Credits().Balance(ana.ID, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance != models.Cents(2, 50) {
+			t.Fatalf("saldo %s, quería 2.50", balance)
+		}
+		// Las monedas en cero no aparecen
+		balances, err := st.Credits().Balances(ana.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(balances) != 1 || balances["USD"] != models.Cents(2, 50) {
+			t.Fatalf("saldos = %v, quería solo 2.50 USD", balances)
+		}
+	})
+}
+
+func TestMethods(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		methods, err := st.Methods().List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(methods) != 5 || methods[0].Code != "transfer" || !methods[0].Public {
+			t.Fatalf("catálogo inicial = %+v", methods)
+		}
+
+		zelle := models.PaymentMethod{Code: "zelle", Name: "Zelle", RefPattern: `\d+`, Public: true, Position: 0, UpdatedBy: "tg:1 Ana"}
+		if err := st.Methods().Save(zelle); err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.Methods().Get("zelle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Public || got.RefPattern != zelle.RefPattern || got.UpdatedBy != zelle.UpdatedBy {
+			t.Fatalf("método guardado = %+v", got)
+		}
+		if _, err := st.Methods().Get("paypal"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Get de un método inexistente: %v, quería ErrNotFound", err)
+		}
+
+		// Los habilitados salen en el orden del catálogo y sin repetir
+		r := newRaffle(t, st, "07")
+		if err := st.Methods().SetForRaffle(r.ID, []string{"cash", "zelle", "cash"}); err != nil {
+			t.Fatal(err)
+		}
+		codes, err := st.Methods().ForRaffle(r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(codes) != 2 || codes[0] != "zelle" || codes[1] != "cash" {
+			t.Fatalf("métodos de la rifa = %v, quería [zelle cash]", codes)
+		}
+		if err := st.Methods().SetForRaffle(r.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+		if codes, _ := st.Methods().ForRaffle(r.ID); len(codes) != 0 {
+			t.Fatalf("métodos de la rifa = %v, quería ninguno", codes)
+		}
+	})
+}
+
+func TestUsersByPhone(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		ana := newUser(t, st, "Ana", "0414-1234567")
+		other := newUser(t, st, "Ana María", "+58 414 1234567")
+		newUser(t, st, "Bo", "0424-7654321")
+
+		// Se busca por el teléfono normalizado y gana el más antiguo
+		got, err := st.Users().FindByPhone("04141234567")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != ana.ID {
+			t.Fatalf("FindByPhone devolvió %d, quería %d", got.ID, ana.ID)
+		}
+		if _, err := st.Users().FindByPhone("0412-0000000"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("FindByPhone de un teléfono nuevo: %v, quería ErrNotFound", err)
+		}
+
+		groups, err := st.Users().Duplicates()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 1 || len(groups[0]) != 2 {
+			t.Fatalf("duplicados = %v, quería un par", groups)
+		}
+
+		if err := st.Users().Merge(ana.ID, []int64{other.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Users().Get(other.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("cliente fusionado: %v, quería ErrNotFound", err)
+		}
+		if groups, _ := st.Users().Duplicates(); len(groups) != 0 {
+			t.Fatalf("duplicados después de fusionar = %v", groups)
+		}
+	})
+}
+
+func TestTxRollback(t *testing.T) {
+	forEachStore(t, func(t *testing.T, st store.Store) {
+		r := newRaffle(t, st, "07")
+		ana := newUser(t, st, "Ana", "0414-1111111")
+		ticket, _ := st.Tickets().GetByNumber(r.ID, "07")
+
+		failed := errors.New("falla")
+		err := st.Tx(func(tx store.Store) error {
+			if err := tx.Tickets().Reserve(ticket.ID, ana.ID, time.Now()); err != nil {
+				return err
+			}
+			if err := tx.Credits().Add(&models.Credit{UserID: ana.ID, Amount: models.Cents(1, 0), Currency: "USD"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("Tx devolvió %v, quería el error de fn", err)
+		}
+		if got, _ := st.Tickets().Get(ticket.ID); got.Status != "available" {
+			t.Fatalf("ticket %s después del rollback, quería disponible", got.Status)
+		}
+		if balance, _ := st.Credits().Balance(ana.ID, "USD"); balance != 0 {
+			t.Fatalf("saldo %s después del rollback, quería 0", balance)
+		}
+	})
+}